@@ -0,0 +1,231 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// defaultCacheMaxSize is the default approximate memory limit of the shared cache
+const defaultCacheMaxSize = 64 << 20
+
+var (
+	errCacheNotInteger = errors.New("the cached value is not an integer")
+
+	sharedCache = newCache(defaultCacheMaxSize)
+)
+
+// cache is a key/value store shared by all PHP threads, least recently used entries are evicted when it is full
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+	maxSize int64
+
+	// metrics are the metrics of the server the cache belongs to, they may implement CacheMetrics
+	metrics Metrics
+}
+
+type cacheEntry struct {
+	key       string
+	value     any
+	size      int64
+	expiresAt time.Time
+}
+
+func newCache(maxSize int64) *cache {
+	return &cache{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		maxSize: maxSize,
+	}
+}
+
+func (c *cache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cm, _ := c.metrics.(CacheMetrics)
+
+	e := c.lookup(key)
+	if e == nil {
+		if cm != nil {
+			cm.CacheMiss()
+		}
+
+		return nil, false
+	}
+
+	if cm != nil {
+		cm.CacheHit()
+	}
+
+	return e.Value.(*cacheEntry).value, true
+}
+
+// set stores a value converted with GoValue, it returns false if the value is larger than the cache
+func (c *cache) set(key string, value any, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.store(key, value, ttl)
+}
+
+func (c *cache) delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.lookup(key)
+	if e == nil {
+		return false
+	}
+
+	c.remove(e)
+
+	return true
+}
+
+// incr atomically adds step to an integer, missing keys start at 0
+func (c *cache) incr(key string, step int64, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int64
+	if e := c.lookup(key); e != nil {
+		entry := e.Value.(*cacheEntry)
+
+		v, ok := entry.value.(int64)
+		if !ok {
+			return 0, errCacheNotInteger
+		}
+
+		n = v + step
+		entry.value = n
+
+		return n, nil
+	}
+
+	n = step
+	c.store(key, n, ttl)
+
+	return n, nil
+}
+
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.size = 0
+}
+
+// lookup returns the element of a key and marks it as recently used, expired entries are removed
+func (c *cache) lookup(key string) *list.Element {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := e.Value.(*cacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.remove(e)
+
+		return nil
+	}
+
+	c.lru.MoveToFront(e)
+
+	return e
+}
+
+func (c *cache) store(key string, value any, ttl time.Duration) bool {
+	entry := &cacheEntry{key: key, value: value, size: int64(len(key)) + cacheValueSize(value)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+
+	if entry.size > c.maxSize {
+		return false
+	}
+
+	for c.size+entry.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += entry.size
+
+	return true
+}
+
+func (c *cache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// cacheValueSize approximates the memory used by a value returned by GoValue
+func cacheValueSize(value any) int64 {
+	const overhead = 16
+
+	switch v := value.(type) {
+	case string:
+		return overhead + int64(len(v))
+	case AssociativeArray:
+		size := int64(overhead)
+		for k, val := range v.Map {
+			size += overhead + int64(len(k)) + cacheValueSize(val)
+		}
+
+		return size
+	case []any:
+		size := int64(overhead)
+		for _, val := range v {
+			size += cacheValueSize(val)
+		}
+
+		return size
+	default:
+		return overhead
+	}
+}
+
+//export go_frankenphp_cache_get
+func go_frankenphp_cache_get(threadIndex C.uintptr_t, key *C.zend_string) (C.bool, unsafe.Pointer) {
+	value, ok := sharedCache.get(GoString(unsafe.Pointer(key)))
+	if !ok {
+		return C.bool(false), nil
+	}
+
+	ptr := PHPValue(value)
+	phpThreads[threadIndex].Pin(ptr)
+
+	return C.bool(true), ptr
+}
+
+//export go_frankenphp_cache_set
+func go_frankenphp_cache_set(key *C.zend_string, value *C.zval, ttl C.zend_long) C.bool {
+	return C.bool(sharedCache.set(GoString(unsafe.Pointer(key)), GoValue(unsafe.Pointer(value)), time.Duration(ttl)*time.Second))
+}
+
+//export go_frankenphp_cache_delete
+func go_frankenphp_cache_delete(key *C.zend_string) C.bool {
+	return C.bool(sharedCache.delete(GoString(unsafe.Pointer(key))))
+}
+
+//export go_frankenphp_cache_incr
+func go_frankenphp_cache_incr(key *C.zend_string, step C.zend_long, ttl C.zend_long) (C.zend_long, C.bool) {
+	n, err := sharedCache.incr(GoString(unsafe.Pointer(key)), int64(step), time.Duration(ttl)*time.Second)
+
+	return C.zend_long(n), C.bool(err == nil)
+}
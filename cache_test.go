@@ -0,0 +1,65 @@
+package frankenphp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCache(3 * (1 + cacheValueSize(int64(0))))
+
+	require.True(t, c.set("a", int64(1), 0))
+	require.True(t, c.set("b", int64(2), 0))
+	require.True(t, c.set("c", int64(3), 0))
+
+	// "a" becomes the most recently used entry
+	_, ok := c.get("a")
+	require.True(t, ok)
+
+	require.True(t, c.set("d", int64(4), 0))
+
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok = c.get(key)
+		assert.True(t, ok, key)
+	}
+}
+
+func TestCacheRejectsValuesLargerThanTheCache(t *testing.T) {
+	c := newCache(32)
+
+	assert.False(t, c.set("key", "a string that doesn't fit in the cache", 0))
+	_, ok := c.get("key")
+	assert.False(t, ok)
+}
+
+func TestCacheExpiresEntries(t *testing.T) {
+	c := newCache(defaultCacheMaxSize)
+
+	require.True(t, c.set("key", "value", time.Millisecond))
+	time.Sleep(2 * time.Millisecond)
+
+	_, ok := c.get("key")
+	assert.False(t, ok)
+	assert.Zero(t, c.size)
+}
+
+func TestCacheIncr(t *testing.T) {
+	c := newCache(defaultCacheMaxSize)
+
+	n, err := c.incr("counter", 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = c.incr("counter", 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), n)
+
+	c.set("string", "foo", 0)
+	_, err = c.incr("string", 1, 0)
+	assert.ErrorIs(t, err, errCacheNotInteger)
+}
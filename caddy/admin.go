@@ -2,10 +2,13 @@ package caddy
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/caddyserver/caddy/v2"
 	"github.com/dunglas/frankenphp"
+	"io"
 	"net/http"
+	"strings"
 )
 
 type FrankenPHPAdmin struct{}
@@ -29,6 +32,22 @@ func (admin FrankenPHPAdmin) Routes() []caddy.AdminRoute {
 			Pattern: "/frankenphp/threads",
 			Handler: caddy.AdminHandlerFunc(admin.threads),
 		},
+		{
+			Pattern: "/frankenphp/schedule",
+			Handler: caddy.AdminHandlerFunc(admin.schedule),
+		},
+		{
+			Pattern: "/frankenphp/queues",
+			Handler: caddy.AdminHandlerFunc(admin.queues),
+		},
+		{
+			Pattern: "/frankenphp/queues/",
+			Handler: caddy.AdminHandlerFunc(admin.queues),
+		},
+		{
+			Pattern: "/frankenphp/response-cache/purge",
+			Handler: caddy.AdminHandlerFunc(admin.purgeResponseCache),
+		},
 	}
 }
 
@@ -54,6 +73,94 @@ func (admin *FrankenPHPAdmin) threads(w http.ResponseWriter, _ *http.Request) er
 	return admin.success(w, string(prettyJson))
 }
 
+func (admin *FrankenPHPAdmin) schedule(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+
+	return admin.json(w, frankenphp.ScheduledTasks())
+}
+
+// queues handles the following routes:
+//
+//	GET /frankenphp/queues: lists the queues
+//	GET /frankenphp/queues/{name}/dead: lists the jobs in the dead-letter store of a queue
+//	POST /frankenphp/queues/{name}/dead/retry: moves the dead jobs back to the queue
+//	DELETE /frankenphp/queues/{name}/dead: deletes the dead jobs
+func (admin *FrankenPHPAdmin) queues(w http.ResponseWriter, r *http.Request) error {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/frankenphp/queues"), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		}
+
+		return admin.json(w, frankenphp.Queues())
+	}
+
+	name, action, _ := strings.Cut(path, "/")
+
+	var (
+		result any
+		err    error
+	)
+	switch {
+	case action == "dead" && r.Method == http.MethodGet:
+		result, err = frankenphp.DeadJobs(name)
+	case action == "dead" && r.Method == http.MethodDelete:
+		var n int
+		n, err = frankenphp.PurgeDeadJobs(name)
+		result = map[string]int{"purged": n}
+	case action == "dead/retry" && r.Method == http.MethodPost:
+		var n int
+		n, err = frankenphp.RetryDeadJobs(name)
+		result = map[string]int{"retried": n}
+	case action == "dead" || action == "dead/retry":
+		return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	default:
+		return admin.error(http.StatusNotFound, fmt.Errorf("not found"))
+	}
+
+	if errors.Is(err, frankenphp.ErrQueueNotFound) {
+		return admin.error(http.StatusNotFound, err)
+	}
+	if err != nil {
+		return admin.error(http.StatusInternalServerError, err)
+	}
+
+	return admin.json(w, result)
+}
+
+// purgeResponseCache removes the cached responses having one of the tags listed in the JSON body of the request,
+// or all cached responses if no tags are given:
+//
+//	POST /frankenphp/response-cache/purge {"tags": ["product-42"]}
+func (admin *FrankenPHPAdmin) purgeResponseCache(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return admin.error(http.StatusBadRequest, err)
+	}
+
+	n := frankenphp.PurgeResponseCache(body.Tags...)
+	caddy.Log().Info("response cache purged from admin api")
+
+	return admin.json(w, map[string]int{"purged": n})
+}
+
+func (admin *FrankenPHPAdmin) json(w http.ResponseWriter, v any) error {
+	prettyJson, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return admin.error(http.StatusInternalServerError, err)
+	}
+
+	return admin.success(w, string(prettyJson))
+}
+
 func (admin *FrankenPHPAdmin) success(w http.ResponseWriter, message string) error {
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte(message))
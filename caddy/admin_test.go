@@ -7,12 +7,15 @@ import (
 	"github.com/dunglas/frankenphp/internal/fastabs"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/caddyserver/caddy/v2/caddytest"
 	"github.com/dunglas/frankenphp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRestartWorkerViaAdminApi(t *testing.T) {
@@ -297,3 +300,83 @@ func TestAddModuleWorkerViaAdminApi(t *testing.T) {
 	// Make a request to the worker to verify it's working
 	tester.AssertGetResponse("http://localhost:"+testPort+"/worker-with-counter.php", http.StatusOK, "requests:1")
 }
+
+func TestInspectQueuesViaAdminApi(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+
+			frankenphp {
+				queue_dir `+t.TempDir()+`
+				worker {
+					file ../testdata/queue-worker.php
+					num 1
+					queue jobs
+				}
+			}
+		}
+
+		localhost:`+testPort+` {
+			route {
+				root ../testdata
+				php
+			}
+		}
+		`, "caddyfile")
+
+	r, err := http.NewRequest("GET", "http://localhost:"+testPort+"/queue-push.php?fail=1", nil)
+	require.NoError(t, err)
+	tester.AssertResponseCode(r, http.StatusOK)
+
+	var queues []frankenphp.QueueInfo
+	assert.Eventually(t, func() bool {
+		queues = nil
+		_ = json.Unmarshal([]byte(getAdminResponseBody(t, tester, "GET", "queues")), &queues)
+
+		return len(queues) == 1 && queues[0].Dead == 1
+	}, 10*time.Second, 100*time.Millisecond)
+	assert.Equal(t, "jobs", queues[0].Name)
+
+	var dead []frankenphp.QueueJob
+	require.NoError(t, json.Unmarshal([]byte(getAdminResponseBody(t, tester, "GET", "queues/jobs/dead")), &dead))
+	require.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, "job failed")
+
+	assertAdminResponse(t, tester, "DELETE", "queues/jobs/dead", http.StatusOK, "{\n    \"purged\": 1\n}")
+	assertAdminResponse(t, tester, "GET", "queues/unknown/dead", http.StatusNotFound, "")
+}
+
+func TestPurgeResponseCacheViaAdminApi(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+		}
+
+		localhost:`+testPort+` {
+			root ../testdata
+			php_server {
+				response_cache
+			}
+		}
+		`, "caddyfile")
+
+	url := "http://localhost:" + testPort + "/response-cache.php?i=0&run=" + t.Name()
+	tester.AssertGetResponse(url, http.StatusOK, "generation 1")
+	tester.AssertGetResponse(url, http.StatusOK, "generation 1")
+
+	r, err := http.NewRequest("POST", "http://localhost:2999/frankenphp/response-cache/purge", strings.NewReader(`{"tags": ["page-0"]}`))
+	require.NoError(t, err)
+	_, _ = tester.AssertResponse(r, http.StatusOK, "{\n    \"purged\": 1\n}")
+
+	tester.AssertGetResponse(url, http.StatusOK, "generation 2")
+
+	assertAdminResponse(t, tester, "POST", "response-cache/purge", http.StatusOK, "{\n    \"purged\": 1\n}")
+	assertAdminResponse(t, tester, "GET", "response-cache/purge", http.StatusMethodNotAllowed, "")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -16,6 +17,8 @@ import (
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
 	"github.com/dunglas/frankenphp"
 	"github.com/dunglas/frankenphp/internal/fastabs"
+	"github.com/dustin/go-humanize"
+	"google.golang.org/grpc"
 )
 
 // FrankenPHPApp represents the global "frankenphp" directive in the Caddyfile
@@ -37,9 +40,25 @@ type FrankenPHPApp struct {
 	PhpIni map[string]string `json:"php_ini,omitempty"`
 	// The maximum amount of time a request may be stalled waiting for a thread
 	MaxWaitTime time.Duration `json:"max_wait_time,omitempty"`
-
-	metrics frankenphp.Metrics
-	logger  *slog.Logger
+	// The directory storing the job queues. Default: the "frankenphp/queues" directory in Caddy's data directory if a worker consumes a queue
+	QueueDir string `json:"queue_dir,omitempty"`
+	// Schedule runs scripts or worker callbacks according to cron expressions
+	Schedule []scheduleConfig `json:"schedule,omitempty"`
+	// The approximate memory limit in bytes of the cache shared by all PHP threads. Default: 64MiB
+	CacheMaxSize int64 `json:"cache_max_size,omitempty"`
+	// The approximate size limit in bytes of the response cache. Default: 64MiB
+	ResponseCacheMaxSize int64 `json:"response_cache_max_size,omitempty"`
+	// The directory storing the cached responses. Default: responses are stored in memory
+	ResponseCacheDir string `json:"response_cache_dir,omitempty"`
+	// FastCGI accepts FastCGI connections from web servers such as NGINX or Apache
+	FastCGI []fastCGIConfig `json:"fastcgi,omitempty"`
+	// GRPC handles the unary gRPC calls with worker scripts
+	GRPC []grpcConfig `json:"grpc,omitempty"`
+
+	metrics          frankenphp.Metrics
+	logger           *slog.Logger
+	fastCGIListeners []net.Listener
+	grpcServers      []*grpc.Server
 }
 
 var iniError = errors.New("'php_ini' must be in the format: php_ini \"<key>\" \"<value>\"")
@@ -117,28 +136,80 @@ func (f *FrankenPHPApp) Start() error {
 		frankenphp.WithMetrics(f.metrics),
 		frankenphp.WithPhpIni(f.PhpIni),
 		frankenphp.WithMaxWaitTime(f.MaxWaitTime),
+		frankenphp.WithMercurePublisher(publishMercureUpdate),
 	}
+	queueDir := f.QueueDir
 	for _, w := range append(f.Workers) {
 		workerOpts := []frankenphp.WorkerOption{
 			frankenphp.WithWorkerEnv(w.Env),
 			frankenphp.WithWorkerWatchMode(w.Watch),
 			frankenphp.WithWorkerMaxFailures(w.MaxConsecutiveFailures),
+			frankenphp.WithWorkerQueues(w.Queues...),
+		}
+
+		if queueDir == "" && len(w.Queues) > 0 {
+			queueDir = filepath.Join(caddy.AppDataDir(), "frankenphp", "queues")
 		}
 
 		opts = append(opts, frankenphp.WithWorkers(w.Name, repl.ReplaceKnown(w.FileName, ""), w.Num, workerOpts...))
 	}
+	opts = append(opts, frankenphp.WithQueueDir(repl.ReplaceKnown(queueDir, "")))
+
+	if f.CacheMaxSize > 0 {
+		opts = append(opts, frankenphp.WithCacheMaxSize(f.CacheMaxSize))
+	}
+
+	if f.ResponseCacheMaxSize > 0 {
+		opts = append(opts, frankenphp.WithResponseCacheMaxSize(f.ResponseCacheMaxSize))
+	}
+	if f.ResponseCacheDir != "" {
+		opts = append(opts, frankenphp.WithResponseCacheDir(repl.ReplaceKnown(f.ResponseCacheDir, "")))
+	}
+
+	for _, s := range f.Schedule {
+		if s.Worker != "" {
+			opts = append(opts, frankenphp.WithScheduledWorker(s.Spec, s.Worker))
+		} else {
+			opts = append(opts, frankenphp.WithScheduledScript(s.Spec, repl.ReplaceKnown(s.FileName, "")))
+		}
+	}
 
 	frankenphp.Shutdown()
 	if err := frankenphp.Init(opts...); err != nil {
 		return err
 	}
 
+	for _, fc := range f.FastCGI {
+		l, err := fc.listen(repl, f.logger)
+		if err != nil {
+			return err
+		}
+
+		f.fastCGIListeners = append(f.fastCGIListeners, l)
+	}
+
+	for _, gc := range f.GRPC {
+		srv, err := gc.serve(repl, f.logger)
+		if err != nil {
+			return err
+		}
+
+		f.grpcServers = append(f.grpcServers, srv)
+	}
+
 	return nil
 }
 
 func (f *FrankenPHPApp) Stop() error {
 	f.logger.Info("FrankenPHP stopped 🐘")
 
+	for _, l := range f.fastCGIListeners {
+		_ = l.Close()
+	}
+	for _, srv := range f.grpcServers {
+		srv.GracefulStop()
+	}
+
 	// attempt a graceful shutdown if caddy is exiting
 	// note: Exiting() is currently marked as 'experimental'
 	// https://github.com/caddyserver/caddy/blob/e76405d55058b0a3e5ba222b44b5ef00516116aa/caddy.go#L810
@@ -150,6 +221,15 @@ func (f *FrankenPHPApp) Stop() error {
 	f.Workers = nil
 	f.NumThreads = 0
 	f.MaxWaitTime = 0
+	f.QueueDir = ""
+	f.Schedule = nil
+	f.CacheMaxSize = 0
+	f.ResponseCacheMaxSize = 0
+	f.ResponseCacheDir = ""
+	f.FastCGI = nil
+	f.fastCGIListeners = nil
+	f.GRPC = nil
+	f.grpcServers = nil
 
 	return nil
 }
@@ -198,6 +278,61 @@ func (f *FrankenPHPApp) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 				}
 
 				f.MaxWaitTime = v
+			case "queue_dir":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				f.QueueDir = d.Val()
+			case "cache_max_size":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				v, err := humanize.ParseBytes(d.Val())
+				if err != nil || v == 0 {
+					return errors.New("cache_max_size must be a valid size (example: 64MiB)")
+				}
+
+				f.CacheMaxSize = int64(v)
+			case "response_cache_max_size":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				v, err := humanize.ParseBytes(d.Val())
+				if err != nil || v == 0 {
+					return errors.New("response_cache_max_size must be a valid size (example: 64MiB)")
+				}
+
+				f.ResponseCacheMaxSize = int64(v)
+			case "response_cache_dir":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				f.ResponseCacheDir = d.Val()
+			case "schedule":
+				sc, err := parseScheduleConfig(d)
+				if err != nil {
+					return err
+				}
+
+				f.Schedule = append(f.Schedule, sc)
+			case "fastcgi":
+				fc, err := parseFastCGIConfig(d)
+				if err != nil {
+					return err
+				}
+
+				f.FastCGI = append(f.FastCGI, fc)
+			case "grpc":
+				gc, err := parseGRPCConfig(d)
+				if err != nil {
+					return err
+				}
+
+				f.GRPC = append(f.GRPC, gc)
 			case "php_ini":
 				parseIniLine := func(d *caddyfile.Dispenser) error {
 					key := d.Val()
@@ -254,7 +389,7 @@ func (f *FrankenPHPApp) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 
 				f.Workers = append(f.Workers, wc)
 			default:
-				allowedDirectives := "num_threads, max_threads, php_ini, worker, max_wait_time"
+				allowedDirectives := "num_threads, max_threads, php_ini, worker, max_wait_time, queue_dir, schedule, cache_max_size, response_cache_max_size, response_cache_dir, fastcgi, grpc"
 				return wrongSubDirectiveError("frankenphp", allowedDirectives, d.Val())
 			}
 		}
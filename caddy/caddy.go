@@ -15,6 +15,8 @@ import (
 const (
 	defaultDocumentRoot = "public"
 	defaultWatchPattern = "./**/*.{php,yaml,yml,twig,env}"
+	// defaultResponseBufferMaxMemory is the size of the responses kept in memory when response_buffering is enabled without a size
+	defaultResponseBufferMaxMemory = 1 << 20
 )
 
 func init() {
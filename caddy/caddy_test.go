@@ -3,6 +3,7 @@ package caddy_test
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -389,6 +390,131 @@ func TestPHPServerDirective(t *testing.T) {
 	tester.AssertGetResponse("http://localhost:"+testPort+"/not-found.txt", http.StatusOK, "I am by birth a Genevese (i not set)")
 }
 
+func TestPHPServerDirectiveSendFileDir(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+			https_port 9443
+		}
+
+		localhost:`+testPort+` {
+			root ../testdata
+			php_server {
+				send_file_dir ../testdata/files
+			}
+		}
+		`, "caddyfile")
+
+	expectedFileResponse, err := os.ReadFile("../testdata/files/static.txt")
+	require.NoError(t, err, "static.txt file must be readable for this test")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/send-file.php?file=files/static.txt", http.StatusOK, string(expectedFileResponse))
+	tester.AssertGetResponse("http://localhost:"+testPort+"/send-file.php?file=hello.txt", http.StatusOK, "the file is not in a directory allowed to be sent")
+}
+
+func TestPHPServerDirectiveResponseBuffering(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+			https_port 9443
+		}
+
+		localhost:`+testPort+` {
+			root ../testdata
+			php_server {
+				response_buffering 1KiB
+			}
+		}
+		`, "caddyfile")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/large-response.php", http.StatusOK, strings.Repeat("Hey\n", 1024))
+	tester.AssertGetResponse("http://localhost:"+testPort+"/flush.php?i=1", http.StatusOK, "Hello 1")
+}
+
+func TestPHPServerDirectiveRequestCoalescing(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+			https_port 9443
+		}
+
+		localhost:`+testPort+` {
+			root ../testdata
+			php_server {
+				request_coalescing Accept-Language
+			}
+		}
+		`, "caddyfile")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/request-coalescing.php?i=0&run=caddy", http.StatusOK, "generation 1")
+	tester.AssertGetResponse("http://localhost:"+testPort+"/request-coalescing.php?i=0&run=caddy", http.StatusOK, "generation 2")
+}
+
+func TestPHPServerDirectiveESI(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+			https_port 9443
+		}
+
+		localhost:`+testPort+` {
+			root ../testdata
+			php_server {
+				esi
+			}
+		}
+		`, "caddyfile")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/esi.php", http.StatusOK, `frankenphp="ESI/1.0":<p>fragment a</p><p>fragment b</p>fragment altfragment c`)
+}
+
+func TestPHPServerDirectiveInternalRedirect(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+			https_port 9443
+		}
+
+		localhost:`+testPort+` {
+			root ../testdata
+			handle /internal/* {
+				respond "internal {uri} {method}"
+			}
+			handle {
+				header X-Kept 1
+				php_server {
+					internal_redirect
+				}
+			}
+		}
+		`, "caddyfile")
+
+	resp, _ := tester.AssertGetResponse("http://localhost:"+testPort+"/internal-redirect.php?to=/internal/file.txt%3Fa%3Db", http.StatusOK, "internal /internal/file.txt?a=b GET")
+	require.Empty(t, resp.Header.Get("X-Discarded"))
+	// the headers set before the script ran are kept
+	require.Equal(t, "1", resp.Header.Get("X-Kept"))
+
+	tester.AssertPostResponseBody("http://localhost:"+testPort+"/internal-redirect.php?to=/internal/post", nil, bytes.NewBufferString("body"), http.StatusOK, "internal /internal/post GET")
+
+	// redirecting to itself
+	tester.AssertGetResponse("http://localhost:"+testPort+"/internal-redirect.php", http.StatusInternalServerError, "")
+}
+
 func TestPHPServerDirectiveDisableFileServer(t *testing.T) {
 	tester := caddytest.NewTester(t)
 	tester.InitServer(`
@@ -1423,3 +1549,106 @@ func TestWorkerMatchDirectiveWithoutFileServer(t *testing.T) {
 	// the request should completely fall through the php_server module
 	tester.AssertGetResponse("http://localhost:"+testPort+"/static.txt", http.StatusNotFound, "Request falls through")
 }
+
+func TestMercurePublish(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+		}
+
+		localhost:`+testPort+` {
+			mercure {
+				transport local
+				publisher_jwt !ChangeThisMercureHubJWTSecretKey!
+				anonymous
+			}
+
+			route {
+				root ../testdata
+				php
+			}
+		}
+		`, "caddyfile")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/mercure-publish.php?id=urn:book:1", http.StatusOK, "urn:book:1")
+
+	r, err := http.NewRequest("GET", "http://localhost:"+testPort+"/mercure-publish.php", nil)
+	require.NoError(t, err)
+	resp := tester.AssertResponseCode(r, http.StatusOK)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(body), "urn:uuid:"), string(body))
+}
+
+func TestMercurePublishWithoutHub(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+		}
+
+		localhost:`+testPort+` {
+			route {
+				root ../testdata
+				php
+			}
+		}
+		`, "caddyfile")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/mercure-publish.php", http.StatusOK, `no Mercure hub is configured, add the "mercure" directive to the Caddyfile or use the --mercure flag`)
+}
+
+func TestFastCGI(t *testing.T) {
+	testDataDir, err := filepath.Abs("../testdata")
+	require.NoError(t, err)
+
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+
+			frankenphp {
+				fastcgi 127.0.0.1:9081 {
+					root ../testdata
+				}
+			}
+		}
+
+		localhost:`+testPort+` {
+			root ../testdata
+
+			handle /outside-root {
+				reverse_proxy 127.0.0.1:9081 {
+					transport fastcgi {
+						env SCRIPT_FILENAME `+testDataDir+`/../frankenphp.stub.php
+					}
+				}
+			}
+
+			handle /not-php {
+				reverse_proxy 127.0.0.1:9081 {
+					transport fastcgi {
+						env SCRIPT_FILENAME `+testDataDir+`/files/static.txt
+					}
+				}
+			}
+
+			handle {
+				php_fastcgi 127.0.0.1:9081
+			}
+		}
+		`, "caddyfile")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/fastcgi.php?i=1", http.StatusOK, "GET /fastcgi.php?i=1\nSCRIPT_NAME: /fastcgi.php\nPATH_INFO: \nAPP_ENV: \nX-Foo: \ni: 1\nbody: ")
+	tester.AssertGetResponse("http://localhost:"+testPort+"/outside-root", http.StatusForbidden, "")
+	tester.AssertGetResponse("http://localhost:"+testPort+"/not-php", http.StatusForbidden, "")
+}
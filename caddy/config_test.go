@@ -1,6 +1,7 @@
 package caddy
 
 import (
+	"net/http/httptest"
 	"testing"
 
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
@@ -220,3 +221,128 @@ func TestModuleWorkerWithCustomName(t *testing.T) {
 	require.Equal(t, "m#custom-worker-name", module.Workers[0].Name, "Worker should have the custom name, prefixed with m#")
 	require.Equal(t, "m#custom-worker-name", app.Workers[0].Name, "Worker should have the custom name, prefixed with m#")
 }
+
+func TestModuleWorkerWithWebSocketPaths(t *testing.T) {
+	configWithWebSocket := `
+	{
+		php {
+			worker {
+				file ../testdata/worker-websocket.php
+				websocket /chat /notifications/* {
+					allowed_origins https://app.example.com
+				}
+			}
+		}
+	}`
+
+	d := caddyfile.NewTestDispenser(configWithWebSocket)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d), "Expected no error when configuring a worker with WebSocket paths")
+	require.Len(t, module.Workers, 1, "Expected one worker to be added to the module")
+	require.Equal(t, []string{"/chat", "/notifications/*"}, module.Workers[0].WebSocketPath)
+	require.Equal(t, []string{"https://app.example.com"}, module.Workers[0].WebSocketAllowedOrigins)
+
+	upgrade := httptest.NewRequest("GET", "/notifications/1", nil)
+	upgrade.Header.Set("Upgrade", "websocket")
+	require.True(t, module.Workers[0].handlesWebSocket(upgrade), "Upgrade requests to a WebSocket path must be handled by the worker")
+	require.False(t, module.Workers[0].handlesWebSocket(httptest.NewRequest("GET", "/chat", nil)), "Regular requests must not be upgraded")
+
+	other := httptest.NewRequest("GET", "/other", nil)
+	other.Header.Set("Upgrade", "websocket")
+	require.False(t, module.Workers[0].handlesWebSocket(other), "Upgrade requests to other paths must not be handled by the worker")
+}
+
+func TestScheduleConfig(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		worker {
+			name my-worker
+			file ../testdata/worker-call.php
+		}
+		schedule "*/5 * * * *" ../testdata/hello.php
+		schedule @hourly worker my-worker
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Equal(t, []scheduleConfig{
+		{Spec: "*/5 * * * *", FileName: "../testdata/hello.php"},
+		{Spec: "@hourly", Worker: "my-worker"},
+	}, app.Schedule)
+}
+
+func TestScheduleConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		`schedule "* * *" ../testdata/hello.php`,
+		`schedule "* * * * *"`,
+		`schedule "* * * * *" job my-worker`,
+	} {
+		d := caddyfile.NewTestDispenser(`
+		frankenphp {
+			` + config + `
+		}`)
+		app := &FrankenPHPApp{}
+
+		require.Error(t, app.UnmarshalCaddyfile(d), config)
+	}
+}
+
+func TestFastCGIConfig(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		fastcgi 127.0.0.1:9000
+		fastcgi unix//run/frankenphp.sock {
+			root ../testdata
+		}
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Equal(t, []fastCGIConfig{
+		{Listen: "127.0.0.1:9000"},
+		{Listen: "unix//run/frankenphp.sock", Root: "../testdata"},
+	}, app.FastCGI)
+
+	for _, config := range []string{
+		`fastcgi`,
+		`fastcgi 127.0.0.1:9000 ../testdata`,
+		`fastcgi 127.0.0.1:9000 {
+			index index.php
+		}`,
+	} {
+		d := caddyfile.NewTestDispenser(`
+		frankenphp {
+			` + config + `
+		}`)
+
+		require.Error(t, (&FrankenPHPApp{}).UnmarshalCaddyfile(d), config)
+	}
+}
+
+func TestGRPCConfig(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		worker {
+			name grpc
+			file ../testdata/worker-grpc.php
+		}
+		grpc 127.0.0.1:50051 grpc
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Equal(t, []grpcConfig{{Listen: "127.0.0.1:50051", Worker: "grpc"}}, app.GRPC)
+
+	for _, config := range []string{
+		`grpc 127.0.0.1:50051`,
+		`grpc 127.0.0.1:50051 grpc extra`,
+	} {
+		d := caddyfile.NewTestDispenser(`
+		frankenphp {
+			` + config + `
+		}`)
+
+		require.Error(t, (&FrankenPHPApp{}).UnmarshalCaddyfile(d), config)
+	}
+}
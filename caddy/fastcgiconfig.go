@@ -0,0 +1,119 @@
+package caddy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/dunglas/frankenphp"
+	"github.com/dunglas/frankenphp/internal/fastabs"
+)
+
+// fastCGIConfig represents the "fastcgi" directive in the Caddyfile
+// it can appear in the "frankenphp" global option
+//
+//	frankenphp {
+//		fastcgi 127.0.0.1:9000
+//		fastcgi unix//run/frankenphp.sock {
+//			root /var/www/public
+//		}
+//	}
+type fastCGIConfig struct {
+	// Listen is the network address to accept FastCGI connections on
+	Listen string `json:"listen"`
+	// Root sets the directory containing the scripts the FastCGI clients can execute, defaults to the current directory
+	Root string `json:"root,omitempty"`
+}
+
+func parseFastCGIConfig(d *caddyfile.Dispenser) (fastCGIConfig, error) {
+	fc := fastCGIConfig{}
+
+	if !d.NextArg() {
+		return fc, d.ArgErr()
+	}
+	fc.Listen = d.Val()
+	if d.NextArg() {
+		return fc, d.ArgErr()
+	}
+
+	for d.NextBlock(1) {
+		switch d.Val() {
+		case "root":
+			if !d.NextArg() {
+				return fc, d.ArgErr()
+			}
+
+			fc.Root = d.Val()
+		default:
+			return fc, wrongSubDirectiveError("fastcgi", "root", d.Val())
+		}
+	}
+
+	if _, err := caddy.ParseNetworkAddress(fc.Listen); err != nil {
+		return fc, d.WrapErr(err)
+	}
+
+	if fc.Root != "" && frankenphp.EmbeddedAppPath != "" && filepath.IsLocal(fc.Root) {
+		fc.Root = filepath.Join(frankenphp.EmbeddedAppPath, fc.Root)
+	}
+
+	return fc, nil
+}
+
+// listen accepts FastCGI connections in the background until the returned listener is closed
+func (fc fastCGIConfig) listen(repl *caddy.Replacer, logger *slog.Logger) (net.Listener, error) {
+	addr, err := caddy.ParseNetworkAddress(repl.ReplaceKnown(fc.Listen, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := addr.Listen(context.Background(), 0, net.ListenConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	nl, ok := ln.(net.Listener)
+	if !ok {
+		return nil, fmt.Errorf("FastCGI requires a stream-oriented address: %q", fc.Listen)
+	}
+	l := &fastCGIListener{Listener: nl}
+
+	var opts []frankenphp.RequestOption
+	if fc.Root != "" {
+		root, err := fastabs.FastAbs(repl.ReplaceKnown(fc.Root, ""))
+		if err != nil {
+			_ = l.Close()
+
+			return nil, err
+		}
+
+		opts = append(opts, frankenphp.WithRequestDocumentRoot(root, false))
+	}
+
+	go func() {
+		if err := frankenphp.ServeFastCGI(l, opts...); err != nil && !l.closed.Load() {
+			logger.LogAttrs(context.Background(), slog.LevelError, "FastCGI listener stopped", slog.String("address", fc.Listen), slog.Any("error", err))
+		}
+	}()
+
+	return l, nil
+}
+
+// fastCGIListener distinguishes the errors returned after the listener has been closed,
+// listeners shared by Caddy between configurations are only closed for real when the last one stops using them
+type fastCGIListener struct {
+	net.Listener
+
+	closed atomic.Bool
+}
+
+func (l *fastCGIListener) Close() error {
+	l.closed.Store(true)
+
+	return l.Listener.Close()
+}
@@ -0,0 +1,75 @@
+package caddy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	frankenphpgrpc "github.com/dunglas/frankenphp/grpc"
+	"google.golang.org/grpc"
+)
+
+// grpcConfig represents the "grpc" directive in the Caddyfile
+// it can appear in the "frankenphp" global option
+//
+//	frankenphp {
+//		worker {
+//			name grpc-worker
+//			file grpc-worker.php
+//		}
+//		grpc 127.0.0.1:50051 grpc-worker
+//	}
+type grpcConfig struct {
+	// Listen is the network address to accept gRPC connections on
+	Listen string `json:"listen"`
+	// Worker is the name of the worker handling the calls
+	Worker string `json:"worker"`
+}
+
+func parseGRPCConfig(d *caddyfile.Dispenser) (grpcConfig, error) {
+	gc := grpcConfig{}
+	args := d.RemainingArgs()
+
+	if len(args) != 2 {
+		return gc, d.Errf(`"grpc" must be in the format: grpc <address> <worker name>`)
+	}
+	gc.Listen = args[0]
+	gc.Worker = args[1]
+
+	if _, err := caddy.ParseNetworkAddress(gc.Listen); err != nil {
+		return gc, d.WrapErr(err)
+	}
+
+	return gc, nil
+}
+
+// serve handles the gRPC calls in the background until the returned server is stopped
+func (gc grpcConfig) serve(repl *caddy.Replacer, logger *slog.Logger) (*grpc.Server, error) {
+	addr, err := caddy.ParseNetworkAddress(repl.ReplaceKnown(gc.Listen, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := addr.Listen(context.Background(), 0, net.ListenConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	l, ok := ln.(net.Listener)
+	if !ok {
+		return nil, fmt.Errorf("gRPC requires a stream-oriented address: %q", gc.Listen)
+	}
+
+	srv := frankenphpgrpc.NewServer(gc.Worker, logger)
+	go func() {
+		// Serve returns nil once the server is stopped
+		if err := srv.Serve(l); err != nil {
+			logger.LogAttrs(context.Background(), slog.LevelError, "gRPC server stopped", slog.String("address", gc.Listen), slog.Any("error", err))
+		}
+	}()
+
+	return srv, nil
+}
@@ -0,0 +1,129 @@
+package caddy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// maxInternalRedirects prevents infinite loops when the target of an internal redirect redirects again
+const maxInternalRedirects = 10
+
+type internalRedirectsKeyStruct struct{}
+
+var internalRedirectsKey = internalRedirectsKeyStruct{}
+
+var errTooManyInternalRedirects = errors.New("too many internal redirects")
+
+// internalRedirectWriter discards the response of the script if it has an X-Accel-Redirect header
+type internalRedirectWriter struct {
+	http.ResponseWriter
+
+	// header holds the headers set before the script ran, by the previous handlers
+	header http.Header
+	// target is the URI of the internal redirect
+	target      string
+	wroteHeader bool
+}
+
+func newInternalRedirectWriter(w http.ResponseWriter) *internalRedirectWriter {
+	return &internalRedirectWriter{ResponseWriter: w, header: w.Header().Clone()}
+}
+
+func (w *internalRedirectWriter) WriteHeader(statusCode int) {
+	if w.target != "" || w.wroteHeader {
+		return
+	}
+
+	if statusCode >= 100 && statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+
+		return
+	}
+
+	h := w.Header()
+	if target := h.Get("X-Accel-Redirect"); target != "" {
+		w.target = target
+		// only the headers set by the script are dropped, the handlers of the new URI set theirs
+		clear(h)
+		maps.Copy(h, w.header)
+
+		return
+	}
+
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *internalRedirectWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader && w.target == "" {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.target != "" {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *internalRedirectWriter) FlushError() error {
+	if !w.wroteHeader && w.target == "" {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.target != "" {
+		return nil
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *internalRedirectWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *internalRedirectWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// internalRedirect passes the request with the URI set by the script through the routes of the server again,
+// like NGINX's X-Accel-Redirect. The body of the request is discarded and its method is changed to GET.
+// The request isn't logged nor counted again, as with the handle_response routes of reverse_proxy.
+func internalRedirect(w http.ResponseWriter, r *http.Request, target string) error {
+	redirects, _ := r.Context().Value(internalRedirectsKey).(int)
+	if redirects >= maxInternalRedirects {
+		return caddyhttp.Error(http.StatusInternalServerError, errTooManyInternalRedirects)
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return caddyhttp.Error(http.StatusInternalServerError, fmt.Errorf("invalid internal redirect URI %q", target))
+	}
+
+	srv, ok := r.Context().Value(caddyhttp.ServerCtxKey).(*caddyhttp.Server)
+	if !ok {
+		return caddyhttp.Error(http.StatusInternalServerError, errors.New("internal redirects require the Caddy HTTP server"))
+	}
+
+	ir := r.Clone(context.WithValue(r.Context(), internalRedirectsKey, redirects+1))
+	if ir.Method != http.MethodHead {
+		ir.Method = http.MethodGet
+	}
+	ir.Body = http.NoBody
+	ir.ContentLength = 0
+	ir.Header.Del("Content-Length")
+	ir.Header.Del("Content-Type")
+	ir.URL.Path = u.Path
+	ir.URL.RawPath = u.RawPath
+	ir.URL.RawQuery = u.RawQuery
+	ir.RequestURI = ir.URL.RequestURI()
+
+	return srv.Routes.Compile(caddyhttp.HandlerFunc(func(http.ResponseWriter, *http.Request) error { return nil })).ServeHTTP(w, ir)
+}
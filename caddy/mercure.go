@@ -0,0 +1,49 @@
+package caddy
+
+import (
+	"errors"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/dunglas/mercure"
+	mercureCaddy "github.com/dunglas/mercure/caddy"
+)
+
+var (
+	errNoMercureHub       = errors.New(`no Mercure hub is configured, add the "mercure" directive to the Caddyfile or use the --mercure flag`)
+	errSeveralMercureHubs = errors.New("several Mercure hubs using different transports are configured, publish updates using HTTP instead")
+)
+
+// publishMercureUpdate dispatches the update directly to the transport of the Mercure hub running in the same process,
+// without authorization since the update comes from the server itself
+func publishMercureUpdate(u frankenphp.MercureUpdate) (string, error) {
+	var transports []mercure.Transport
+	mercureCaddy.TransportUsagePool.Range(func(_, value any) bool {
+		switch d := value.(type) {
+		case mercureCaddy.TransportDestructor[*mercure.BoltTransport]:
+			transports = append(transports, d.Transport)
+		case mercureCaddy.TransportDestructor[*mercure.LocalTransport]:
+			transports = append(transports, d.Transport)
+		}
+
+		return true
+	})
+
+	switch len(transports) {
+	case 0:
+		return "", errNoMercureHub
+	case 1:
+	default:
+		return "", errSeveralMercureHubs
+	}
+
+	update := &mercure.Update{
+		Topics:  u.Topics,
+		Private: u.Private,
+		Event:   mercure.Event{Data: u.Data, ID: u.ID, Type: u.Type, Retry: u.Retry},
+	}
+	if err := transports[0].Dispatch(update); err != nil {
+		return "", err
+	}
+
+	return update.ID, nil
+}
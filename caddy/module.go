@@ -2,6 +2,7 @@ package caddy
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -19,6 +20,7 @@ import (
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp/rewrite"
 	"github.com/dunglas/frankenphp"
 	"github.com/dunglas/frankenphp/internal/fastabs"
+	"github.com/dustin/go-humanize"
 )
 
 // FrankenPHPModule represents the "php_server" and "php" directives in the Caddyfile
@@ -40,6 +42,18 @@ type FrankenPHPModule struct {
 	Env map[string]string `json:"env,omitempty"`
 	// Workers configures the worker scripts to start.
 	Workers []workerConfig `json:"workers,omitempty"`
+	// SendFileDirs sets the directories containing the files that PHP scripts can send with frankenphp_send_file().
+	SendFileDirs []string `json:"send_file_dirs,omitempty"`
+	// ResponseBuffering enables the buffering of responses, up to this number of bytes are kept in memory before spilling to disk.
+	ResponseBuffering int64 `json:"response_buffering,omitempty"`
+	// ResponseCache enables the response cache, the responses are stored according to their Cache-Control header.
+	ResponseCache *responseCacheConfig `json:"response_cache,omitempty"`
+	// ESI enables the processing of the Edge Side Includes tags of HTML responses, fragments are handled as sub-requests.
+	ESI bool `json:"esi,omitempty"`
+	// InternalRedirect handles the request again with the URI of the X-Accel-Redirect header set by the script, its response is discarded.
+	InternalRedirect bool `json:"internal_redirect,omitempty"`
+	// RequestCoalescing enables request coalescing, identical requests wait for the one being handled and receive a copy of its response if it is shareable.
+	RequestCoalescing *requestCoalescingConfig `json:"request_coalescing,omitempty"`
 
 	resolvedDocumentRoot        string
 	preparedEnv                 frankenphp.PreparedEnv
@@ -47,6 +61,18 @@ type FrankenPHPModule struct {
 	logger                      *slog.Logger
 }
 
+// responseCacheConfig represents the "response_cache" option of the "php_server" and "php" directives
+type responseCacheConfig struct {
+	// Vary contains the request headers whose values are part of the cache key
+	Vary []string `json:"vary,omitempty"`
+}
+
+// requestCoalescingConfig represents the "request_coalescing" option of the "php_server" and "php" directives
+type requestCoalescingConfig struct {
+	// Vary contains the request headers whose values are part of the request fingerprint
+	Vary []string `json:"vary,omitempty"`
+}
+
 // CaddyModule returns the Caddy module information.
 func (FrankenPHPModule) CaddyModule() caddy.ModuleInfo {
 	return caddy.ModuleInfo{
@@ -131,6 +157,18 @@ func (f *FrankenPHPModule) Provision(ctx caddy.Context) error {
 		}
 	}
 
+	for i, dir := range f.SendFileDirs {
+		if frankenphp.EmbeddedAppPath != "" && filepath.IsLocal(dir) {
+			dir = filepath.Join(frankenphp.EmbeddedAppPath, dir)
+		}
+
+		dir, err := fastabs.FastAbs(dir)
+		if err != nil {
+			return fmt.Errorf("unable to make the send_file_dir path absolute: %w", err)
+		}
+		f.SendFileDirs[i] = dir
+	}
+
 	if f.preparedEnv == nil {
 		f.preparedEnv = frankenphp.PrepareEnv(f.Env)
 
@@ -177,6 +215,22 @@ func (f *FrankenPHPModule) ServeHTTP(w http.ResponseWriter, r *http.Request, _ c
 		}
 	}
 
+	for _, wc := range f.Workers {
+		if wc.handlesWebSocket(r) {
+			if err := frankenphp.ServeWebSocket(w, r, wc.Name, frankenphp.WithWebSocketAllowedOrigins(wc.WebSocketAllowedOrigins...)); err != nil {
+				return caddyhttp.Error(http.StatusInternalServerError, err)
+			}
+
+			return nil
+		}
+	}
+
+	var redirectWriter *internalRedirectWriter
+	if f.InternalRedirect {
+		redirectWriter = newInternalRedirectWriter(w)
+		w = redirectWriter
+	}
+
 	workerName := ""
 	for _, w := range f.Workers {
 		if w.matchesPath(r, documentRoot) {
@@ -185,19 +239,33 @@ func (f *FrankenPHPModule) ServeHTTP(w http.ResponseWriter, r *http.Request, _ c
 		}
 	}
 
-	fr, err := frankenphp.NewRequestWithContext(
-		r,
+	opts := []frankenphp.RequestOption{
 		documentRootOption,
 		frankenphp.WithRequestSplitPath(f.SplitPath),
 		frankenphp.WithRequestPreparedEnv(env),
 		frankenphp.WithOriginalRequest(&origReq),
 		frankenphp.WithWorkerName(workerName),
-	)
+		frankenphp.WithRequestSendFileDirs(f.SendFileDirs),
+		frankenphp.WithRequestResponseBuffering(f.ResponseBuffering),
+		frankenphp.WithRequestESI(f.ESI),
+	}
+	if f.ResponseCache != nil {
+		opts = append(opts, frankenphp.WithRequestResponseCache(f.ResponseCache.Vary))
+	}
+	if f.RequestCoalescing != nil {
+		opts = append(opts, frankenphp.WithRequestCoalescing(f.RequestCoalescing.Vary))
+	}
+
+	fr, err := frankenphp.NewRequestWithContext(r, opts...)
 
 	if err = frankenphp.ServeHTTP(w, fr); err != nil {
 		return caddyhttp.Error(http.StatusInternalServerError, err)
 	}
 
+	if redirectWriter != nil && redirectWriter.target != "" {
+		return internalRedirect(redirectWriter.ResponseWriter, r, redirectWriter.target)
+	}
+
 	return nil
 }
 
@@ -251,8 +319,51 @@ func (f *FrankenPHPModule) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 				}
 				f.Workers = append(f.Workers, wc)
 
+			case "send_file_dir":
+				args := d.RemainingArgs()
+				if len(args) == 0 {
+					return d.ArgErr()
+				}
+				f.SendFileDirs = append(f.SendFileDirs, args...)
+
+			case "response_buffering":
+				f.ResponseBuffering = defaultResponseBufferMaxMemory
+				if !d.NextArg() {
+					continue
+				}
+
+				v, err := humanize.ParseBytes(d.Val())
+				if err != nil || v == 0 {
+					return errors.New("response_buffering must be a valid size (example: 1MiB)")
+				}
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+
+				f.ResponseBuffering = int64(v)
+
+			case "response_cache":
+				f.ResponseCache = &responseCacheConfig{Vary: d.RemainingArgs()}
+
+			case "esi":
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+
+				f.ESI = true
+
+			case "internal_redirect":
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+
+				f.InternalRedirect = true
+
+			case "request_coalescing":
+				f.RequestCoalescing = &requestCoalescingConfig{Vary: d.RemainingArgs()}
+
 			default:
-				allowedDirectives := "root, split, env, resolve_root_symlink, worker"
+				allowedDirectives := "root, split, env, resolve_root_symlink, worker, send_file_dir, response_buffering, response_cache, request_coalescing, esi, internal_redirect"
 				return wrongSubDirectiveError("php or php_server", allowedDirectives, d.Val())
 			}
 		}
@@ -571,6 +682,9 @@ func prependWorkerRoutes(routes caddyhttp.RouteList, h httpcaddyfile.Helper, f F
 		for _, path := range w.MatchPath {
 			allWorkerMatches = append(allWorkerMatches, path)
 		}
+
+		// WebSocket paths must not be rewritten to the index
+		allWorkerMatches = append(allWorkerMatches, w.WebSocketPath...)
 	}
 
 	if len(allWorkerMatches) == 0 {
@@ -28,7 +28,7 @@ import (
 func init() {
 	caddycmd.RegisterCommand(caddycmd.Command{
 		Name:  "php-server",
-		Usage: "[--domain=<example.com>] [--root=<path>] [--listen=<addr>] [--worker=/path/to/worker.php<,nb-workers>] [--watch[=<glob-pattern>]]... [--access-log] [--debug] [--no-compress] [--mercure]",
+		Usage: "[--domain=<example.com>] [--root=<path>] [--listen=<addr>] [--fastcgi=<addr>] [--worker=/path/to/worker.php<,nb-workers>] [--watch[=<glob-pattern>]]... [--access-log] [--debug] [--no-compress] [--mercure]",
 		Short: "Spins up a production-ready PHP server",
 		Long: `
 A simple but production-ready PHP server. Useful for quick deployments,
@@ -36,6 +36,10 @@ demos, and development.
 
 The listener's socket address can be customized with the --listen flag.
 
+With --fastcgi, FastCGI connections (from NGINX or Apache for instance) are
+accepted on the given address, such as 127.0.0.1:9000 or unix//run/php.sock.
+The HTTP server is then only started if --listen or --domain is also set.
+
 If a domain name is specified with --domain, the default listener address
 will be changed to the HTTPS port and the server will use HTTPS. If using
 a public domain, ensure A/AAAA records are properly configured before
@@ -46,6 +50,7 @@ For more advanced use cases, see https://github.com/php/frankenphp/blob/main/doc
 			cmd.Flags().StringP("domain", "d", "", "Domain name at which to serve the files")
 			cmd.Flags().StringP("root", "r", "", "The path to the root of the site")
 			cmd.Flags().StringP("listen", "l", "", "The address to which to bind the listener")
+			cmd.Flags().String("fastcgi", "", "The address to which to bind the FastCGI listener")
 			cmd.Flags().StringArrayP("worker", "w", []string{}, "Worker script")
 			cmd.Flags().StringArray("watch", []string{}, "Glob pattern of directories and files to watch for changes")
 			cmd.Flags().BoolP("access-log", "a", false, "Enable the access log")
@@ -67,6 +72,9 @@ func cmdPHPServer(fs caddycmd.Flags) (int, error) {
 	domain := fs.String("domain")
 	root := fs.String("root")
 	listen := fs.String("listen")
+	fastCGI := fs.String("fastcgi")
+	// only FastCGI requests are served if no HTTP listener is explicitly configured
+	serveHTTP := fastCGI == "" || listen != "" || domain != ""
 	accessLog := fs.Bool("access-log")
 	debug := fs.Bool("debug")
 	compress := !fs.Bool("no-compress")
@@ -319,6 +327,11 @@ func cmdPHPServer(fs caddycmd.Flags) (int, error) {
 		Servers: map[string]*caddyhttp.Server{"php": server},
 	}
 
+	frankenPHPApp := FrankenPHPApp{Workers: workersOption}
+	if fastCGI != "" {
+		frankenPHPApp.FastCGI = []fastCGIConfig{{Listen: fastCGI, Root: root}}
+	}
+
 	var f bool
 	cfg := &caddy.Config{
 		Admin: &caddy.AdminConfig{
@@ -328,10 +341,12 @@ func cmdPHPServer(fs caddycmd.Flags) (int, error) {
 			},
 		},
 		AppsRaw: caddy.ModuleMap{
-			"http":       caddyconfig.JSON(httpApp, nil),
-			"frankenphp": caddyconfig.JSON(FrankenPHPApp{Workers: workersOption}, nil),
+			"frankenphp": caddyconfig.JSON(frankenPHPApp, nil),
 		},
 	}
+	if serveHTTP {
+		cfg.AppsRaw["http"] = caddyconfig.JSON(httpApp, nil)
+	}
 
 	if debug {
 		cfg.Logging = &caddy.Logging{
@@ -348,7 +363,12 @@ func cmdPHPServer(fs caddycmd.Flags) (int, error) {
 		return caddy.ExitCodeFailedStartup, err
 	}
 
-	log.Printf("Caddy serving PHP app on %s", listen)
+	if serveHTTP {
+		log.Printf("Caddy serving PHP app on %s", listen)
+	}
+	if fastCGI != "" {
+		log.Printf("FrankenPHP serving FastCGI requests on %s", fastCGI)
+	}
 
 	select {}
 }
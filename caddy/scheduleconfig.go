@@ -0,0 +1,51 @@
+package caddy
+
+import (
+	"path/filepath"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/dunglas/frankenphp"
+	"github.com/dunglas/frankenphp/internal/cron"
+)
+
+// scheduleConfig represents the "schedule" directive in the Caddyfile
+// it can appear in the "frankenphp" global option
+//
+//	frankenphp {
+//		schedule "*/5 * * * *" script.php
+//		schedule @hourly worker my-worker
+//	}
+type scheduleConfig struct {
+	// Spec is the cron expression
+	Spec string `json:"spec"`
+	// FileName sets the path to the script to run on a regular thread
+	FileName string `json:"file_name,omitempty"`
+	// Worker sets the name of the worker to call
+	Worker string `json:"worker,omitempty"`
+}
+
+func parseScheduleConfig(d *caddyfile.Dispenser) (scheduleConfig, error) {
+	sc := scheduleConfig{}
+	args := d.RemainingArgs()
+
+	switch {
+	case len(args) == 2:
+		sc.Spec = args[0]
+		sc.FileName = args[1]
+	case len(args) == 3 && args[1] == "worker":
+		sc.Spec = args[0]
+		sc.Worker = args[2]
+	default:
+		return sc, d.Errf(`"schedule" must be in the format: schedule "<cron expression>" <script> or schedule "<cron expression>" worker <name>`)
+	}
+
+	if _, err := cron.Parse(sc.Spec); err != nil {
+		return sc, d.WrapErr(err)
+	}
+
+	if sc.FileName != "" && frankenphp.EmbeddedAppPath != "" && filepath.IsLocal(sc.FileName) {
+		sc.FileName = filepath.Join(frankenphp.EmbeddedAppPath, sc.FileName)
+	}
+
+	return sc, nil
+}
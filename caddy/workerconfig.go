@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
@@ -37,6 +38,12 @@ type workerConfig struct {
 	MatchPath []string `json:"match_path,omitempty"`
 	// MaxConsecutiveFailures sets the maximum number of consecutive failures before panicking (defaults to 6, set to -1 to never panick)
 	MaxConsecutiveFailures int `json:"max_consecutive_failures,omitempty"`
+	// Queues sets the job queues consumed by the worker
+	Queues []string `json:"queues,omitempty"`
+	// The paths of the WebSocket connections whose events are handled by the worker
+	WebSocketPath []string `json:"websocket_path,omitempty"`
+	// The origins of the cross-origin pages allowed to open WebSocket connections, "*" allows all origins
+	WebSocketAllowedOrigins []string `json:"websocket_allowed_origins,omitempty"`
 }
 
 func parseWorkerConfig(d *caddyfile.Dispenser) (workerConfig, error) {
@@ -122,8 +129,38 @@ func parseWorkerConfig(d *caddyfile.Dispenser) (workerConfig, error) {
 			}
 
 			wc.MaxConsecutiveFailures = int(v)
+		case "queue":
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return wc, d.ArgErr()
+			}
+
+			wc.Queues = append(wc.Queues, args...)
+		case "websocket":
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return wc, d.ArgErr()
+			}
+
+			caddyMatchPath := (caddyhttp.MatchPath)(args)
+			caddyMatchPath.Provision(caddy.Context{})
+			wc.WebSocketPath = append(wc.WebSocketPath, caddyMatchPath...)
+
+			for nesting := d.Nesting(); d.NextBlock(nesting); {
+				switch v := d.Val(); v {
+				case "allowed_origins":
+					origins := d.RemainingArgs()
+					if len(origins) == 0 {
+						return wc, d.ArgErr()
+					}
+
+					wc.WebSocketAllowedOrigins = append(wc.WebSocketAllowedOrigins, origins...)
+				default:
+					return wc, wrongSubDirectiveError("websocket", "allowed_origins", v)
+				}
+			}
 		default:
-			allowedDirectives := "name, file, num, env, watch, match, max_consecutive_failures"
+			allowedDirectives := "name, file, num, env, watch, match, max_consecutive_failures, queue, websocket"
 			return wc, wrongSubDirectiveError("worker", allowedDirectives, v)
 		}
 	}
@@ -151,6 +188,13 @@ func (wc workerConfig) inheritEnv(env map[string]string) {
 	}
 }
 
+// handlesWebSocket checks if the worker handles the events of the WebSocket connection requested by r
+func (wc workerConfig) handlesWebSocket(r *http.Request) bool {
+	return len(wc.WebSocketPath) != 0 &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		(caddyhttp.MatchPath)(wc.WebSocketPath).Match(r)
+}
+
 func (wc workerConfig) matchesPath(r *http.Request, documentRoot string) bool {
 
 	// try to match against a pattern if one is assigned
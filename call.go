@@ -0,0 +1,86 @@
+package frankenphp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PHPException is returned when the PHP callback throws an exception that it doesn't catch.
+type PHPException struct {
+	Class   string
+	Message string
+	Code    int64
+}
+
+func (e *PHPException) Error() string {
+	return fmt.Sprintf("uncaught PHP exception %s: %s", e.Class, e.Message)
+}
+
+// Call sends params to the worker named workerName of the running server, see Server.Call.
+func Call(ctx context.Context, workerName string, params any) (any, error) {
+	s := activeServer.Load()
+	if s == nil {
+		return nil, ErrNotRunning
+	}
+
+	return s.Call(ctx, workerName, params)
+}
+
+// Call sends params to the worker named workerName without going through HTTP.
+// The callback passed to frankenphp_handle_request() receives params converted with PHPValue,
+// Call waits for it to finish and returns its return value converted with GoValue.
+//
+// If the callback throws, the exception is returned as a *PHPException and the worker script is restarted.
+// If ctx is done before the callback returns, Call returns ctx.Err() without waiting for the PHP thread.
+func (s *Server) Call(ctx context.Context, workerName string, params any) (any, error) {
+	if !s.Running() {
+		return nil, ErrNotRunning
+	}
+
+	w := s.getWorkerByName(workerName)
+	if w == nil {
+		return nil, fmt.Errorf("%w: %q", ErrWorkerNotFound, workerName)
+	}
+
+	if err := checkPHPValue(params); err != nil {
+		return nil, err
+	}
+
+	fc := newFrankenPHPContext()
+	fc.server = s
+	fc.logger = s.logger
+	fc.worker = w
+	fc.handlerParameters = params
+	fc.ctx, fc.cancel = context.WithCancel(ctx)
+
+	if !w.queueRequest(fc, ctx.Done()) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrMaxWaitTimeExceeded
+	}
+
+	// the request is only counted once a thread has taken it, it would never be stopped otherwise
+	s.metrics.StartWorkerRequest(w.name)
+
+	select {
+	case <-fc.done:
+	case <-ctx.Done():
+		go func() {
+			<-fc.done
+			s.metrics.StopWorkerRequest(w.name, time.Since(fc.startedAt))
+		}()
+
+		return nil, ctx.Err()
+	}
+
+	s.metrics.StopWorkerRequest(w.name, time.Since(fc.startedAt))
+
+	if fc.handlerError != nil {
+		return nil, fc.handlerError
+	}
+
+	return fc.handlerReturn, nil
+}
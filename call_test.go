@@ -0,0 +1,133 @@
+package frankenphp_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCall(t *testing.T) {
+	cwd, _ := os.Getwd()
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 1),
+	))
+	defer frankenphp.Shutdown()
+
+	ret, err := frankenphp.Call(context.Background(), "call", map[string]any{"a": 1, "b": int64(2)})
+	require.NoError(t, err)
+	assert.Equal(t, frankenphp.AssociativeArray{Map: map[string]any{"sum": int64(3)}, Order: []string{"sum"}}, ret)
+
+	_, err = frankenphp.Call(context.Background(), "call", map[string]any{"throw": true})
+	var phpErr *frankenphp.PHPException
+	require.ErrorAs(t, err, &phpErr)
+	assert.Equal(t, "RuntimeException", phpErr.Class)
+	assert.Equal(t, "something went wrong", phpErr.Message)
+	assert.Equal(t, int64(42), phpErr.Code)
+
+	// the worker script must have been restarted
+	ret, err = frankenphp.Call(context.Background(), "call", map[string]any{"a": 3, "b": 4})
+	require.NoError(t, err)
+	assert.Equal(t, frankenphp.AssociativeArray{Map: map[string]any{"sum": int64(7)}, Order: []string{"sum"}}, ret)
+}
+
+func TestCallHonorsContext(t *testing.T) {
+	cwd, _ := os.Getwd()
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 1),
+	))
+	defer frankenphp.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := frankenphp.Call(ctx, "call", map[string]any{"sleep": 500_000})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+
+	// the next call waits until the thread is available again
+	ret, err := frankenphp.Call(context.Background(), "call", map[string]any{"a": 1})
+	require.NoError(t, err)
+	assert.Equal(t, frankenphp.AssociativeArray{Map: map[string]any{"sum": int64(1)}, Order: []string{"sum"}}, ret)
+}
+
+func TestCallErrors(t *testing.T) {
+	_, err := frankenphp.Call(context.Background(), "call", nil)
+	assert.ErrorIs(t, err, frankenphp.ErrNotRunning)
+
+	cwd, _ := os.Getwd()
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 1),
+	))
+	defer frankenphp.Shutdown()
+
+	_, err = frankenphp.Call(context.Background(), "unknown", nil)
+	assert.ErrorIs(t, err, frankenphp.ErrWorkerNotFound)
+
+	_, err = frankenphp.Call(context.Background(), "call", map[string]any{"a": struct{}{}})
+	assert.ErrorContains(t, err, "unsupported Go type")
+}
+
+func TestCallBusyWorkersMetric(t *testing.T) {
+	cwd, _ := os.Getwd()
+	registry := prometheus.NewRegistry()
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithMetrics(frankenphp.NewPrometheusMetrics(registry)),
+		frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 1),
+	))
+	defer frankenphp.Shutdown()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		_, err := frankenphp.Call(context.Background(), "call", map[string]any{"sleep": 300_000})
+		assert.NoError(t, err)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	// the only thread is busy, this call is cancelled while waiting for it
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := frankenphp.Call(ctx, "call", nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	wg.Wait()
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var busyWorkers *float64
+	for _, family := range families {
+		if family.GetName() != "frankenphp_busy_workers" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "worker" && label.GetValue() == "call" {
+					busyWorkers = new(float64)
+					*busyWorkers = m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+
+	require.NotNil(t, busyWorkers)
+	assert.Zero(t, *busyWorkers)
+}
@@ -15,6 +15,7 @@ import (
 	"crypto/tls"
 	"net"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unsafe"
 
@@ -202,6 +203,17 @@ func addHeadersToServer(fc *frankenPHPContext, trackVarsArray *C.zval) {
 	}
 }
 
+// addDeadlineToServer exposes the deadline of the request context as a UNIX timestamp with microsecond precision
+func addDeadlineToServer(fc *frankenPHPContext, trackVarsArray *C.zval) {
+	deadline, ok := fc.deadline()
+	if !ok {
+		return
+	}
+
+	v := strconv.FormatFloat(float64(deadline.UnixMicro())/1e6, 'f', 6, 64)
+	C.frankenphp_register_variable_safe(toUnsafeChar("REQUEST_DEADLINE_FLOAT\x00"), toUnsafeChar(v), C.size_t(len(v)), trackVarsArray)
+}
+
 func addPreparedEnvToServer(fc *frankenPHPContext, trackVarsArray *C.zval) {
 	for k, v := range fc.env {
 		C.frankenphp_register_variable_safe(toUnsafeChar(k), toUnsafeChar(v), C.size_t(len(v)), trackVarsArray)
@@ -214,17 +226,22 @@ func go_register_variables(threadIndex C.uintptr_t, trackVarsArray *C.zval) {
 	thread := phpThreads[threadIndex]
 	fc := thread.getRequestContext()
 
-	if fc.request != nil {
+	if fc.cli != nil {
+		C.frankenphp_register_cli_variables(thread.pinCString(fc.cli.script), trackVarsArray)
+	} else if fc.request != nil {
 		addKnownVariablesToServer(thread, fc, trackVarsArray)
 		addHeadersToServer(fc, trackVarsArray)
 	}
 
+	addDeadlineToServer(fc, trackVarsArray)
+
 	// The Prepared Environment is registered last and can overwrite any previous values
 	addPreparedEnvToServer(fc, trackVarsArray)
 }
 
 // splitCgiPath splits the request path into SCRIPT_NAME, SCRIPT_FILENAME, PATH_INFO, DOCUMENT_URI
-func splitCgiPath(fc *frankenPHPContext) {
+// and matches the script against the workers of s
+func splitCgiPath(fc *frankenPHPContext, s *Server) {
 	path := fc.request.URL.Path
 	splitPath := fc.splitPath
 
@@ -249,7 +266,9 @@ func splitCgiPath(fc *frankenPHPContext) {
 	// TODO: is it possible to delay this and avoid saving everything in the context?
 	// SCRIPT_FILENAME is the absolute path of SCRIPT_NAME
 	fc.scriptFilename = sanitizedPathJoin(fc.documentRoot, fc.scriptName)
-	fc.worker = getWorkerByPath(fc.scriptFilename)
+	if s != nil {
+		fc.worker = s.getWorkerByPath(fc.scriptFilename)
+	}
 }
 
 // splitPos returns the index where path should
@@ -282,6 +301,20 @@ func go_update_request_info(threadIndex C.uintptr_t, info *C.sapi_request_info)
 	fc := thread.getRequestContext()
 	request := fc.request
 
+	if fc.cli != nil {
+		// $argv and $argc are built from the arguments of the command, see php_build_argv()
+		argv := make([]*C.char, len(fc.cli.argv))
+		for i, arg := range fc.cli.argv {
+			argv[i] = thread.pinCString(arg)
+		}
+		thread.Pin(&argv[0])
+
+		info.argc = C.int(len(argv))
+		info.argv = &argv[0]
+
+		return false
+	}
+
 	if request == nil {
 		return C.bool(fc.worker != nil)
 	}
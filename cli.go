@@ -0,0 +1,231 @@
+package frankenphp
+
+// #include <sys/types.h>
+// #include "frankenphp.h"
+import "C"
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"unsafe"
+
+	"github.com/dunglas/frankenphp/internal/fastabs"
+)
+
+// cliScript is a script executed as a command with RunScript
+type cliScript struct {
+	ctx    context.Context
+	script string
+	argv   []string
+	env    map[string]string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	exitStatus int
+	started    bool
+
+	// vmInterrupt points to EG(vm_interrupt) of the PHP thread while the script runs, it is used to cancel the script
+	mu          sync.Mutex
+	vmInterrupt unsafe.Pointer
+}
+
+// RunScript executes script as a command with the running server, see Server.RunScript.
+func RunScript(ctx context.Context, script string, args []string, stdin io.Reader, stdout, stderr io.Writer, env map[string]string) (int, error) {
+	s := activeServer.Load()
+	if s == nil {
+		return -1, ErrNotRunning
+	}
+
+	return s.RunScript(ctx, script, args, stdin, stdout, stderr, env)
+}
+
+// EXPERIMENTAL: RunScript executes script as a command, as the php CLI would, and returns its exit status.
+// Unlike ExecuteScriptCLI, the script runs on a regular PHP thread of the server, and several scripts can run concurrently.
+//
+// $argv contains script followed by args. The output of the script, including what is written to STDOUT
+// and php://stdout, is written to stdout; what is written to STDERR and php://stderr is written to stderr.
+// STDIN and php://stdin read from stdin. Nil readers and writers behave as empty and discarding ones.
+// env is added to the environment of the script only, as with getenv(), $_ENV and $_SERVER.
+//
+// If ctx is done before the script ends, the script is stopped with a fatal error at the next PHP instruction
+// or output, and ctx.Err() is returned. If the script is stopped by a fatal error, it is returned as a *PHPFatalError.
+func (s *Server) RunScript(ctx context.Context, script string, args []string, stdin io.Reader, stdout, stderr io.Writer, env map[string]string) (int, error) {
+	if !s.Running() {
+		return -1, ErrNotRunning
+	}
+
+	scriptFilename, err := fastabs.FastAbs(script)
+	if err != nil {
+		return -1, err
+	}
+
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "", http.NoBody)
+	if err != nil {
+		return -1, err
+	}
+
+	cli := &cliScript{
+		ctx:    ctx,
+		script: script,
+		argv:   append([]string{script}, args...),
+		env:    env,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	fc := newFrankenPHPContext()
+	fc.server = s
+	fc.logger = s.logger
+	fc.request = r
+	fc.scriptFilename = scriptFilename
+	fc.cli = cli
+	fc.responseWriter = &cliOutput{cli: cli, header: make(http.Header)}
+
+	fcCtx, cancel := context.WithCancel(ctx)
+	fc.ctx = context.WithValue(fcCtx, contextKey, fc)
+	fc.cancel = cancel
+
+	stop := context.AfterFunc(ctx, cli.interrupt)
+	defer stop()
+
+	if !s.handleRequestWithRegularPHPThreads(fc, ctx.Done()) {
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+
+		return -1, ErrMaxWaitTimeExceeded
+	}
+
+	if err := ctx.Err(); err != nil {
+		return cli.exitStatus, err
+	}
+
+	return cli.exitStatus, fc.handlerError
+}
+
+// start is called on the PHP thread before the request startup
+func (cli *cliScript) start(vmInterrupt unsafe.Pointer) {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+
+	cli.started = true
+	cli.vmInterrupt = vmInterrupt
+
+	// the context may have been cancelled while the script was waiting for a thread
+	if cli.ctx.Err() != nil {
+		C.frankenphp_interrupt_script(vmInterrupt)
+	}
+}
+
+// finish is called on the PHP thread once the script has been executed, the thread can't be interrupted anymore
+func (cli *cliScript) finish(exitStatus int) {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+
+	cli.exitStatus = exitStatus
+	cli.vmInterrupt = nil
+}
+
+// interrupt makes the script stop at the next PHP instruction, see frankenphp_interrupt_function()
+func (cli *cliScript) interrupt() {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+
+	if cli.vmInterrupt != nil {
+		C.frankenphp_interrupt_script(cli.vmInterrupt)
+	}
+}
+
+// cliOutput passes the output of the script to stdout, headers are ignored
+type cliOutput struct {
+	cli    *cliScript
+	header http.Header
+}
+
+func (o *cliOutput) Header() http.Header {
+	return o.header
+}
+
+func (o *cliOutput) WriteHeader(int) {}
+
+func (o *cliOutput) Write(p []byte) (int, error) {
+	// responses sent without executing the script, such as when no thread is available in time, are discarded
+	if !o.cli.started {
+		return len(p), nil
+	}
+
+	return o.cli.stdout.Write(p)
+}
+
+func (o *cliOutput) Flush() {}
+
+//export go_frankenphp_start_cli_script
+func go_frankenphp_start_cli_script(threadIndex C.uintptr_t, vmInterrupt unsafe.Pointer) C.bool {
+	thread := phpThreads[threadIndex]
+	fc := thread.getRequestContext()
+	if fc == nil || fc.cli == nil {
+		return false
+	}
+
+	// the env of the script is sandboxed in the thread, it is cleared before the next request
+	if len(fc.cli.env) > 0 {
+		cloneSandboxedEnv(thread)
+		for key, val := range fc.cli.env {
+			removeEnvFromThread(thread, key)
+			thread.sandboxedEnv[key] = C.frankenphp_init_persistent_string(toUnsafeChar(val), C.size_t(len(val)))
+		}
+	}
+
+	fc.cli.start(vmInterrupt)
+
+	return true
+}
+
+//export go_frankenphp_is_script_cancelled
+func go_frankenphp_is_script_cancelled(threadIndex C.uintptr_t) C.bool {
+	fc := phpThreads[threadIndex].getRequestContext()
+
+	return C.bool(fc != nil && fc.cli != nil && fc.cli.ctx.Err() != nil)
+}
+
+//export go_frankenphp_cli_write
+func go_frankenphp_cli_write(threadIndex C.uintptr_t, fd C.int, buf *C.char, length C.size_t) C.ssize_t {
+	cli := phpThreads[threadIndex].getRequestContext().cli
+
+	w := cli.stdout
+	if fd == 2 {
+		w = cli.stderr
+	}
+
+	n, err := w.Write(unsafe.Slice((*byte)(unsafe.Pointer(buf)), length))
+	if err != nil && n == 0 {
+		return -1
+	}
+
+	return C.ssize_t(n)
+}
+
+//export go_frankenphp_cli_read
+func go_frankenphp_cli_read(threadIndex C.uintptr_t, buf *C.char, count C.size_t) C.ssize_t {
+	cli := phpThreads[threadIndex].getRequestContext().cli
+	if cli.stdin == nil || cli.ctx.Err() != nil {
+		return 0
+	}
+
+	n, err := cli.stdin.Read(unsafe.Slice((*byte)(unsafe.Pointer(buf)), count))
+	if err != nil && err != io.EOF && n == 0 {
+		return -1
+	}
+
+	return C.ssize_t(n)
+}
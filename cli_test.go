@@ -0,0 +1,118 @@
+package frankenphp_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunScript(t *testing.T) {
+	require.NoError(t, frankenphp.Init(frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	defer frankenphp.Shutdown()
+
+	var stdout, stderr bytes.Buffer
+	status, err := frankenphp.RunScript(context.Background(), "testdata/cli.php", []string{"foo", "bar"}, strings.NewReader("input"), &stdout, &stderr, map[string]string{"FOO": "baz"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, status)
+
+	assert.NotContains(t, stdout.String(), "#!/usr/bin/env php")
+	assert.Contains(t, stdout.String(), "argv: testdata/cli.php,foo,bar\n")
+	assert.Contains(t, stdout.String(), "stdin: input\n")
+	assert.Equal(t, "getenv: baz\nserver: baz\n", stderr.String())
+}
+
+func TestRunScriptConcurrently(t *testing.T) {
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithNumThreads(4),
+	))
+	defer frankenphp.Shutdown()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			n := strconv.Itoa(i)
+
+			var stdout, stderr bytes.Buffer
+			status, err := frankenphp.RunScript(context.Background(), "testdata/cli.php", []string{n}, nil, &stdout, &stderr, map[string]string{"FOO": n})
+			assert.NoError(t, err)
+			assert.Equal(t, 3, status)
+			assert.Contains(t, stdout.String(), "argv: testdata/cli.php,"+n+"\n")
+			assert.Equal(t, "getenv: "+n+"\nserver: "+n+"\n", stderr.String())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRunScriptCancellation(t *testing.T) {
+	require.NoError(t, frankenphp.Init(frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	defer frankenphp.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	status, err := frankenphp.RunScript(ctx, "testdata/cli.php", []string{"loop"}, nil, nil, nil, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 255, status)
+}
+
+func TestRunScriptCancellationWhileWaitingForAThread(t *testing.T) {
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithNumThreads(1),
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	))
+	defer frankenphp.Shutdown()
+
+	// the only thread is busy
+	loopCtx, stopLoop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = frankenphp.RunScript(loopCtx, "testdata/cli.php", []string{"loop"}, nil, nil, nil, nil)
+	}()
+	defer func() {
+		stopLoop()
+		<-done
+	}()
+
+	assert.Eventually(t, func() bool {
+		for _, s := range frankenphp.DebugState().ThreadDebugStates {
+			if s.IsBusy {
+				return true
+			}
+		}
+
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	status, err := frankenphp.RunScript(ctx, "testdata/cli.php", nil, nil, nil, nil, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, -1, status)
+}
+
+func TestRunScriptFatalError(t *testing.T) {
+	require.NoError(t, frankenphp.Init(frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	defer frankenphp.Shutdown()
+
+	status, err := frankenphp.RunScript(context.Background(), "testdata/fatal-error.php", nil, nil, nil, nil, nil)
+	var fatalError *frankenphp.PHPFatalError
+	require.ErrorAs(t, err, &fatalError)
+	assert.Contains(t, fatalError.Message, "Call to undefined function undefined_function()")
+	assert.Equal(t, 255, status)
+}
@@ -0,0 +1,192 @@
+package frankenphp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxCoalescedResponseSize is the size limit of the responses copied to the coalesced requests
+const maxCoalescedResponseSize = 16 << 20
+
+// coalescedRequest is a request handled by a PHP thread on behalf of the identical requests received in the meantime
+type coalescedRequest struct {
+	done chan struct{}
+	// shareable is true if the response can be sent to the waiting requests, set before done is closed
+	shareable bool
+	status    int
+	header    http.Header
+	body      []byte
+}
+
+var (
+	coalescedRequestsMu sync.Mutex
+	coalescedRequests   = make(map[string]*coalescedRequest)
+)
+
+// WithRequestCoalescing coalesces the identical requests received while a request is handled:
+// only one of them is sent to a PHP thread, the others receive a copy of its response if it is shareable.
+// Requests are identical if they have the same method, URL and values for the given request headers.
+func WithRequestCoalescing(vary []string) RequestOption {
+	return func(o *frankenPHPContext) error {
+		o.coalesce = true
+		o.coalescingVary = vary
+
+		return nil
+	}
+}
+
+// joinCoalescedRequest returns the in-flight request with the same fingerprint,
+// or registers a new one if there is none, in which case leader is true
+func joinCoalescedRequest(key string) (c *coalescedRequest, leader bool) {
+	coalescedRequestsMu.Lock()
+	defer coalescedRequestsMu.Unlock()
+
+	if c, ok := coalescedRequests[key]; ok {
+		return c, false
+	}
+
+	c = &coalescedRequest{done: make(chan struct{})}
+	coalescedRequests[key] = c
+
+	return c, true
+}
+
+// serveCoalescedResponse waits for the leader of the request and writes a copy of its response,
+// it returns false if the response is not shareable and the request must be handled by a PHP thread
+func (fc *frankenPHPContext) serveCoalescedResponse(c *coalescedRequest) bool {
+	select {
+	case <-c.done:
+	case <-fc.request.Context().Done():
+		// the client is gone, there is nothing to send
+		return true
+	}
+
+	if !c.shareable {
+		return false
+	}
+
+	h := fc.responseWriter.Header()
+	for k, v := range c.header {
+		h[k] = slices.Clone(v)
+	}
+	if cs := h.Get("Cache-Status"); strings.HasPrefix(cs, responseCacheStatus+";") {
+		h.Set("Cache-Status", cs+"; collapsed")
+	}
+
+	fc.responseWriter.WriteHeader(c.status)
+	if fc.request.Method != http.MethodHead {
+		if _, err := fc.responseWriter.Write(c.body); err != nil {
+			fc.logger.LogAttrs(context.Background(), slog.LevelWarn, "write error", slog.Any("error", err))
+		}
+	}
+
+	return true
+}
+
+// finishCoalescedRequest shares the recorded response with the waiting requests
+func (fc *frankenPHPContext) finishCoalescedRequest(key string, c *coalescedRequest, recorder *coalescingRecorder) {
+	coalescedRequestsMu.Lock()
+	delete(coalescedRequests, key)
+	coalescedRequestsMu.Unlock()
+
+	if recorder.status != 0 && !recorder.overflow && fc.stream == nil && fc.sentFile == nil && len(fc.trailers) == 0 && !fc.clientHasClosed() && isResponseShareable(recorder.header, fc.coalescingVary) {
+		c.shareable = true
+		c.status = recorder.status
+		c.header = recorder.header
+		c.body = recorder.body.Bytes()
+	}
+
+	close(c.done)
+}
+
+// isResponseShareable checks if the Cache-Control header of the response allows sending it to other clients,
+// and that the request headers listed in its Vary header are part of the fingerprint built from the vary request headers
+func isResponseShareable(header http.Header, vary []string) bool {
+	if header.Get("Set-Cookie") != "" || !isVaryKeyed(header, vary) {
+		return false
+	}
+
+	shareable := false
+	for _, directive := range strings.Split(strings.Join(header.Values("Cache-Control"), ","), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store", "private":
+			return false
+		case "public":
+			shareable = true
+		case "max-age", "s-maxage":
+			if parseDeltaSeconds(value) > 0 {
+				shareable = true
+			}
+		}
+	}
+
+	return shareable
+}
+
+// coalescingRecorder sends the response to the client while recording it for the coalesced requests
+type coalescingRecorder struct {
+	http.ResponseWriter
+
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+func newCoalescingRecorder(w http.ResponseWriter) *coalescingRecorder {
+	return &coalescingRecorder{ResponseWriter: w}
+}
+
+func (w *coalescingRecorder) WriteHeader(statusCode int) {
+	if w.status != 0 || (statusCode >= 100 && statusCode < 200) {
+		w.ResponseWriter.WriteHeader(statusCode)
+
+		return
+	}
+
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+
+	// recorded once the underlying writers have updated the headers, the response cache removes Cache-Tag for instance
+	w.header = w.ResponseWriter.Header().Clone()
+}
+
+func (w *coalescingRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.overflow {
+		if w.body.Len()+len(p) > maxCoalescedResponseSize {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(p)
+		}
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *coalescingRecorder) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *coalescingRecorder) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *coalescingRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,46 @@
+package frankenphp
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsResponseShareable(t *testing.T) {
+	for _, tc := range []struct {
+		header    http.Header
+		shareable bool
+	}{
+		{http.Header{"Cache-Control": {"public"}}, true},
+		{http.Header{"Cache-Control": {"max-age=60"}}, true},
+		{http.Header{"Cache-Control": {"no-cache", "s-maxage=10"}}, true},
+		{http.Header{}, false},
+		{http.Header{"Cache-Control": {"max-age=0"}}, false},
+		{http.Header{"Cache-Control": {"public, private"}}, false},
+		{http.Header{"Cache-Control": {"public, no-store"}}, false},
+		{http.Header{"Cache-Control": {"public"}, "Set-Cookie": {"foo=bar"}}, false},
+		{http.Header{"Cache-Control": {"public"}, "Vary": {"*"}}, false},
+		{http.Header{"Cache-Control": {"public"}, "Vary": {"accept-language"}}, true},
+		{http.Header{"Cache-Control": {"public"}, "Vary": {"Accept-Language, Accept-Encoding"}}, false},
+	} {
+		assert.Equal(t, tc.shareable, isResponseShareable(tc.header, []string{"Accept-Language"}), tc.header)
+	}
+}
+
+func TestJoinCoalescedRequest(t *testing.T) {
+	c, leader := joinCoalescedRequest("test-key")
+	assert.True(t, leader)
+
+	c2, leader := joinCoalescedRequest("test-key")
+	assert.False(t, leader)
+	assert.Same(t, c, c2)
+
+	_, leader = joinCoalescedRequest("other-key")
+	assert.True(t, leader)
+
+	coalescedRequestsMu.Lock()
+	delete(coalescedRequests, "test-key")
+	delete(coalescedRequests, "other-key")
+	coalescedRequestsMu.Unlock()
+}
@@ -20,6 +20,9 @@ type frankenPHPContext struct {
 	originalRequest *http.Request
 	worker          *worker
 
+	// server is the server handling the request, it is set when the request is dispatched
+	server *Server
+
 	docURI         string
 	pathInfo       string
 	scriptName     string
@@ -28,9 +31,51 @@ type frankenPHPContext struct {
 	// Whether the request is already closed by us
 	isDone bool
 
-	responseWriter    http.ResponseWriter
-	handlerParameters any
-	handlerReturn     any
+	responseWriter http.ResponseWriter
+	// responseBufferMaxMemory enables response buffering if it is greater than 0
+	responseBufferMaxMemory int64
+	handlerParameters       any
+	handlerReturn           any
+	handlerError            error
+
+	// trailers are sent after the response body, see frankenphp_set_trailer()
+	trailers http.Header
+
+	// sendFileDirs contains the directories of the files that can be sent with frankenphp_send_file()
+	sendFileDirs []string
+	// sentFile is set when the script hands the response off to Go with frankenphp_send_file()
+	sentFile *sentFile
+
+	// cacheResponse enables the response cache, responseCacheVary contains the request headers that are part of the key
+	cacheResponse     bool
+	responseCacheVary []string
+	// responseCacheTags are added by the script with frankenphp_response_cache_tag()
+	responseCacheTags []string
+
+	// coalesce enables request coalescing, coalescingVary contains the request headers that are part of the fingerprint
+	coalesce       bool
+	coalescingVary []string
+
+	// esi enables the processing of the Edge Side Includes tags of HTML responses
+	esi bool
+
+	// stream is set when the script hands the response off to Go with frankenphp_stream_detach()
+	stream *detachedStream
+
+	// headless is set when the response is not sent to any client, such as when a stale cached response
+	// is refreshed in the background, the script can then not hand the response off to Go
+	headless bool
+
+	// cli is set when the script is executed as a command with RunScript
+	cli *cliScript
+
+	// isSubRequest is set for the requests sent with frankenphp_subrequest(), they are rejected instead of waiting
+	// for a thread: the waiting thread could be the only one able to handle them
+	isSubRequest bool
+
+	// ctx is derived from the context of the request and cancelled once PHP is done with it
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	done      chan any
 	startedAt time.Time
@@ -60,8 +105,10 @@ func NewRequestWithContext(r *http.Request, opts ...RequestOption) (*http.Reques
 		}
 	}
 
-	if fc.logger == nil {
-		fc.logger = logger
+	// the workers and the logger are the ones of the running server
+	s := activeServer.Load()
+	if fc.logger == nil && s != nil {
+		fc.logger = s.logger
 	}
 
 	if fc.documentRoot == "" {
@@ -81,12 +128,14 @@ func NewRequestWithContext(r *http.Request, opts ...RequestOption) (*http.Reques
 	} else {
 		// If no worker was assigned, split the path into the "traditional" CGI path variables.
 		// This needs to already happen here in case a worker script still matches the path.
-		splitCgiPath(fc)
+		splitCgiPath(fc, s)
 	}
 
-	c := context.WithValue(r.Context(), contextKey, fc)
+	ctx, cancel := context.WithCancel(r.Context())
+	fc.ctx = context.WithValue(ctx, contextKey, fc)
+	fc.cancel = cancel
 
-	return r.WithContext(c), nil
+	return r.WithContext(fc.ctx), nil
 }
 
 // newDummyContext creates a fake context from a request path
@@ -114,6 +163,19 @@ func (fc *frankenPHPContext) closeContext() {
 
 	close(fc.done)
 	fc.isDone = true
+
+	if fc.cancel != nil {
+		fc.cancel()
+	}
+}
+
+// deadline returns the deadline of the context the request was created with, if any
+func (fc *frankenPHPContext) deadline() (time.Time, bool) {
+	if fc.ctx == nil {
+		return time.Time{}, false
+	}
+
+	return fc.ctx.Deadline()
 }
 
 // validate checks if the request should be outright rejected
@@ -16,8 +16,21 @@ type FrankenPHPDebugState struct {
 	ReservedThreadCount int
 }
 
-// EXPERIMENTAL: DebugState prints the state of all PHP threads - debugging purposes only
+// EXPERIMENTAL: DebugState prints the state of all PHP threads of the running server - debugging purposes only
 func DebugState() FrankenPHPDebugState {
+	if s := activeServer.Load(); s != nil {
+		return s.DebugState()
+	}
+
+	return FrankenPHPDebugState{ThreadDebugStates: []ThreadDebugState{}}
+}
+
+// EXPERIMENTAL: DebugState prints the state of all PHP threads - debugging purposes only
+func (s *Server) DebugState() FrankenPHPDebugState {
+	if !s.Running() {
+		return FrankenPHPDebugState{ThreadDebugStates: []ThreadDebugState{}}
+	}
+
 	fullState := FrankenPHPDebugState{
 		ThreadDebugStates:   make([]ThreadDebugState, 0, len(phpThreads)),
 		ReservedThreadCount: 0,
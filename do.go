@@ -0,0 +1,124 @@
+package frankenphp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// EXPERIMENTAL: Do executes the request with PHP and returns its response, without needing a ResponseWriter.
+// The request is prepared with NewRequestWithContext and opts, unless it already has been.
+//
+// Do returns once the script has sent the headers: the body is streamed while the script runs and must be closed.
+// Closing the body before the end aborts the connection as seen by the script.
+//
+// If the script is stopped before sending its response, the error is returned: *PHPFatalError for fatal errors,
+// such as uncaught exceptions, or *PHPException when the callback of a worker throws.
+// If it is stopped after, the error is returned when reading the end of the body.
+func Do(req *http.Request, opts ...RequestOption) (*http.Response, error) {
+	fr := req
+	fc, ok := fromContext(req.Context())
+	if !ok || len(opts) > 0 {
+		var err error
+		if fr, err = NewRequestWithContext(req, opts...); err != nil {
+			return nil, err
+		}
+		fc, _ = fromContext(fr.Context())
+	}
+
+	pr, pw := io.Pipe()
+	w := &doResponseWriter{fc: fc, header: make(http.Header), body: pw, ready: make(chan struct{})}
+
+	go func() {
+		err := ServeHTTP(w, fr)
+		if err == nil {
+			err = fc.handlerError
+		}
+
+		if w.status == 0 {
+			if err == nil {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				w.err = err
+				close(w.ready)
+			}
+		}
+
+		_ = pw.CloseWithError(err)
+	}()
+
+	<-w.ready
+	if w.err != nil {
+		_ = pr.Close()
+
+		return nil, w.err
+	}
+
+	contentLength := int64(-1)
+	if cl, err := strconv.ParseInt(w.sentHeader.Get("Content-Length"), 10, 64); err == nil && cl >= 0 {
+		contentLength = cl
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        w.sentHeader,
+		Body:          pr,
+		ContentLength: contentLength,
+		Request:       req,
+	}, nil
+}
+
+// doResponseWriter passes the response written by PHP to Do,
+// all its methods are called from the PHP thread or once the script has finished
+type doResponseWriter struct {
+	fc         *frankenPHPContext
+	header     http.Header
+	sentHeader http.Header
+	status     int
+	body       *io.PipeWriter
+	// ready is closed once the headers have been sent or the script has been stopped
+	ready chan struct{}
+	// err is set when the script has been stopped before sending its response, the body is then discarded
+	err error
+}
+
+func (w *doResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *doResponseWriter) WriteHeader(statusCode int) {
+	// interim responses such as 103 Early Hints aren't returned
+	if w.status != 0 || statusCode < http.StatusOK {
+		return
+	}
+
+	w.status = statusCode
+	w.sentHeader = w.header.Clone()
+
+	// the headers of the error page are sent while shutting down a script stopped by an error
+	if w.fc.handlerError != nil {
+		w.err = w.fc.handlerError
+	}
+
+	close(w.ready)
+}
+
+func (w *doResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.err != nil {
+		return len(p), nil
+	}
+
+	return w.body.Write(p)
+}
+
+// Flush is a no-op, writes are passed as is to the body
+func (w *doResponseWriter) Flush() {}
@@ -0,0 +1,77 @@
+package frankenphp_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+	cwd, _ := os.Getwd()
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithWorkers("counter", cwd+"/testdata/worker-with-counter.php", 1),
+	))
+	defer frankenphp.Shutdown()
+
+	do := func(target string, opts ...frankenphp.RequestOption) (*http.Response, error) {
+		opts = append([]frankenphp.RequestOption{frankenphp.WithRequestDocumentRoot(cwd+"/testdata", false)}, opts...)
+
+		return frankenphp.Do(httptest.NewRequest(http.MethodGet, target, nil), opts...)
+	}
+
+	resp, err := do("/hello.php")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "Hello from PHP", string(body))
+
+	resp, err = do("/response-headers.php?i=3")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "bar", resp.Header.Get("Foo"))
+	_ = resp.Body.Close()
+
+	resp, err = do("/worker-with-counter.php", frankenphp.WithWorkerName("counter"))
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "requests:1", string(body))
+}
+
+func TestDoFatalError(t *testing.T) {
+	cwd, _ := os.Getwd()
+	require.NoError(t, frankenphp.Init(frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	defer frankenphp.Shutdown()
+
+	do := func(target string) (*http.Response, error) {
+		return frankenphp.Do(httptest.NewRequest(http.MethodGet, target, nil), frankenphp.WithRequestDocumentRoot(cwd+"/testdata", false))
+	}
+
+	// the script is stopped before sending the response
+	_, err := do("/fatal-error.php")
+	var fatalError *frankenphp.PHPFatalError
+	require.ErrorAs(t, err, &fatalError)
+	assert.Contains(t, fatalError.Message, "Call to undefined function undefined_function()")
+	assert.Equal(t, cwd+"/testdata/fatal-error.php", fatalError.File)
+	assert.Equal(t, 10, fatalError.Line)
+
+	// the script is stopped after sending the headers
+	resp, err := do("/fatal-error.php?flush")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.ErrorAs(t, err, &fatalError)
+	assert.Contains(t, string(body), "partial")
+	_ = resp.Body.Close()
+}
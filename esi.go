@@ -0,0 +1,241 @@
+package frankenphp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// esiSurrogateCapability tells frameworks such as Symfony that ESI tags are supported
+const esiSurrogateCapability = `frankenphp="ESI/1.0"`
+
+// esiSurrogateControl is the directive of the Surrogate-Control header of the responses containing ESI tags
+const esiSurrogateControl = `content="ESI/1.0"`
+
+var (
+	esiIncludeRegexp   = regexp.MustCompile(`(?s)<esi:include\s+(.*?)\s*(?:/>|>\s*</esi:include>)`)
+	esiRemoveRegexp    = regexp.MustCompile(`(?s)<esi:remove>.*?</esi:remove>`)
+	esiCommentRegexp   = regexp.MustCompile(`(?s)<esi:comment\s.*?/>`)
+	esiEscapedRegexp   = regexp.MustCompile(`(?s)<!--esi\s(.*?)-->`)
+	esiAttributeRegexp = regexp.MustCompile(`([a-z]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// WithRequestESI enables the processing of the Edge Side Includes tags of the responses having a
+// Surrogate-Control: content="ESI/1.0" header.
+// Included fragments are handled as sub-requests, in parallel, using the same options as the request.
+func WithRequestESI(enabled bool) RequestOption {
+	return func(o *frankenPHPContext) error {
+		o.esi = enabled
+
+		return nil
+	}
+}
+
+// esiResponseWriter buffers the responses containing ESI tags until the script has finished to replace them,
+// other responses are sent to the client directly
+type esiResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	// buffering is true if the response has a Surrogate-Control: content="ESI/1.0" header
+	buffering bool
+	body      bytes.Buffer
+}
+
+func newESIResponseWriter(w http.ResponseWriter) *esiResponseWriter {
+	return &esiResponseWriter{ResponseWriter: w}
+}
+
+func (w *esiResponseWriter) WriteHeader(statusCode int) {
+	if w.status != 0 || (statusCode >= 100 && statusCode < 200) {
+		if !w.buffering {
+			w.ResponseWriter.WriteHeader(statusCode)
+		}
+
+		return
+	}
+
+	w.status = statusCode
+
+	// user content must not trigger sub-requests carrying the headers of the visitor,
+	// only the responses the application marked as containing ESI tags are processed
+	h := w.ResponseWriter.Header()
+	if !slices.ContainsFunc(h.Values("Surrogate-Control"), func(v string) bool { return strings.Contains(v, esiSurrogateControl) }) {
+		w.ResponseWriter.WriteHeader(statusCode)
+
+		return
+	}
+
+	w.buffering = true
+	h.Del("Surrogate-Control")
+	// the size changes once the tags are replaced
+	h.Del("Content-Length")
+}
+
+func (w *esiResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.buffering {
+		return w.body.Write(p)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// FlushError is a no-op for the buffered responses, the whole document is needed to replace the tags
+func (w *esiResponseWriter) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.buffering {
+		return nil
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *esiResponseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *esiResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finishESI replaces the ESI tags of the buffered document and sends it to the client
+func (fc *frankenPHPContext) finishESI(w *esiResponseWriter) {
+	if !w.buffering {
+		return
+	}
+
+	body := w.body.Bytes()
+	if bytes.Contains(body, []byte("<esi:")) || bytes.Contains(body, []byte("<!--esi")) {
+		var err error
+		if body, err = fc.processESI(body); err != nil {
+			fc.logger.LogAttrs(context.Background(), slog.LevelError, "unable to process the ESI tags", slog.Any("error", err))
+			http.Error(w.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if _, err := w.ResponseWriter.Write(body); err != nil {
+		fc.logger.LogAttrs(context.Background(), slog.LevelWarn, "write error", slog.Any("error", err))
+	}
+}
+
+// processESI replaces the ESI tags of the document, fragments are fetched in parallel
+func (fc *frankenPHPContext) processESI(body []byte) ([]byte, error) {
+	body = esiEscapedRegexp.ReplaceAll(body, []byte("$1"))
+	body = esiRemoveRegexp.ReplaceAll(body, nil)
+	body = esiCommentRegexp.ReplaceAll(body, nil)
+
+	matches := esiIncludeRegexp.FindAllSubmatchIndex(body, -1)
+	if len(matches) == 0 {
+		return body, nil
+	}
+
+	fragments := make([][]byte, len(matches))
+	errs := make([]error, len(matches))
+
+	var wg sync.WaitGroup
+	wg.Add(len(matches))
+	for i, m := range matches {
+		attributes := parseESIAttributes(body[m[2]:m[3]])
+
+		go func() {
+			defer wg.Done()
+
+			fragments[i], errs[i] = fc.includeESIFragment(attributes)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	last := 0
+	for i, m := range matches {
+		b.Write(body[last:m[0]])
+		b.Write(fragments[i])
+		last = m[1]
+	}
+	b.Write(body[last:])
+
+	return b.Bytes(), nil
+}
+
+func parseESIAttributes(tag []byte) map[string]string {
+	attributes := make(map[string]string)
+	for _, m := range esiAttributeRegexp.FindAllSubmatch(tag, -1) {
+		attributes[string(m[1])] = string(m[2]) + string(m[3])
+	}
+
+	return attributes
+}
+
+// includeESIFragment fetches the fragment, or its alternative if it fails.
+// Failures are ignored if the tag has the onerror="continue" attribute.
+func (fc *frankenPHPContext) includeESIFragment(attributes map[string]string) ([]byte, error) {
+	body, err := fc.fetchESIFragment(attributes["src"])
+	if err != nil && attributes["alt"] != "" {
+		body, err = fc.fetchESIFragment(attributes["alt"])
+	}
+
+	if err != nil && attributes["onerror"] == "continue" {
+		fc.logger.LogAttrs(context.Background(), slog.LevelWarn, "unable to include the ESI fragment", slog.String("src", attributes["src"]), slog.Any("error", err))
+
+		return nil, nil
+	}
+
+	return body, err
+}
+
+// fetchESIFragment handles the fragment as a sub-request having the headers of the request
+func (fc *frankenPHPContext) fetchESIFragment(src string) ([]byte, error) {
+	u, err := fc.resolveSubRequestURL(src)
+	if err != nil {
+		return nil, err
+	}
+
+	header := fc.request.Header.Clone()
+	for _, name := range []string{"Content-Length", "Content-Type", "Range", "If-Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"} {
+		header.Del(name)
+	}
+
+	sr, err := fc.newSubRequest(http.MethodGet, u, header, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := fc.server.serveSubRequests(fc.request.Context(), []*http.Request{sr})
+	if err != nil {
+		return nil, err
+	}
+
+	if status := responses[0].statusCode(); status >= http.StatusBadRequest {
+		return nil, fmt.Errorf("the ESI fragment %q returned the status code %d", src, status)
+	}
+
+	return responses[0].body.Bytes(), nil
+}
+
+// addSurrogateCapability advertises the support of ESI to the script
+func (fc *frankenPHPContext) addSurrogateCapability() {
+	if !slices.Contains(fc.request.Header.Values("Surrogate-Capability"), esiSurrogateCapability) {
+		fc.request.Header.Add("Surrogate-Capability", esiSurrogateCapability)
+	}
+}
@@ -0,0 +1,48 @@
+package frankenphp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseESIAttributes(t *testing.T) {
+	assert.Equal(t, map[string]string{"src": "/fragment?a=b", "alt": "/alt", "onerror": "continue"}, parseESIAttributes([]byte(`src="/fragment?a=b" alt='/alt'  onerror = "continue"`)))
+}
+
+func TestESIResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newESIResponseWriter(rec)
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Surrogate-Control", `content="ESI/1.0"`)
+	w.Header().Set("Content-Length", "10")
+
+	_, _ = w.Write([]byte("<esi:remove>removed</esi:remove>"))
+	w.Flush()
+
+	assert.True(t, w.buffering)
+	assert.False(t, rec.Flushed)
+	assert.Empty(t, rec.Header().Get("Surrogate-Control"))
+	assert.Empty(t, rec.Header().Get("Content-Length"))
+
+	// HTML documents not marked as containing ESI tags are sent as is
+	rec = httptest.NewRecorder()
+	w = newESIResponseWriter(rec)
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	_, _ = w.Write([]byte(`<esi:include src="/admin" />`))
+
+	assert.False(t, w.buffering)
+	assert.Equal(t, `<esi:include src="/admin" />`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	w = newESIResponseWriter(rec)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(`{}`))
+
+	assert.False(t, w.buffering)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{}`, rec.Body.String())
+}
@@ -0,0 +1,565 @@
+package frankenphp
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/cgi"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FastCGI protocol constants, see https://fastcgi-archives.github.io/FastCGI_Specification.html
+const (
+	fastCGIVersion1 = 1
+
+	fastCGIBeginRequest    = 1
+	fastCGIAbortRequest    = 2
+	fastCGIEndRequest      = 3
+	fastCGIParams          = 4
+	fastCGIStdin           = 5
+	fastCGIStdout          = 6
+	fastCGIStderr          = 7
+	fastCGIGetValues       = 9
+	fastCGIGetValuesResult = 10
+	fastCGIUnknownType     = 11
+
+	fastCGIResponder = 1
+	fastCGIKeepConn  = 1
+
+	fastCGIRequestComplete = 0
+	fastCGICantMpxConn     = 1
+	fastCGIUnknownRole     = 3
+
+	fastCGIHeaderLen  = 8
+	fastCGIMaxContent = 65535
+)
+
+var errFastCGIForbidden = errors.New("forbidden FastCGI params")
+
+// fastCGIConn is a connection from a FastCGI client such as NGINX or Apache's mod_proxy_fcgi.
+// Requests are not multiplexed: the connection handles one request at a time.
+type fastCGIConn struct {
+	server *Server
+	conn   net.Conn
+	r      *bufio.Reader
+	opts   []RequestOption
+
+	// mu protects w, the response is written by the goroutine handling the request
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+// fastCGIRequest is the request being received on a connection
+type fastCGIRequest struct {
+	id       uint16
+	keepConn bool
+	params   bytes.Buffer
+	body     *io.PipeReader
+	bodyW    *io.PipeWriter
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// started is true once all params have been received and the request is handled
+	started bool
+	done    chan struct{}
+}
+
+// EXPERIMENTAL: ServeFastCGI handles the FastCGI connections accepted on the listener with the running server, see Server.ServeFastCGI.
+func ServeFastCGI(l net.Listener, opts ...RequestOption) error {
+	s := activeServer.Load()
+	if s == nil {
+		return ErrNotRunning
+	}
+
+	return s.ServeFastCGI(l, opts...)
+}
+
+// EXPERIMENTAL: ServeFastCGI accepts FastCGI connections on the listener and handles the requests with PHP until the listener is closed.
+//
+// The FastCGI params take precedence over the options: SCRIPT_FILENAME, SCRIPT_NAME, PATH_INFO and DOCUMENT_ROOT select the script to execute
+// (and the worker, if SCRIPT_FILENAME is the file of a worker script), the other params are added to $_SERVER.
+// DOCUMENT_ROOT and SCRIPT_FILENAME must be in the document root set with the options (the current directory by default),
+// and SCRIPT_FILENAME must end with one of the split path extensions (".php" by default), requests not complying are refused.
+func (s *Server) ServeFastCGI(l net.Listener, opts ...RequestOption) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		c := &fastCGIConn{server: s, conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn), opts: opts}
+		go c.serve()
+	}
+}
+
+func (c *fastCGIConn) serve() {
+	defer c.conn.Close()
+
+	var req *fastCGIRequest
+	defer func() {
+		if req != nil {
+			req.cancel()
+			_ = req.bodyW.Close()
+		}
+	}()
+
+	for {
+		typ, id, content, err := c.readRecord()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.server.logger.LogAttrs(context.Background(), slog.LevelDebug, "FastCGI connection error", slog.Any("error", err))
+			}
+
+			return
+		}
+
+		// the previous request has been handled, the connection can be reused
+		if req != nil && req.started && isClosed(req.done) {
+			req = nil
+		}
+
+		switch typ {
+		case fastCGIGetValues:
+			c.writeGetValuesResult(content)
+
+		case fastCGIBeginRequest:
+			if len(content) < 3 {
+				return
+			}
+
+			if req != nil {
+				c.writeEndRequest(id, fastCGICantMpxConn)
+
+				continue
+			}
+
+			if binary.BigEndian.Uint16(content) != fastCGIResponder {
+				c.writeEndRequest(id, fastCGIUnknownRole)
+
+				continue
+			}
+
+			req = newFastCGIRequest(id, content[2]&fastCGIKeepConn != 0)
+
+		case fastCGIParams:
+			if req == nil || req.id != id || req.started {
+				continue
+			}
+
+			if len(content) != 0 {
+				req.params.Write(content)
+
+				continue
+			}
+
+			params, err := parseFastCGIParams(req.params.Bytes())
+			if err != nil {
+				c.server.logger.LogAttrs(context.Background(), slog.LevelDebug, "invalid FastCGI params", slog.Any("error", err))
+
+				return
+			}
+
+			req.started = true
+			go c.serveRequest(req, params)
+
+		case fastCGIStdin:
+			if req == nil || req.id != id {
+				continue
+			}
+
+			if len(content) == 0 {
+				_ = req.bodyW.Close()
+
+				continue
+			}
+
+			// fails if the script has finished without reading the whole body
+			_, _ = req.bodyW.Write(content)
+
+		case fastCGIAbortRequest:
+			if req != nil && req.id == id {
+				req.cancel()
+			}
+
+		default:
+			c.mu.Lock()
+			_ = c.writeRecord(fastCGIUnknownType, 0, []byte{typ, 0, 0, 0, 0, 0, 0, 0})
+			_ = c.w.Flush()
+			c.mu.Unlock()
+		}
+	}
+}
+
+func newFastCGIRequest(id uint16, keepConn bool) *fastCGIRequest {
+	body, bodyW := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &fastCGIRequest{id: id, keepConn: keepConn, body: body, bodyW: bodyW, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// serveRequest converts the FastCGI params to an HTTP request and handles it with PHP
+func (c *fastCGIConn) serveRequest(req *fastCGIRequest, params map[string]string) {
+	w := &fastCGIResponseWriter{c: c, id: req.id, header: make(http.Header)}
+
+	if err := c.handle(w, req, params); err != nil {
+		c.server.logger.LogAttrs(context.Background(), slog.LevelError, "unable to handle the FastCGI request", slog.Any("error", err))
+
+		c.mu.Lock()
+		_ = c.writeRecord(fastCGIStderr, req.id, []byte(err.Error()))
+		c.mu.Unlock()
+
+		if !w.wroteHeader {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errFastCGIForbidden) {
+				status = http.StatusForbidden
+			}
+
+			w.WriteHeader(status)
+		}
+	}
+
+	w.finish()
+
+	req.cancel()
+	// unblock the connection if it is still sending a body that will never be read
+	_ = req.body.Close()
+	// the connection must be ready for the next request when the client receives the end of this one
+	close(req.done)
+	c.writeEndRequest(req.id, fastCGIRequestComplete)
+
+	if !req.keepConn {
+		_ = c.conn.Close()
+	}
+}
+
+func (c *fastCGIConn) handle(w *fastCGIResponseWriter, req *fastCGIRequest, params map[string]string) error {
+	r, err := cgi.RequestFromMap(params)
+	if err != nil {
+		return err
+	}
+	r.Body = req.body
+	if contentLength := params["CONTENT_LENGTH"]; contentLength != "" {
+		r.Header.Set("Content-Length", contentLength)
+	}
+	if r.RequestURI == "" {
+		r.RequestURI = r.URL.RequestURI()
+	}
+	r = r.WithContext(req.ctx)
+
+	fr, err := NewRequestWithContext(r, append(slices.Clone(c.opts), withFastCGIParams(params))...)
+	if err != nil {
+		return err
+	}
+
+	fc, _ := fromContext(fr.Context())
+	if err := fc.applyFastCGIScriptParams(c.server, params); err != nil {
+		return err
+	}
+
+	return c.server.ServeHTTP(w, fr)
+}
+
+// applyFastCGIScriptParams selects the script to execute with the params sent by the client.
+// The client can't escape the configured document root, nor execute files not having one of the split path extensions.
+func (fc *frankenPHPContext) applyFastCGIScriptParams(s *Server, params map[string]string) error {
+	root := fc.documentRoot
+
+	if documentRoot := params["DOCUMENT_ROOT"]; documentRoot != "" && documentRoot != root {
+		if !isInDir(root, documentRoot) {
+			return fmt.Errorf("%w: DOCUMENT_ROOT %q is not in %q", errFastCGIForbidden, documentRoot, root)
+		}
+
+		fc.documentRoot = filepath.Clean(documentRoot)
+		if fc.worker == nil {
+			splitCgiPath(fc, s)
+		}
+	}
+
+	scriptFilename := params["SCRIPT_FILENAME"]
+	if scriptFilename == "" {
+		return nil
+	}
+
+	if !isInDir(root, scriptFilename) {
+		return fmt.Errorf("%w: SCRIPT_FILENAME %q is not in %q", errFastCGIForbidden, scriptFilename, root)
+	}
+
+	splitPath := fc.splitPath
+	if splitPath == nil {
+		splitPath = []string{".php"}
+	}
+	if !slices.ContainsFunc(splitPath, func(ext string) bool {
+		return ext != "" && strings.HasSuffix(strings.ToLower(scriptFilename), strings.ToLower(ext))
+	}) {
+		return fmt.Errorf("%w: SCRIPT_FILENAME %q doesn't end with %s", errFastCGIForbidden, scriptFilename, strings.Join(splitPath, ", "))
+	}
+
+	fc.scriptFilename = filepath.Clean(scriptFilename)
+	fc.scriptName = params["SCRIPT_NAME"]
+	fc.pathInfo = params["PATH_INFO"]
+	fc.docURI = cmp.Or(params["DOCUMENT_URI"], fc.scriptName)
+	fc.worker = s.getWorkerByPath(fc.scriptFilename)
+
+	return nil
+}
+
+// isInDir checks if the absolute path is dir or is in dir, without resolving symbolic links
+func isInDir(dir, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// withFastCGIParams adds the params sent by the FastCGI client to $_SERVER
+func withFastCGIParams(params map[string]string) RequestOption {
+	return func(o *frankenPHPContext) error {
+		env := make(PreparedEnv, len(o.env)+len(params))
+		maps.Copy(env, o.env)
+		for k, v := range params {
+			// headers and the body are handled by the HTTP request
+			if strings.HasPrefix(k, "HTTP_") || k == "CONTENT_LENGTH" || k == "CONTENT_TYPE" {
+				continue
+			}
+
+			env[k+"\x00"] = v
+		}
+		o.env = env
+
+		return nil
+	}
+}
+
+func (c *fastCGIConn) readRecord() (typ uint8, id uint16, content []byte, err error) {
+	var h [fastCGIHeaderLen]byte
+	if _, err = io.ReadFull(c.r, h[:]); err != nil {
+		return 0, 0, nil, err
+	}
+
+	if h[0] != fastCGIVersion1 {
+		return 0, 0, nil, fmt.Errorf("unsupported FastCGI version %d", h[0])
+	}
+
+	content = make([]byte, binary.BigEndian.Uint16(h[4:]))
+	if _, err = io.ReadFull(c.r, content); err != nil {
+		return 0, 0, nil, err
+	}
+
+	if _, err = c.r.Discard(int(h[6])); err != nil {
+		return 0, 0, nil, err
+	}
+
+	return h[1], binary.BigEndian.Uint16(h[2:]), content, nil
+}
+
+// writeRecord must be called with c.mu locked
+func (c *fastCGIConn) writeRecord(typ uint8, id uint16, content []byte) error {
+	padding := -len(content) & 7
+
+	h := [fastCGIHeaderLen]byte{fastCGIVersion1, typ}
+	binary.BigEndian.PutUint16(h[2:], id)
+	binary.BigEndian.PutUint16(h[4:], uint16(len(content)))
+	h[6] = uint8(padding)
+
+	if _, err := c.w.Write(h[:]); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(content); err != nil {
+		return err
+	}
+
+	_, err := c.w.Write(make([]byte, padding))
+
+	return err
+}
+
+// writeStream splits the data into records
+func (c *fastCGIConn) writeStream(typ uint8, id uint16, p []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(p) > 0 {
+		n := min(len(p), fastCGIMaxContent)
+		if err := c.writeRecord(typ, id, p[:n]); err != nil {
+			return err
+		}
+		p = p[n:]
+	}
+
+	return nil
+}
+
+func (c *fastCGIConn) writeEndRequest(id uint16, protocolStatus uint8) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.writeRecord(fastCGIEndRequest, id, []byte{0, 0, 0, 0, protocolStatus, 0, 0, 0})
+	_ = c.w.Flush()
+}
+
+// writeGetValuesResult tells the client that requests are not multiplexed
+func (c *fastCGIConn) writeGetValuesResult(content []byte) {
+	names, err := parseFastCGIParams(content)
+	if err != nil {
+		return
+	}
+
+	var b bytes.Buffer
+	if _, ok := names["FCGI_MPXS_CONNS"]; ok {
+		writeFastCGIParam(&b, "FCGI_MPXS_CONNS", "0")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.writeRecord(fastCGIGetValuesResult, 0, b.Bytes())
+	_ = c.w.Flush()
+}
+
+// parseFastCGIParams decodes name-value pairs
+func parseFastCGIParams(data []byte) (map[string]string, error) {
+	params := make(map[string]string)
+
+	readLen := func() (int, bool) {
+		if len(data) == 0 {
+			return 0, false
+		}
+
+		if data[0]>>7 == 0 {
+			n := int(data[0])
+			data = data[1:]
+
+			return n, true
+		}
+
+		if len(data) < 4 {
+			return 0, false
+		}
+
+		n := int(binary.BigEndian.Uint32(data) & 0x7fffffff)
+		data = data[4:]
+
+		return n, true
+	}
+
+	for len(data) > 0 {
+		nameLen, ok := readLen()
+		if !ok {
+			return nil, errors.New("invalid FastCGI param name length")
+		}
+		valueLen, ok := readLen()
+		if !ok || len(data) < nameLen+valueLen {
+			return nil, errors.New("invalid FastCGI param value length")
+		}
+
+		params[string(data[:nameLen])] = string(data[nameLen : nameLen+valueLen])
+		data = data[nameLen+valueLen:]
+	}
+
+	return params, nil
+}
+
+func writeFastCGIParam(b *bytes.Buffer, name, value string) {
+	for _, n := range []int{len(name), len(value)} {
+		if n < 128 {
+			b.WriteByte(byte(n))
+		} else {
+			_ = binary.Write(b, binary.BigEndian, uint32(n)|1<<31)
+		}
+	}
+
+	b.WriteString(name)
+	b.WriteString(value)
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// fastCGIResponseWriter sends the response as a CGI response in FastCGI records
+type fastCGIResponseWriter struct {
+	c           *fastCGIConn
+	id          uint16
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *fastCGIResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *fastCGIResponseWriter) WriteHeader(statusCode int) {
+	// interim responses such as Early Hints can't be sent with FastCGI
+	if w.wroteHeader || (statusCode >= 100 && statusCode < 200) {
+		return
+	}
+	w.wroteHeader = true
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Status: %d %s\r\n", statusCode, http.StatusText(statusCode))
+	_ = w.header.Write(&b)
+	b.WriteString("\r\n")
+
+	_ = w.c.writeStream(fastCGIStdout, w.id, b.Bytes())
+}
+
+func (w *fastCGIResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if err := w.c.writeStream(fastCGIStdout, w.id, p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (w *fastCGIResponseWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.c.mu.Lock()
+	defer w.c.mu.Unlock()
+
+	return w.c.w.Flush()
+}
+
+func (w *fastCGIResponseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// finish ends the stream of the response
+func (w *fastCGIResponseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.c.mu.Lock()
+	_ = w.c.writeRecord(fastCGIStdout, w.id, nil)
+	w.c.mu.Unlock()
+}
@@ -0,0 +1,26 @@
+package frankenphp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFastCGIParams(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	var b bytes.Buffer
+	writeFastCGIParam(&b, "SCRIPT_NAME", "/index.php")
+	writeFastCGIParam(&b, "EMPTY", "")
+	writeFastCGIParam(&b, "LONG", long)
+
+	params, err := parseFastCGIParams(b.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"SCRIPT_NAME": "/index.php", "EMPTY": "", "LONG": long}, params)
+
+	_, err = parseFastCGIParams(b.Bytes()[:b.Len()-1])
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// PHPFatalError is returned when the script is stopped by a fatal error, such as a parse error or an uncaught exception.
+// Type is the E_* constant of the error.
+type PHPFatalError struct {
+	Type    int
+	Message string
+	File    string
+	Line    int
+}
+
+func (e *PHPFatalError) Error() string {
+	return fmt.Sprintf("PHP fatal error: %s in %s on line %d", e.Message, e.File, e.Line)
+}
+
+// go_frankenphp_report_fatal_error is called when the script has been stopped by a fatal error.
+// Exceptions already reported by go_frankenphp_report_exception take precedence.
+//
+//export go_frankenphp_report_fatal_error
+func go_frankenphp_report_fatal_error(threadIndex C.uintptr_t, errorType C.int, message *C.zend_string, file *C.zend_string, line C.int) {
+	fc := phpThreads[threadIndex].getRequestContext()
+	if fc == nil || fc.handlerError != nil {
+		return
+	}
+
+	fatalError := &PHPFatalError{
+		Type:    int(errorType),
+		Message: GoString(unsafe.Pointer(message)),
+		Line:    int(line),
+	}
+	if file != nil {
+		fatalError.File = GoString(unsafe.Pointer(file))
+	}
+
+	fc.handlerError = fatalError
+}
@@ -6,6 +6,7 @@
 #include <errno.h>
 #include <ext/spl/spl_exceptions.h>
 #include <ext/standard/head.h>
+#include <ext/standard/php_fopen_wrappers.h>
 #include <inttypes.h>
 #include <php.h>
 #include <php_config.h>
@@ -74,6 +75,9 @@ bool should_filter_var = 0;
 __thread uintptr_t thread_index;
 __thread bool is_worker_thread = false;
 __thread zval *os_environment = NULL;
+/* set when frankenphp_send_file() ends the callback of a worker, the worker
+ * itself keeps running */
+__thread bool is_file_sent = false;
 
 static void frankenphp_update_request_context() {
   /* the server context is stored on the go side, still SG(server_context) needs
@@ -99,6 +103,8 @@ static void frankenphp_free_request_context() {
   SG(request_info).content_type = NULL;
   SG(request_info).path_translated = NULL;
   SG(request_info).request_uri = NULL;
+  SG(request_info).argc = 0;
+  SG(request_info).argv = NULL;
 }
 
 /* reset all 'auto globals' in worker mode except of $_ENV
@@ -411,6 +417,580 @@ PHP_FUNCTION(frankenphp_response_headers) /* {{{ */
 }
 /* }}} */
 
+/* {{{ Fetch the deadline of the request context as a UNIX timestamp */
+PHP_FUNCTION(frankenphp_request_deadline) {
+  ZEND_PARSE_PARAMETERS_NONE();
+
+  struct go_frankenphp_request_deadline_return deadline =
+      go_frankenphp_request_deadline(thread_index);
+  if (!deadline.r0) {
+    RETURN_NULL();
+  }
+
+  RETURN_DOUBLE(deadline.r1);
+}
+/* }}} */
+
+/* {{{ Push a job to a persistent queue */
+PHP_FUNCTION(frankenphp_queue_push) {
+  zend_string *queue;
+  zval *payload;
+  zval *options = NULL;
+
+  ZEND_PARSE_PARAMETERS_START(2, 3)
+  Z_PARAM_STR(queue)
+  Z_PARAM_ZVAL(payload)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_ARRAY(options)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (Z_TYPE_P(payload) == IS_OBJECT || Z_TYPE_P(payload) == IS_RESOURCE) {
+    zend_argument_type_error(2, "must be a scalar, an array or null, %s given",
+                             zend_zval_type_name(payload));
+    RETURN_THROWS();
+  }
+
+  struct go_frankenphp_queue_push_return result =
+      go_frankenphp_queue_push(thread_index, queue, payload, options);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, ZSTR_VAL(result.r1), 0);
+    zend_string_release(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_STR(result.r0);
+}
+/* }}} */
+
+/* {{{ Fetch a value from the cache shared by all threads */
+PHP_FUNCTION(frankenphp_cache_get) {
+  zend_string *key;
+  zval *default_value = NULL;
+
+  ZEND_PARSE_PARAMETERS_START(1, 2)
+  Z_PARAM_STR(key)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_ZVAL(default_value)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_cache_get_return result =
+      go_frankenphp_cache_get(thread_index, key);
+  if (!result.r0) {
+    if (default_value != NULL) {
+      RETURN_COPY(default_value);
+    }
+
+    RETURN_NULL();
+  }
+
+  /* the zval has been allocated by Go, take ownership of its value */
+  RETURN_COPY_VALUE((zval *)result.r1);
+}
+/* }}} */
+
+/* {{{ Store a value in the cache shared by all threads */
+PHP_FUNCTION(frankenphp_cache_set) {
+  zend_string *key;
+  zval *value;
+  zend_long ttl = 0;
+
+  ZEND_PARSE_PARAMETERS_START(2, 3)
+  Z_PARAM_STR(key)
+  Z_PARAM_ZVAL(value)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_LONG(ttl)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (Z_TYPE_P(value) == IS_OBJECT || Z_TYPE_P(value) == IS_RESOURCE) {
+    zend_argument_type_error(2, "must be a scalar, an array or null, %s given",
+                             zend_zval_type_name(value));
+    RETURN_THROWS();
+  }
+
+  RETURN_BOOL(go_frankenphp_cache_set(key, value, ttl));
+}
+/* }}} */
+
+/* {{{ Delete a value from the cache shared by all threads */
+PHP_FUNCTION(frankenphp_cache_delete) {
+  zend_string *key;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(key)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_BOOL(go_frankenphp_cache_delete(key));
+}
+/* }}} */
+
+/* {{{ Atomically increment an integer stored in the cache shared by all
+ * threads */
+PHP_FUNCTION(frankenphp_cache_incr) {
+  zend_string *key;
+  zend_long step = 1;
+  zend_long ttl = 0;
+
+  ZEND_PARSE_PARAMETERS_START(1, 3)
+  Z_PARAM_STR(key)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_LONG(step)
+  Z_PARAM_LONG(ttl)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_cache_incr_return result =
+      go_frankenphp_cache_incr(key, step, ttl);
+  if (!result.r1) {
+    zend_throw_exception(spl_ce_RuntimeException,
+                         "the cached value is not an integer", 0);
+    RETURN_THROWS();
+  }
+
+  RETURN_LONG(result.r0);
+}
+/* }}} */
+
+static void frankenphp_acquire_semaphore(INTERNAL_FUNCTION_PARAMETERS,
+                                         zend_string *name, zend_long permits,
+                                         double timeout) {
+  struct go_frankenphp_semaphore_acquire_return result =
+      go_frankenphp_semaphore_acquire(thread_index, name, permits, timeout);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, ZSTR_VAL(result.r1), 0);
+    zend_string_release(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_BOOL(result.r0);
+}
+
+/* {{{ Acquire a lock shared by all threads */
+PHP_FUNCTION(frankenphp_lock) {
+  zend_string *name;
+  double timeout = -1;
+
+  ZEND_PARSE_PARAMETERS_START(1, 2)
+  Z_PARAM_STR(name)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_DOUBLE(timeout)
+  ZEND_PARSE_PARAMETERS_END();
+
+  frankenphp_acquire_semaphore(INTERNAL_FUNCTION_PARAM_PASSTHRU, name, 1,
+                               timeout);
+}
+/* }}} */
+
+/* {{{ Release a lock acquired by the current thread */
+PHP_FUNCTION(frankenphp_unlock) {
+  zend_string *name;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(name)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_BOOL(go_frankenphp_semaphore_release(thread_index, name));
+}
+/* }}} */
+
+/* {{{ Acquire a permit of a semaphore shared by all threads */
+PHP_FUNCTION(frankenphp_semaphore_acquire) {
+  zend_string *name;
+  zend_long permits;
+  double timeout = -1;
+
+  ZEND_PARSE_PARAMETERS_START(2, 3)
+  Z_PARAM_STR(name)
+  Z_PARAM_LONG(permits)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_DOUBLE(timeout)
+  ZEND_PARSE_PARAMETERS_END();
+
+  frankenphp_acquire_semaphore(INTERNAL_FUNCTION_PARAM_PASSTHRU, name, permits,
+                               timeout);
+}
+/* }}} */
+
+/* {{{ Release a permit acquired by the current thread */
+PHP_FUNCTION(frankenphp_semaphore_release) {
+  zend_string *name;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(name)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_BOOL(go_frankenphp_semaphore_release(thread_index, name));
+}
+/* }}} */
+
+/* {{{ Atomically add a value to a counter shared by all threads */
+PHP_FUNCTION(frankenphp_counter_add) {
+  zend_string *name;
+  zend_long delta = 1;
+
+  ZEND_PARSE_PARAMETERS_START(1, 2)
+  Z_PARAM_STR(name)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_LONG(delta)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_LONG(go_frankenphp_counter_add(name, delta));
+}
+/* }}} */
+
+/* {{{ Consume a token of a rate limiter shared by all threads */
+PHP_FUNCTION(frankenphp_rate_limit) {
+  zend_string *name;
+  zend_long limit;
+  double interval;
+
+  ZEND_PARSE_PARAMETERS_START(3, 3)
+  Z_PARAM_STR(name)
+  Z_PARAM_LONG(limit)
+  Z_PARAM_DOUBLE(interval)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_rate_limit_return result =
+      go_frankenphp_rate_limit(name, limit, interval);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, ZSTR_VAL(result.r1), 0);
+    zend_string_release(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_BOOL(result.r0);
+}
+/* }}} */
+
+/* {{{ Send a value to the subscribers of a topic */
+PHP_FUNCTION(frankenphp_publish) {
+  zend_string *topic;
+  zval *value;
+
+  ZEND_PARSE_PARAMETERS_START(2, 2)
+  Z_PARAM_STR(topic)
+  Z_PARAM_ZVAL(value)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (Z_TYPE_P(value) == IS_OBJECT || Z_TYPE_P(value) == IS_RESOURCE) {
+    zend_argument_type_error(2, "must be a scalar, an array or null, %s given",
+                             zend_zval_type_name(value));
+    RETURN_THROWS();
+  }
+
+  RETURN_LONG(go_frankenphp_publish(topic, value));
+}
+/* }}} */
+
+/* {{{ Receive the messages published on a topic */
+PHP_FUNCTION(frankenphp_subscribe) {
+  zend_string *topic;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(topic)
+  ZEND_PARSE_PARAMETERS_END();
+
+  go_frankenphp_subscribe(thread_index, topic);
+}
+/* }}} */
+
+/* {{{ Stop receiving the messages published on a topic */
+PHP_FUNCTION(frankenphp_unsubscribe) {
+  zend_string *topic;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(topic)
+  ZEND_PARSE_PARAMETERS_END();
+
+  go_frankenphp_unsubscribe(thread_index, topic);
+}
+/* }}} */
+
+/* {{{ Wait for a message published on a subscribed topic */
+PHP_FUNCTION(frankenphp_receive) {
+  double timeout = 0;
+
+  ZEND_PARSE_PARAMETERS_START(0, 1)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_DOUBLE(timeout)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_receive_return result =
+      go_frankenphp_receive(thread_index, timeout);
+  if (!result.r0) {
+    RETURN_NULL();
+  }
+
+  /* the zval has been allocated by Go, take ownership of its value */
+  RETURN_COPY_VALUE((zval *)result.r1);
+}
+/* }}} */
+
+/* {{{ Publish an update to the Mercure hub embedded in the server */
+PHP_FUNCTION(mercure_publish) {
+  zval *topics;
+  zend_string *data = NULL;
+  bool private = false;
+  zend_string *id = NULL;
+  zend_string *type = NULL;
+  zend_long retry = 0;
+
+  ZEND_PARSE_PARAMETERS_START(1, 6)
+  Z_PARAM_ZVAL(topics)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_STR(data)
+  Z_PARAM_BOOL(private)
+  Z_PARAM_STR_OR_NULL(id)
+  Z_PARAM_STR_OR_NULL(type)
+  Z_PARAM_LONG(retry)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (Z_TYPE_P(topics) != IS_STRING && Z_TYPE_P(topics) != IS_ARRAY) {
+    zend_argument_type_error(1, "must be of type array|string, %s given",
+                             zend_zval_type_name(topics));
+    RETURN_THROWS();
+  }
+
+  struct go_mercure_publish_return result =
+      go_mercure_publish(topics, data, private, id, type, retry);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, ZSTR_VAL(result.r1), 0);
+    zend_string_release(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_STR(result.r0);
+}
+/* }}} */
+
+/* {{{ Queue a message to send to a WebSocket connection */
+PHP_FUNCTION(frankenphp_ws_send) {
+  zend_string *connection;
+  zend_string *data;
+  bool binary = false;
+
+  ZEND_PARSE_PARAMETERS_START(2, 3)
+  Z_PARAM_STR(connection)
+  Z_PARAM_STR(data)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_BOOL(binary)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_BOOL(go_frankenphp_ws_send(connection, data, binary));
+}
+/* }}} */
+
+/* {{{ Queue a message to send to several WebSocket connections */
+PHP_FUNCTION(frankenphp_ws_broadcast) {
+  zend_string *data;
+  zval *connections = NULL;
+  bool binary = false;
+
+  ZEND_PARSE_PARAMETERS_START(1, 3)
+  Z_PARAM_STR(data)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_ARRAY_OR_NULL(connections)
+  Z_PARAM_BOOL(binary)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_LONG(go_frankenphp_ws_broadcast(data, connections, binary));
+}
+/* }}} */
+
+/* {{{ Close a WebSocket connection */
+PHP_FUNCTION(frankenphp_ws_close) {
+  zend_string *connection;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(connection)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_BOOL(go_frankenphp_ws_close(connection));
+}
+/* }}} */
+
+/* {{{ Finish the request and let Go write the messages published on the
+ * channel to the client */
+PHP_FUNCTION(frankenphp_stream_detach) {
+  zend_string *channel;
+  double timeout = 0;
+  zend_long limit = 0;
+
+  ZEND_PARSE_PARAMETERS_START(1, 3)
+  Z_PARAM_STR(channel)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_DOUBLE(timeout)
+  Z_PARAM_LONG(limit)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (go_is_context_done(thread_index)) {
+    RETURN_FALSE;
+  }
+
+  php_output_end_all();
+  php_header();
+
+  RETURN_BOOL(
+      go_frankenphp_stream_detach(thread_index, channel, timeout, limit));
+}
+/* }}} */
+
+/* {{{ Set an HTTP trailer sent after the response body */
+PHP_FUNCTION(frankenphp_set_trailer) {
+  zend_string *name;
+  zend_string *value;
+
+  ZEND_PARSE_PARAMETERS_START(2, 2)
+  Z_PARAM_STR(name)
+  Z_PARAM_STR(value)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_set_trailer_return result =
+      go_frankenphp_set_trailer(thread_index, name, value);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, ZSTR_VAL(result.r1), 0);
+    zend_string_release(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_BOOL(result.r0);
+}
+/* }}} */
+
+/* {{{ Finish the request and let Go send the file */
+PHP_FUNCTION(frankenphp_send_file) {
+  zend_string *path;
+  zval *options = NULL;
+
+  ZEND_PARSE_PARAMETERS_START(1, 2)
+  Z_PARAM_PATH_STR(path)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_ARRAY(options)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (SG(headers_sent)) {
+    zend_throw_exception(spl_ce_RuntimeException,
+                         "the headers have already been sent", 0);
+    RETURN_THROWS();
+  }
+
+  /* resolve the path relative to the working directory of the script */
+  char *resolved_path = expand_filepath(ZSTR_VAL(path), NULL);
+  if (resolved_path == NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, "invalid path", 0);
+    RETURN_THROWS();
+  }
+
+  zend_string *error = go_frankenphp_send_file(
+      thread_index, resolved_path, options, &SG(sapi_headers).headers);
+  efree(resolved_path);
+  if (error != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, ZSTR_VAL(error), 0);
+    zend_string_release(error);
+    RETURN_THROWS();
+  }
+
+  /* the response is sent by Go, the script ends as with exit() */
+  php_output_discard_all();
+  is_file_sent = true;
+  zend_throw_unwind_exit();
+}
+/* }}} */
+
+/* {{{ Check that all the variadic arguments are strings */
+static bool frankenphp_check_string_args(zval *args, uint32_t argc) {
+  for (uint32_t i = 0; i < argc; i++) {
+    if (Z_TYPE(args[i]) != IS_STRING) {
+      zend_argument_type_error(i + 1, "must be of type string, %s given",
+                               zend_zval_type_name(&args[i]));
+      return false;
+    }
+  }
+
+  return true;
+}
+/* }}} */
+
+/* {{{ Add tags to the cached response, to purge it later */
+PHP_FUNCTION(frankenphp_response_cache_tag) {
+  zval *args = NULL;
+  uint32_t argc = 0;
+
+  ZEND_PARSE_PARAMETERS_START(0, -1)
+  Z_PARAM_VARIADIC('*', args, argc)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (!frankenphp_check_string_args(args, argc)) {
+    RETURN_THROWS();
+  }
+
+  for (uint32_t i = 0; i < argc; i++) {
+    go_frankenphp_response_cache_tag(thread_index, Z_STR(args[i]));
+  }
+}
+/* }}} */
+
+/* {{{ Remove the cached responses having one of the tags, or all of them */
+PHP_FUNCTION(frankenphp_response_cache_purge) {
+  zval *args = NULL;
+  uint32_t argc = 0;
+
+  ZEND_PARSE_PARAMETERS_START(0, -1)
+  Z_PARAM_VARIADIC('*', args, argc)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (!frankenphp_check_string_args(args, argc)) {
+    RETURN_THROWS();
+  }
+
+  if (argc == 0) {
+    RETURN_LONG(go_frankenphp_response_cache_purge(NULL));
+  }
+
+  zend_long purged = 0;
+  for (uint32_t i = 0; i < argc; i++) {
+    purged += go_frankenphp_response_cache_purge(Z_STR(args[i]));
+  }
+
+  RETURN_LONG(purged);
+}
+/* }}} */
+
+/* {{{ Send internal HTTP requests handled by other threads in parallel */
+PHP_FUNCTION(frankenphp_subrequest) {
+  zval *requests;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_ARRAY(requests)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_subrequest_return result =
+      go_frankenphp_subrequest(thread_index, requests);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, ZSTR_VAL(result.r1), 0);
+    zend_string_release(result.r1);
+    RETURN_THROWS();
+  }
+
+  /* the zval has been allocated by Go, take ownership of its value */
+  RETURN_COPY_VALUE((zval *)result.r0);
+}
+/* }}} */
+
+/* Pass an uncaught exception to Go before it is turned into a fatal error */
+static void frankenphp_report_exception(zend_object *ex) {
+  zend_class_entry *base = zend_get_exception_base(ex);
+  zval message_rv, code_rv;
+
+  zval *message = zend_read_property_ex(base, ex, ZSTR_KNOWN(ZEND_STR_MESSAGE),
+                                        1, &message_rv);
+  zval *code =
+      zend_read_property_ex(base, ex, ZSTR_KNOWN(ZEND_STR_CODE), 1, &code_rv);
+
+  zend_string *message_str = zval_get_string(message);
+  go_frankenphp_report_exception(thread_index, ex->ce->name, message_str,
+                                 zval_get_long(code));
+  zend_string_release(message_str);
+}
+
 PHP_FUNCTION(frankenphp_handle_request) {
   zend_fcall_info fci;
   zend_fcall_info_cache fcc;
@@ -458,16 +1038,26 @@ PHP_FUNCTION(frankenphp_handle_request) {
   fci.params = result.r1;
   fci.param_count = result.r1 == NULL ? 0 : 1;
 
+  is_file_sent = false;
   if (zend_call_function(&fci, &fcc) == SUCCESS && Z_TYPE(retval) != IS_UNDEF) {
     callback_ret = &retval;
   }
 
+  /* frankenphp_send_file() only ends the callback, not the worker */
+  if (is_file_sent) {
+    is_file_sent = false;
+    if (EG(exception) && zend_is_unwind_exit(EG(exception))) {
+      zend_clear_exception();
+    }
+  }
+
   /*
    * If an exception occurred, print the message to the client before
    * closing the connection and bailout.
    */
   if (EG(exception) && !zend_is_unwind_exit(EG(exception)) &&
       !zend_is_graceful_exit(EG(exception))) {
+    frankenphp_report_exception(EG(exception));
     zend_exception_error(EG(exception), E_ERROR);
     zend_bailout();
   }
@@ -505,9 +1095,25 @@ PHP_FUNCTION(headers_send) {
   RETURN_LONG(sapi_send_headers());
 }
 
+static void (*original_zend_interrupt_function)(zend_execute_data *) = NULL;
+
+/* Stop the scripts executed with RunScript() once their context is done */
+static void frankenphp_interrupt_function(zend_execute_data *execute_data) {
+  if (go_frankenphp_is_script_cancelled(thread_index)) {
+    zend_error_noreturn(E_ERROR, "The script has been cancelled");
+  }
+
+  if (original_zend_interrupt_function != NULL) {
+    original_zend_interrupt_function(execute_data);
+  }
+}
+
 PHP_MINIT_FUNCTION(frankenphp) {
   zend_function *func;
 
+  original_zend_interrupt_function = zend_interrupt_function;
+  zend_interrupt_function = frankenphp_interrupt_function;
+
   // Override putenv
   func = zend_hash_str_find_ptr(CG(function_table), "putenv",
                                 sizeof("putenv") - 1);
@@ -981,6 +1587,130 @@ bool frankenphp_new_php_thread(uintptr_t thread_index) {
   return true;
 }
 
+/* {{{ Streams of the scripts executed with RunScript(), passed to Go */
+static ssize_t frankenphp_cli_stream_write(php_stream *stream, const char *buf,
+                                           size_t count) {
+  return go_frankenphp_cli_write(thread_index, (int)(intptr_t)stream->abstract,
+                                 (char *)buf, count);
+}
+
+static ssize_t frankenphp_cli_stream_read(php_stream *stream, char *buf,
+                                          size_t count) {
+  ssize_t read = go_frankenphp_cli_read(thread_index, buf, count);
+  if (read <= 0) {
+    stream->eof = 1;
+  }
+
+  return read;
+}
+
+static int frankenphp_cli_stream_close(php_stream *stream, int close_handle) {
+  return 0;
+}
+
+static int frankenphp_cli_stream_flush(php_stream *stream) { return 0; }
+
+static const php_stream_ops frankenphp_cli_stream_ops = {
+    frankenphp_cli_stream_write,
+    frankenphp_cli_stream_read,
+    frankenphp_cli_stream_close,
+    frankenphp_cli_stream_flush,
+    "FrankenPHP CLI",
+    NULL, /* seek */
+    NULL, /* cast */
+    NULL, /* stat */
+    NULL, /* set_option */
+};
+
+/* php://stdin, php://stdout and php://stderr use the streams of the script,
+ * the other php:// streams are opened as usual */
+static php_stream *frankenphp_cli_stream_opener(
+    php_stream_wrapper *wrapper, const char *path, const char *mode,
+    int options, zend_string **opened_path,
+    php_stream_context *context STREAMS_DC) {
+  static const char *names[] = {"stdin", "stdout", "stderr"};
+
+  if (!strncasecmp(path, "php://", 6)) {
+    for (int fd = 0; fd < 3; fd++) {
+      if (!strcasecmp(path + 6, names[fd])) {
+        return php_stream_alloc_rel(&frankenphp_cli_stream_ops,
+                                    (void *)(intptr_t)fd, NULL, mode);
+      }
+    }
+  }
+
+  return php_stream_php_wrapper.wops->stream_opener(
+      wrapper, path, mode, options, opened_path, context STREAMS_REL_CC);
+}
+
+static const php_stream_wrapper_ops frankenphp_cli_wrapper_ops = {
+    frankenphp_cli_stream_opener,
+    NULL, /* close */
+    NULL, /* fstat */
+    NULL, /* stat */
+    NULL, /* opendir */
+    "PHP",
+    NULL, /* unlink */
+    NULL, /* rename */
+    NULL, /* mkdir */
+    NULL, /* rmdir */
+    NULL, /* metadata */
+};
+
+static php_stream_wrapper frankenphp_cli_wrapper = {
+    &frankenphp_cli_wrapper_ops,
+    NULL,
+    0,
+};
+
+/* Register the STDIN, STDOUT and STDERR constants and override the php://
+ * wrapper for the current request only */
+static void frankenphp_register_cli_streams(void) {
+  static const char *names[] = {"STDIN", "STDOUT", "STDERR"};
+  static const char *modes[] = {"rb", "wb", "wb"};
+
+  for (int fd = 0; fd < 3; fd++) {
+    zend_constant c;
+    php_stream *s = php_stream_alloc(&frankenphp_cli_stream_ops,
+                                     (void *)(intptr_t)fd, NULL, modes[fd]);
+
+    php_stream_to_zval(s, &c.value);
+    ZEND_CONSTANT_SET_FLAGS(&c, CONST_CS, 0);
+    c.name = zend_string_init_interned(names[fd], strlen(names[fd]), 0);
+    zend_register_constant(&c);
+  }
+
+  zend_string *protocol = zend_string_init("php", sizeof("php") - 1, 0);
+  php_unregister_url_stream_wrapper_volatile(protocol);
+  php_register_url_stream_wrapper_volatile(protocol, &frankenphp_cli_wrapper);
+  zend_string_release(protocol);
+}
+/* }}} */
+
+/* $_SERVER variables of the scripts executed with RunScript(), as with the
+ * CLI */
+void frankenphp_register_cli_variables(char *script, zval *track_vars_array) {
+  static const char *keys[] = {"PHP_SELF", "SCRIPT_NAME", "SCRIPT_FILENAME",
+                               "PATH_TRANSLATED"};
+
+  for (size_t i = 0; i < sizeof(keys) / sizeof(keys[0]); i++) {
+    char *val = script;
+    size_t len = strlen(script);
+    register_server_variable_filtered(keys[i], &val, &len, track_vars_array);
+  }
+
+  char *docroot = "";
+  size_t len = 0U;
+  register_server_variable_filtered("DOCUMENT_ROOT", &docroot, &len,
+                                    track_vars_array);
+
+  php_build_argv(NULL, track_vars_array);
+}
+
+void frankenphp_interrupt_script(void *vm_interrupt) {
+  zend_atomic_bool_store((zend_atomic_bool *)vm_interrupt, true);
+}
+
 static int frankenphp_request_startup() {
   frankenphp_update_request_context();
   if (php_request_startup() == SUCCESS) {
@@ -994,11 +1724,22 @@ static int frankenphp_request_startup() {
 }
 
 int frankenphp_execute_script(char *file_name) {
+  /* Scripts executed with RunScript() behave as with the CLI */
+  bool is_cli_script =
+      go_frankenphp_start_cli_script(thread_index, &EG(vm_interrupt));
+
   if (frankenphp_request_startup() == FAILURE) {
 
     return FAILURE;
   }
 
+  if (is_cli_script) {
+    frankenphp_register_cli_streams();
+    /* $argv and $argc are set even if $_SERVER isn't used */
+    php_build_argv(NULL, NULL);
+    CG(skip_shebang) = 1;
+  }
+
   int status = SUCCESS;
 
   zend_file_handle file_handle;
@@ -1014,6 +1755,16 @@ int frankenphp_execute_script(char *file_name) {
   zend_catch { status = EG(exit_status); }
   zend_end_try();
 
+  /* Pass fatal errors to Go, the last error is cleared when the request shuts
+   * down */
+  if (PG(last_error_message) != NULL &&
+      (PG(last_error_type) & (E_ERROR | E_CORE_ERROR | E_COMPILE_ERROR |
+                              E_USER_ERROR | E_RECOVERABLE_ERROR | E_PARSE))) {
+    go_frankenphp_report_fatal_error(
+        thread_index, PG(last_error_type), PG(last_error_message),
+        PG(last_error_file), PG(last_error_lineno));
+  }
+
   // free the cached os environment before shutting down the script
   if (os_environment != NULL) {
     zval_ptr_dtor(os_environment);
@@ -1023,6 +1774,10 @@ int frankenphp_execute_script(char *file_name) {
 
   zend_destroy_file_handle(&file_handle);
 
+  if (is_cli_script) {
+    CG(skip_shebang) = 0;
+  }
+
   frankenphp_request_shutdown();
 
   return status;
@@ -30,8 +30,9 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"slices"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
@@ -50,16 +51,12 @@ var (
 	ErrMainThreadCreation     = errors.New("error creating the main thread")
 	ErrRequestContextCreation = errors.New("error during request context creation")
 	ErrScriptExecution        = errors.New("error during PHP script execution")
+	ErrWorkerNotFound         = errors.New("worker not found")
+	ErrMaxWaitTimeExceeded    = errors.New("max wait time exceeded while waiting for a thread")
 	ErrNotRunning             = errors.New("FrankenPHP is not running. For proper configuration visit: https://frankenphp.dev/docs/config/#caddyfile-config")
 
-	isRunning bool
-
-	loggerMu sync.RWMutex
-	logger   *slog.Logger
-
-	metrics Metrics = nullMetrics{}
-
-	maxWaitTime time.Duration
+	// activeServer is the server owning the PHP runtime
+	activeServer atomic.Pointer[Server]
 )
 
 type syslogLevel int
@@ -146,8 +143,6 @@ func calculateMaxThreads(opt *opt) (int, int, int, error) {
 			// https://github.com/php/frankenphp/issues/126
 			opt.workers[i].num = maxProcs
 		}
-		metrics.TotalWorkers(w.name, w.num)
-
 		numWorkers += opt.workers[i].num
 	}
 
@@ -201,12 +196,34 @@ func calculateMaxThreads(opt *opt) (int, int, int, error) {
 	return opt.numThreads, numWorkers, opt.maxThreads, nil
 }
 
-// Init starts the PHP runtime and the configured workers.
+// Init starts the PHP runtime and the configured workers, it is a shortcut for NewServer(options...).Start().
 func Init(options ...Option) error {
-	if isRunning {
+	return NewServer(options...).Start()
+}
+
+// Start starts the PHP runtime and the workers of the server.
+//
+// The PHP runtime is global to the process, so ErrAlreadyStarted is returned if another server is running.
+// A server can be started again once it has been shut down.
+func (s *Server) Start() (err error) {
+	if !activeServer.CompareAndSwap(nil, s) {
 		return ErrAlreadyStarted
 	}
-	isRunning = true
+
+	// a server failing to start releases the runtime
+	threadsStarted := false
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if threadsStarted {
+			s.Shutdown()
+		}
+		activeServer.Store(nil)
+	}()
+
+	options := slices.Clone(s.options)
 
 	// Ignore all SIGPIPE signals to prevent weird issues with systemd: https://github.com/php/frankenphp/issues/1020
 	// Docker/Moby has a similar hack: https://github.com/moby/moby/blob/d828b032a87606ae34267e349bf7f7ccb1f6495a/cmd/dockerd/docker.go#L87-L90
@@ -226,32 +243,46 @@ func Init(options ...Option) error {
 		}
 	}
 
-	if opt.logger == nil {
+	s.logger = opt.logger
+	if s.logger == nil {
 		// set a default logger
 		// to disable logging, set the logger to slog.New(slog.NewTextHandler(io.Discard, nil))
-		l := slog.New(slog.NewTextHandler(os.Stdout, nil))
+		s.logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	}
 
-		loggerMu.Lock()
-		logger = l
-		loggerMu.Unlock()
-	} else {
-		loggerMu.Lock()
-		logger = opt.logger
-		loggerMu.Unlock()
+	s.metrics = opt.metrics
+	if s.metrics == nil {
+		s.metrics = nullMetrics{}
 	}
 
-	if opt.metrics != nil {
-		metrics = opt.metrics
+	s.maxWaitTime = opt.maxWaitTime
+
+	if opt.cacheMaxSize == 0 {
+		opt.cacheMaxSize = defaultCacheMaxSize
 	}
+	sharedCache = newCache(opt.cacheMaxSize)
+	sharedCache.metrics = s.metrics
+	mercurePublisher = opt.mercure
 
-	maxWaitTime = opt.maxWaitTime
+	if opt.responseCacheMaxSize == 0 {
+		opt.responseCacheMaxSize = defaultResponseCacheMaxSize
+	}
+	if opt.responseCacheDir != "" {
+		if err := os.MkdirAll(opt.responseCacheDir, 0o700); err != nil {
+			return err
+		}
+	}
+	responseCacheStore = newResponseCache(opt.responseCacheMaxSize, opt.responseCacheDir)
 
 	totalThreadCount, workerThreadCount, maxThreadCount, err := calculateMaxThreads(opt)
 	if err != nil {
 		return err
 	}
 
-	metrics.TotalThreads(totalThreadCount)
+	s.metrics.TotalThreads(totalThreadCount)
+	for _, w := range opt.workers {
+		s.metrics.TotalWorkers(w.name, w.num)
+	}
 
 	config := Config()
 
@@ -261,17 +292,18 @@ func Init(options ...Option) error {
 
 	if config.ZTS {
 		if !config.ZendMaxExecutionTimers && runtime.GOOS == "linux" {
-			logger.Warn(`Zend Max Execution Timers are not enabled, timeouts (e.g. "max_execution_time") are disabled, recompile PHP with the "--enable-zend-max-execution-timers" configuration option to fix this issue`)
+			s.logger.Warn(`Zend Max Execution Timers are not enabled, timeouts (e.g. "max_execution_time") are disabled, recompile PHP with the "--enable-zend-max-execution-timers" configuration option to fix this issue`)
 		}
 	} else {
 		totalThreadCount = 1
-		logger.Warn(`ZTS is not enabled, only 1 thread will be available, recompile PHP using the "--enable-zts" configuration option or performance will be degraded`)
+		s.logger.Warn(`ZTS is not enabled, only 1 thread will be available, recompile PHP using the "--enable-zts" configuration option or performance will be degraded`)
 	}
 
-	mainThread, err := initPHPThreads(totalThreadCount, maxThreadCount, opt.phpIni)
+	mainThread, err := s.initPHPThreads(totalThreadCount, maxThreadCount, opt.phpIni)
 	if err != nil {
 		return err
 	}
+	threadsStarted = true
 
 	regularRequestChan = make(chan *frankenPHPContext, totalThreadCount-workerThreadCount)
 	regularThreads = make([]*phpThread, 0, totalThreadCount-workerThreadCount)
@@ -279,45 +311,79 @@ func Init(options ...Option) error {
 		convertToRegularThread(getInactivePHPThread())
 	}
 
-	if err := initWorkers(opt.workers); err != nil {
+	if err := s.initWorkers(opt.workers); err != nil {
+		return err
+	}
+
+	if err := s.initQueues(opt.queueDir, opt.workers); err != nil {
+		return err
+	}
+
+	if err := s.initSchedule(opt.schedule); err != nil {
 		return err
 	}
 
 	initAutoScaling(mainThread)
 
 	ctx := context.Background()
-	logger.LogAttrs(ctx, slog.LevelInfo, "FrankenPHP started 🐘", slog.String("php_version", Version().Version), slog.Int("num_threads", mainThread.numThreads), slog.Int("max_threads", mainThread.maxThreads))
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "FrankenPHP started 🐘", slog.String("php_version", Version().Version), slog.Int("num_threads", mainThread.numThreads), slog.Int("max_threads", mainThread.maxThreads))
 	if EmbeddedAppPath != "" {
-		logger.LogAttrs(ctx, slog.LevelInfo, "embedded PHP app 📦", slog.String("path", EmbeddedAppPath))
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "embedded PHP app 📦", slog.String("path", EmbeddedAppPath))
 	}
 
 	return nil
 }
 
-// Shutdown stops the workers and the PHP runtime.
+// Shutdown stops the workers and the PHP runtime of the running server.
 func Shutdown() {
-	if !isRunning {
+	if s := activeServer.Load(); s != nil {
+		s.Shutdown()
+	}
+}
+
+// Shutdown stops the workers and the PHP runtime if the server is running.
+func (s *Server) Shutdown() {
+	if activeServer.Load() != s {
 		return
 	}
 
+	s.drainSchedule()
+	drainWebSockets()
+	drainStreams()
+	drainResponseCache()
 	drainWatcher()
+	drainExtensionWorkers()
+	drainQueues()
 	drainAutoScaling()
 	drainPHPThreads()
+	closeQueues()
+	responseCacheStore.purge()
 
-	metrics.Shutdown()
+	s.metrics.Shutdown()
 
 	// Remove the installed app
 	if EmbeddedAppPath != "" {
 		_ = os.RemoveAll(EmbeddedAppPath)
 	}
 
-	isRunning = false
-	logger.Debug("FrankenPHP shut down")
+	activeServer.Store(nil)
+	s.logger.Debug("FrankenPHP shut down")
 }
 
-// ServeHTTP executes a PHP script according to the given context.
+// ServeHTTP executes a PHP script according to the given context with the running server.
 func ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) error {
-	if !isRunning {
+	s := activeServer.Load()
+	if s == nil {
+		return ErrNotRunning
+	}
+
+	return s.ServeHTTP(responseWriter, request)
+}
+
+// ServeHTTP executes a PHP script according to the given context.
+// The request must have been created with NewRequestWithContext.
+func (s *Server) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) error {
+	if activeServer.Load() != s {
 		return ErrNotRunning
 	}
 
@@ -326,22 +392,101 @@ func ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) error
 		return ErrInvalidRequest
 	}
 
+	fc.server = s
 	fc.responseWriter = responseWriter
+	if fc.logger == nil {
+		fc.logger = s.logger
+	}
 
 	if !fc.validate() {
 		return nil
 	}
 
+	// ESI tags are replaced once the response is complete, including responses served from the cache
+	if fc.esi {
+		fc.addSurrogateCapability()
+
+		esiWriter := newESIResponseWriter(fc.responseWriter)
+		fc.responseWriter = esiWriter
+		defer fc.finishESI(esiWriter)
+	}
+
+	var recorder *responseCacheRecorder
+	if fc.cacheResponse && fc.isResponseCacheable() {
+		// Cache hits don't use a PHP thread
+		if fc.serveCachedResponse() {
+			return nil
+		}
+
+		recorder = newResponseCacheRecorder(responseWriter)
+		fc.responseWriter = recorder
+	}
+
+	// Identical requests wait for the one being handled instead of using a PHP thread
+	if fc.coalesce && fc.isResponseCacheable() {
+		key := fc.requestFingerprint(fc.request.Method, fc.coalescingVary)
+
+		c, leader := joinCoalescedRequest(key)
+		if !leader && fc.serveCoalescedResponse(c) {
+			return nil
+		}
+
+		if leader {
+			coalescingRecorder := newCoalescingRecorder(fc.responseWriter)
+			fc.responseWriter = coalescingRecorder
+			defer fc.finishCoalescedRequest(key, c, coalescingRecorder)
+		}
+	}
+
+	var bufferedWriter *bufferedResponseWriter
+	if fc.responseBufferMaxMemory > 0 {
+		bufferedWriter = newBufferedResponseWriter(fc.responseWriter, fc.responseBufferMaxMemory)
+		fc.responseWriter = bufferedWriter
+	}
+
+	s.dispatch(fc)
+
+	// The script has finished, send the buffered output to the client
+	if bufferedWriter != nil {
+		if err := bufferedWriter.finish(); err != nil {
+			fc.logger.LogAttrs(context.Background(), slog.LevelWarn, "write error", slog.Any("error", err))
+		}
+	}
+
+	fc.serveHandedOffResponse()
+
+	if recorder != nil {
+		fc.storeResponse(fc.responseCacheKey(), recorder)
+	}
+
+	return nil
+}
+
+// serveHandedOffResponse sends the parts of the response handled by Go once the script has finished
+func (fc *frankenPHPContext) serveHandedOffResponse() {
+	// The script detached the response, keep writing it until the stream ends
+	if fc.stream != nil {
+		fc.serveDetachedStream()
+	}
+
+	if fc.sentFile != nil {
+		fc.serveSentFile()
+	}
+
+	fc.writeTrailers()
+}
+
+// dispatch sends the request to a PHP thread and waits for the script to finish handling it
+func (s *Server) dispatch(fc *frankenPHPContext) {
 	// Detect if a worker is available to handle this request
 	if fc.worker != nil {
 		fc.worker.handleRequest(fc)
 
-		return nil
+		return
 	}
 
 	// If no worker was available, send the request to non-worker threads
-	handleRequestWithRegularPHPThreads(fc)
-	return nil
+	s.handleRequestWithRegularPHPThreads(fc, nil)
 }
 
 //export go_ub_write
@@ -382,7 +527,7 @@ func go_apache_request_headers(threadIndex C.uintptr_t) (*C.go_string, C.size_t)
 	if fc.responseWriter == nil {
 		// worker mode, not handling a request
 
-		logger.LogAttrs(context.Background(), slog.LevelDebug, "apache_request_headers() called in non-HTTP context", slog.String("worker", fc.worker.name))
+		fc.logger.LogAttrs(context.Background(), slog.LevelDebug, "apache_request_headers() called in non-HTTP context", slog.String("worker", fc.worker.name))
 
 		return nil, 0
 	}
@@ -491,16 +636,16 @@ func go_write_headers(threadIndex C.uintptr_t, status C.int, headers *C.zend_lli
 //export go_sapi_flush
 func go_sapi_flush(threadIndex C.uintptr_t) bool {
 	fc := phpThreads[threadIndex].getRequestContext()
-	if fc == nil || fc.responseWriter == nil {
+	if fc == nil || fc.responseWriter == nil || fc.isDone {
 		return false
 	}
 
-	if fc.clientHasClosed() && !fc.isDone {
+	if fc.clientHasClosed() {
 		return true
 	}
 
 	if err := http.NewResponseController(fc.responseWriter).Flush(); err != nil {
-		logger.LogAttrs(context.Background(), slog.LevelWarn, "the current responseWriter is not a flusher, if you are not using a custom build, please report this issue", slog.Any("error", err))
+		fc.logger.LogAttrs(context.Background(), slog.LevelWarn, "the current responseWriter is not a flusher, if you are not using a custom build, please report this issue", slog.Any("error", err))
 	}
 
 	return false
@@ -546,6 +691,7 @@ func go_read_cookies(threadIndex C.uintptr_t) *C.char {
 
 //export go_log
 func go_log(message *C.char, level C.int) {
+	logger := mainThread.server.logger
 	m := C.GoString(message)
 
 	var le syslogLevel
@@ -574,6 +720,21 @@ func go_is_context_done(threadIndex C.uintptr_t) C.bool {
 	return C.bool(phpThreads[threadIndex].getRequestContext().isDone)
 }
 
+//export go_frankenphp_request_deadline
+func go_frankenphp_request_deadline(threadIndex C.uintptr_t) (C.bool, C.double) {
+	fc := phpThreads[threadIndex].getRequestContext()
+	if fc == nil {
+		return C.bool(false), 0
+	}
+
+	deadline, ok := fc.deadline()
+	if !ok {
+		return C.bool(false), 0
+	}
+
+	return C.bool(true), C.double(float64(deadline.UnixMicro()) / 1e6)
+}
+
 // ExecuteScriptCLI executes the PHP script passed as parameter.
 // It returns the exit status code of the script.
 func ExecuteScriptCLI(script string, args []string) int {
@@ -55,6 +55,8 @@ int frankenphp_execute_script(char *file_name);
 
 int frankenphp_execute_script_cli(char *script, int argc, char **argv,
                                   bool eval);
+void frankenphp_register_cli_variables(char *script, zval *track_vars_array);
+void frankenphp_interrupt_script(void *vm_interrupt);
 
 void frankenphp_register_variables_from_request_info(
     zval *track_vars_array, zend_string *content_type,
@@ -27,6 +27,58 @@ function getallheaders(): array {}
 
 function frankenphp_response_headers(): array|bool {}
 
+function frankenphp_request_deadline(): ?float {}
+
+function frankenphp_queue_push(string $queue, mixed $payload, array $options = []): string {}
+
+function frankenphp_cache_get(string $key, mixed $default = null): mixed {}
+
+function frankenphp_cache_set(string $key, mixed $value, int $ttl = 0): bool {}
+
+function frankenphp_cache_delete(string $key): bool {}
+
+function frankenphp_cache_incr(string $key, int $step = 1, int $ttl = 0): int {}
+
+function frankenphp_lock(string $name, float $timeout = -1): bool {}
+
+function frankenphp_unlock(string $name): bool {}
+
+function frankenphp_semaphore_acquire(string $name, int $permits, float $timeout = -1): bool {}
+
+function frankenphp_semaphore_release(string $name): bool {}
+
+function frankenphp_counter_add(string $name, int $delta = 1): int {}
+
+function frankenphp_rate_limit(string $name, int $limit, float $interval): bool {}
+
+function frankenphp_publish(string $topic, mixed $value): int {}
+
+function frankenphp_subscribe(string $topic): void {}
+
+function frankenphp_unsubscribe(string $topic): void {}
+
+function frankenphp_receive(float $timeout = 0): ?array {}
+
+function mercure_publish(string|array $topics, string $data = '', bool $private = false, ?string $id = null, ?string $type = null, int $retry = 0): string {}
+
+function frankenphp_ws_send(string $connection, string $data, bool $binary = false): bool {}
+
+function frankenphp_ws_broadcast(string $data, ?array $connections = null, bool $binary = false): int {}
+
+function frankenphp_ws_close(string $connection): bool {}
+
+function frankenphp_stream_detach(string $channel, float $timeout = 0, int $limit = 0): bool {}
+
+function frankenphp_set_trailer(string $name, string $value): bool {}
+
+function frankenphp_send_file(string $path, array $options = []): never {}
+
+function frankenphp_response_cache_tag(string ...$tags): void {}
+
+function frankenphp_response_cache_purge(string ...$tags): int {}
+
+function frankenphp_subrequest(array $requests): array {}
+
 /**
  * @alias frankenphp_response_headers
  */
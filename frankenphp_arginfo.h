@@ -1,5 +1,5 @@
 /* This is a generated file, edit the .stub.php file instead.
- * Stub hash: 05ebde17137c559e891362fba6524fad1e0a2dfe */
+ * Stub hash: 09509d683cb00101ee8232efe24e155f93544ee7 */
 
 ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_handle_request, 0, 1,
                                         _IS_BOOL, 0)
@@ -30,11 +30,187 @@ ZEND_END_ARG_INFO()
 
 #define arginfo_apache_response_headers arginfo_frankenphp_response_headers
 
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_request_deadline, 0,
+                                        0, IS_DOUBLE, 1)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_queue_push, 0, 2,
+                                        IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, queue, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, payload, IS_MIXED, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, options, IS_ARRAY, 0, "[]")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_cache_get, 0, 1,
+                                        IS_MIXED, 0)
+ZEND_ARG_TYPE_INFO(0, key, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, default, IS_MIXED, 0, "null")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_cache_set, 0, 2,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, key, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, value, IS_MIXED, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, ttl, IS_LONG, 0, "0")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_cache_delete, 0, 1,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, key, IS_STRING, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_cache_incr, 0, 1,
+                                        IS_LONG, 0)
+ZEND_ARG_TYPE_INFO(0, key, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, step, IS_LONG, 0, "1")
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, ttl, IS_LONG, 0, "0")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_lock, 0, 1, _IS_BOOL,
+                                        0)
+ZEND_ARG_TYPE_INFO(0, name, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, timeout, IS_DOUBLE, 0, "-1")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_unlock, 0, 1,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, name, IS_STRING, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_semaphore_acquire, 0,
+                                        2, _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, name, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, permits, IS_LONG, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, timeout, IS_DOUBLE, 0, "-1")
+ZEND_END_ARG_INFO()
+
+#define arginfo_frankenphp_semaphore_release arginfo_frankenphp_unlock
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_counter_add, 0, 1,
+                                        IS_LONG, 0)
+ZEND_ARG_TYPE_INFO(0, name, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, delta, IS_LONG, 0, "1")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_rate_limit, 0, 3,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, name, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, limit, IS_LONG, 0)
+ZEND_ARG_TYPE_INFO(0, interval, IS_DOUBLE, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_publish, 0, 2,
+                                        IS_LONG, 0)
+ZEND_ARG_TYPE_INFO(0, topic, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, value, IS_MIXED, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_subscribe, 0, 1,
+                                        IS_VOID, 0)
+ZEND_ARG_TYPE_INFO(0, topic, IS_STRING, 0)
+ZEND_END_ARG_INFO()
+
+#define arginfo_frankenphp_unsubscribe arginfo_frankenphp_subscribe
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_receive, 0, 0,
+                                        IS_ARRAY, 1)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, timeout, IS_DOUBLE, 0, "0")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_mercure_publish, 0, 1,
+                                        IS_STRING, 0)
+ZEND_ARG_TYPE_MASK(0, topics, MAY_BE_STRING | MAY_BE_ARRAY, NULL)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, data, IS_STRING, 0, "\'\'")
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, private, _IS_BOOL, 0, "false")
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, id, IS_STRING, 1, "null")
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, type, IS_STRING, 1, "null")
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, retry, IS_LONG, 0, "0")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_ws_send, 0, 2,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, connection, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, data, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, binary, _IS_BOOL, 0, "false")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_ws_broadcast, 0, 1,
+                                        IS_LONG, 0)
+ZEND_ARG_TYPE_INFO(0, data, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, connections, IS_ARRAY, 1, "null")
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, binary, _IS_BOOL, 0, "false")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_ws_close, 0, 1,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, connection, IS_STRING, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_stream_detach, 0, 1,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, channel, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, timeout, IS_DOUBLE, 0, "0")
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, limit, IS_LONG, 0, "0")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_set_trailer, 0, 2,
+                                        _IS_BOOL, 0)
+ZEND_ARG_TYPE_INFO(0, name, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO(0, value, IS_STRING, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_send_file, 0, 1,
+                                        IS_NEVER, 0)
+ZEND_ARG_TYPE_INFO(0, path, IS_STRING, 0)
+ZEND_ARG_TYPE_INFO_WITH_DEFAULT_VALUE(0, options, IS_ARRAY, 0, "[]")
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_response_cache_tag,
+                                        0, 0, IS_VOID, 0)
+ZEND_ARG_VARIADIC_TYPE_INFO(0, tags, IS_STRING, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_response_cache_purge,
+                                        0, 0, IS_LONG, 0)
+ZEND_ARG_VARIADIC_TYPE_INFO(0, tags, IS_STRING, 0)
+ZEND_END_ARG_INFO()
+
+ZEND_BEGIN_ARG_WITH_RETURN_TYPE_INFO_EX(arginfo_frankenphp_subrequest, 0, 1,
+                                        IS_ARRAY, 0)
+ZEND_ARG_TYPE_INFO(0, requests, IS_ARRAY, 0)
+ZEND_END_ARG_INFO()
+
 ZEND_FUNCTION(frankenphp_handle_request);
 ZEND_FUNCTION(headers_send);
 ZEND_FUNCTION(frankenphp_finish_request);
 ZEND_FUNCTION(frankenphp_request_headers);
 ZEND_FUNCTION(frankenphp_response_headers);
+ZEND_FUNCTION(frankenphp_request_deadline);
+ZEND_FUNCTION(frankenphp_queue_push);
+ZEND_FUNCTION(frankenphp_cache_get);
+ZEND_FUNCTION(frankenphp_cache_set);
+ZEND_FUNCTION(frankenphp_cache_delete);
+ZEND_FUNCTION(frankenphp_cache_incr);
+ZEND_FUNCTION(frankenphp_lock);
+ZEND_FUNCTION(frankenphp_unlock);
+ZEND_FUNCTION(frankenphp_semaphore_acquire);
+ZEND_FUNCTION(frankenphp_semaphore_release);
+ZEND_FUNCTION(frankenphp_counter_add);
+ZEND_FUNCTION(frankenphp_rate_limit);
+ZEND_FUNCTION(frankenphp_publish);
+ZEND_FUNCTION(frankenphp_subscribe);
+ZEND_FUNCTION(frankenphp_unsubscribe);
+ZEND_FUNCTION(frankenphp_receive);
+ZEND_FUNCTION(mercure_publish);
+ZEND_FUNCTION(frankenphp_ws_send);
+ZEND_FUNCTION(frankenphp_ws_broadcast);
+ZEND_FUNCTION(frankenphp_ws_close);
+ZEND_FUNCTION(frankenphp_stream_detach);
+ZEND_FUNCTION(frankenphp_set_trailer);
+ZEND_FUNCTION(frankenphp_send_file);
+ZEND_FUNCTION(frankenphp_response_cache_tag);
+ZEND_FUNCTION(frankenphp_response_cache_purge);
+ZEND_FUNCTION(frankenphp_subrequest);
 
 // clang-format off
 static const zend_function_entry ext_functions[] = {
@@ -47,6 +223,33 @@ static const zend_function_entry ext_functions[] = {
   ZEND_FALIAS(getallheaders, frankenphp_request_headers, arginfo_getallheaders)
   ZEND_FE(frankenphp_response_headers, arginfo_frankenphp_response_headers)
   ZEND_FALIAS(apache_response_headers, frankenphp_response_headers, arginfo_apache_response_headers)
+  ZEND_FE(frankenphp_request_deadline, arginfo_frankenphp_request_deadline)
+  ZEND_FE(frankenphp_queue_push, arginfo_frankenphp_queue_push)
+  ZEND_FE(frankenphp_cache_get, arginfo_frankenphp_cache_get)
+  ZEND_FE(frankenphp_cache_set, arginfo_frankenphp_cache_set)
+  ZEND_FE(frankenphp_cache_delete, arginfo_frankenphp_cache_delete)
+  ZEND_FE(frankenphp_cache_incr, arginfo_frankenphp_cache_incr)
+  ZEND_FE(frankenphp_lock, arginfo_frankenphp_lock)
+  ZEND_FE(frankenphp_unlock, arginfo_frankenphp_unlock)
+  ZEND_FE(frankenphp_semaphore_acquire, arginfo_frankenphp_semaphore_acquire)
+  ZEND_FE(frankenphp_semaphore_release, arginfo_frankenphp_semaphore_release)
+  ZEND_FE(frankenphp_counter_add, arginfo_frankenphp_counter_add)
+  ZEND_FE(frankenphp_rate_limit, arginfo_frankenphp_rate_limit)
+  ZEND_FE(frankenphp_publish, arginfo_frankenphp_publish)
+  ZEND_FE(frankenphp_subscribe, arginfo_frankenphp_subscribe)
+  ZEND_FE(frankenphp_unsubscribe, arginfo_frankenphp_unsubscribe)
+  ZEND_FE(frankenphp_receive, arginfo_frankenphp_receive)
+  ZEND_FE(mercure_publish, arginfo_mercure_publish)
+  ZEND_FE(frankenphp_ws_send, arginfo_frankenphp_ws_send)
+  ZEND_FE(frankenphp_ws_broadcast, arginfo_frankenphp_ws_broadcast)
+  ZEND_FE(frankenphp_ws_close, arginfo_frankenphp_ws_close)
+  ZEND_FE(frankenphp_stream_detach, arginfo_frankenphp_stream_detach)
+  ZEND_FE(frankenphp_set_trailer, arginfo_frankenphp_set_trailer)
+  ZEND_FE(frankenphp_send_file, arginfo_frankenphp_send_file)
+  ZEND_FE(frankenphp_response_cache_tag, arginfo_frankenphp_response_cache_tag)
+  ZEND_FE(frankenphp_response_cache_purge,
+          arginfo_frankenphp_response_cache_purge)
+  ZEND_FE(frankenphp_subrequest, arginfo_frankenphp_subrequest)
   ZEND_FE_END
 };
 // clang-format on
@@ -7,12 +7,14 @@ package frankenphp_test
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
@@ -22,10 +24,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/dunglas/frankenphp"
 	"github.com/dunglas/frankenphp/internal/fastabs"
@@ -46,6 +50,7 @@ type testOptions struct {
 	realServer         bool
 	logger             *slog.Logger
 	initOpts           []frankenphp.Option
+	requestOpts        []frankenphp.RequestOption
 	phpIni             map[string]string
 }
 
@@ -82,7 +87,7 @@ func runTest(t *testing.T, test func(func(http.ResponseWriter, *http.Request), *
 	defer frankenphp.Shutdown()
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
-		req, err := frankenphp.NewRequestWithContext(r, frankenphp.WithRequestDocumentRoot(testDataDir, false))
+		req, err := frankenphp.NewRequestWithContext(r, append([]frankenphp.RequestOption{frankenphp.WithRequestDocumentRoot(testDataDir, false)}, opts.requestOpts...)...)
 		assert.NoError(t, err)
 
 		err = frankenphp.ServeHTTP(w, req)
@@ -600,6 +605,132 @@ func testRequestHeaders(t *testing.T, opts *testOptions) {
 	}, opts)
 }
 
+func TestRequestDeadline_module(t *testing.T) { testRequestDeadline(t, &testOptions{}) }
+func TestRequestDeadline_worker(t *testing.T) {
+	testRequestDeadline(t, &testOptions{workerScript: "request-deadline.php"})
+}
+func testRequestDeadline(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		body, _ := testGet(fmt.Sprintf("http://example.com/request-deadline.php?i=%d", i), handler, t)
+		assert.Equal(t, "none\nnone", body)
+
+		deadline := time.Now().Add(time.Hour)
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		defer cancel()
+
+		req := httptest.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://example.com/request-deadline.php?i=%d", i), nil)
+		body, _ = testRequest(req, handler, t)
+
+		lines := strings.Split(body, "\n")
+		require.Len(t, lines, 2)
+		for _, line := range lines {
+			d, err := strconv.ParseFloat(line, 64)
+			require.NoError(t, err)
+			assert.InDelta(t, float64(deadline.UnixMicro())/1e6, d, 0.001)
+		}
+	}, opts)
+}
+
+func TestCache_module(t *testing.T) { testCache(t, &testOptions{}) }
+func TestCache_worker(t *testing.T) {
+	testCache(t, &testOptions{workerScript: "cache.php"})
+}
+func testCache(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		body, _ := testGet(fmt.Sprintf("http://example.com/cache.php?i=%d", i), handler, t)
+
+		// the name of the given type depends on the PHP version
+		assert.True(t, strings.HasPrefix(body, `string(7) "default"
+bool(true)
+array(2) {
+  ["foo"]=>
+  string(3) "bar"
+  ["list"]=>
+  array(3) {
+    [0]=>
+    int(1)
+    [1]=>
+    float(2.5)
+    [2]=>
+    NULL
+  }
+}
+int(2)
+int(3)
+bool(true)
+bool(false)
+frankenphp_cache_set(): Argument #2 ($value) must be a scalar, an array or null, `), body)
+	}, opts)
+}
+
+func TestLock_module(t *testing.T) { testLock(t, &testOptions{}) }
+func TestLock_worker(t *testing.T) {
+	testLock(t, &testOptions{workerScript: "lock.php"})
+}
+func testLock(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		// the second request can only acquire the lock if it has been released at the end of the first one
+		for range 2 {
+			body, _ := testGet(fmt.Sprintf("http://example.com/lock.php?i=%d", i), handler, t)
+
+			assert.Equal(t, `bool(true)
+the lock is already held by this thread
+bool(true)
+bool(true)
+bool(false)
+bool(true)
+bool(true)
+bool(false)
+`, body)
+		}
+	}, opts)
+}
+
+func TestPubSub_module(t *testing.T) { testPubSub(t, &testOptions{}) }
+func TestPubSub_worker(t *testing.T) {
+	testPubSub(t, &testOptions{workerScript: "pubsub.php"})
+}
+func testPubSub(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		sub := frankenphp.Subscribe(fmt.Sprintf("pubsub-go-%d", i))
+		defer sub.Unsubscribe()
+
+		body, _ := testGet(fmt.Sprintf("http://example.com/pubsub.php?i=%d", i), handler, t)
+
+		assert.Equal(t, fmt.Sprintf(`int(1)
+array(2) {
+  ["topic"]=>
+  string(%d) "pubsub-test-%d"
+  ["value"]=>
+  array(1) {
+    ["foo"]=>
+    string(3) "bar"
+  }
+}
+NULL
+int(0)
+int(1)
+`, len(fmt.Sprintf("pubsub-test-%d", i)), i), body)
+
+		m := <-sub.C
+		assert.Equal(t, fmt.Sprintf("pubsub-go-%d", i), m.Topic)
+		assert.Equal(t, "hello Go", m.Value)
+	}, opts)
+}
+
+func TestRequestContextIsCancelledWhenPHPFinishes(t *testing.T) {
+	require.NoError(t, frankenphp.Init(frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	defer frankenphp.Shutdown()
+
+	cwd, _ := os.Getwd()
+	req, err := frankenphp.NewRequestWithContext(httptest.NewRequest("GET", "http://example.com/index.php", nil), frankenphp.WithRequestDocumentRoot(cwd+"/testdata/", false))
+	require.NoError(t, err)
+	require.NoError(t, req.Context().Err())
+
+	require.NoError(t, frankenphp.ServeHTTP(httptest.NewRecorder(), req))
+	assert.ErrorIs(t, req.Context().Err(), context.Canceled)
+}
+
 func TestFailingWorker(t *testing.T) {
 	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
 		body, _ := testGet("http://example.com/failing-worker.php", handler, t)
@@ -1003,3 +1134,373 @@ func FuzzRequest(f *testing.F) {
 		}, &testOptions{workerScript: "request-headers.php"})
 	})
 }
+
+func TestStreamDetach_module(t *testing.T) { testStreamDetach(t, &testOptions{}) }
+func TestStreamDetach_worker(t *testing.T) {
+	testStreamDetach(t, &testOptions{workerScript: "stream-detach.php"})
+}
+func testStreamDetach(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		go func() {
+			for range 500 {
+				if n, _ := frankenphp.Publish(fmt.Sprintf("stream-%d", i), "data: from Go\n\n"); n > 0 {
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+
+		body, resp := testGet(fmt.Sprintf("http://example.com/stream-detach.php?i=%d", i), handler, t)
+
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(body, "data: start\n\n"))
+		assert.Contains(t, body, "data: from PHP\n\n")
+		assert.Contains(t, body, "data: from Go\n\n")
+		assert.NotContains(t, body, "not sent")
+	}, opts)
+}
+
+func TestTrailers_module(t *testing.T) { testTrailers(t, &testOptions{}) }
+func TestTrailers_worker(t *testing.T) {
+	testTrailers(t, &testOptions{workerScript: "trailers.php"})
+}
+func testTrailers(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		body, resp := testGet(fmt.Sprintf("http://example.com/trailers.php?i=%d", i), handler, t)
+
+		assert.Equal(t, "bool(true)\nbool(true)\nthe trailer name is invalid\n", body)
+		assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+		assert.Equal(t, fmt.Sprintf("OK %d", i), resp.Trailer.Get("Grpc-Message"))
+		assert.Empty(t, resp.Trailer.Get("Too-Late"))
+	}, opts)
+}
+
+func TestSendFile_module(t *testing.T) { testSendFile(t, &testOptions{}) }
+func TestSendFile_worker(t *testing.T) {
+	testSendFile(t, &testOptions{workerScript: "send-file.php"})
+}
+func testSendFile(t *testing.T, opts *testOptions) {
+	cwd, _ := os.Getwd()
+	opts.requestOpts = []frankenphp.RequestOption{frankenphp.WithRequestSendFileDirs([]string{cwd + "/testdata/files"})}
+
+	sub := frankenphp.Subscribe("send-file")
+	defer sub.Unsubscribe()
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		body, resp := testGet("http://example.com/send-file.php?file=files/static.txt", handler, t)
+		assert.Equal(t, "Hello from file\n", body)
+		assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "private", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+		assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+		etag := resp.Header.Get("ETag")
+		assert.True(t, strings.HasPrefix(etag, `W/"`))
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/send-file.php?file=files/static.txt", nil)
+		req.Header.Set("If-None-Match", etag)
+		_, resp = testRequest(req, handler, t)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/send-file.php?file=files/static.txt&name=hello.txt", nil)
+		req.Header.Set("Range", "bytes=0-4")
+		body, resp = testRequest(req, handler, t)
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "Hello", body)
+		assert.Equal(t, `attachment; filename=hello.txt`, resp.Header.Get("Content-Disposition"))
+
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/send-file.php?file=files/static.txt", nil)
+		req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		_, resp = testRequest(req, handler, t)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+		body, _ = testGet("http://example.com/send-file.php?file=hello.txt", handler, t)
+		assert.Equal(t, "the file is not in a directory allowed to be sent", body)
+
+		body, _ = testGet("http://example.com/send-file.php?file=files/../hello.txt", handler, t)
+		assert.Equal(t, "the file is not in a directory allowed to be sent", body)
+	}, opts)
+
+	// the code following frankenphp_send_file() never runs, runTest waits for all the scripts to end
+	assert.Empty(t, sub.C)
+}
+
+func TestResponseBuffering_module(t *testing.T) {
+	testResponseBuffering(t, &testOptions{requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestResponseBuffering(1024)}})
+}
+func TestResponseBuffering_worker(t *testing.T) {
+	testResponseBuffering(t, &testOptions{workerScript: "large-response.php", requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestResponseBuffering(1024)}})
+}
+func testResponseBuffering(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		body, resp := testGet("http://example.com/large-response.php", handler, t)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, strings.Repeat("Hey\n", 1024), body)
+	}, opts)
+}
+
+func TestResponseCache_module(t *testing.T) {
+	testResponseCache(t, &testOptions{requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestResponseCache([]string{"Accept-Language"})}})
+}
+func TestResponseCache_worker(t *testing.T) {
+	testResponseCache(t, &testOptions{workerScript: "response-cache.php", requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestResponseCache([]string{"Accept-Language"})}})
+}
+func testResponseCache(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		url := fmt.Sprintf("http://example.com/response-cache.php?i=%d&run=%s", i, t.Name())
+
+		body, resp := testGet(url, handler, t)
+		assert.Equal(t, "generation 1", body)
+		assert.Equal(t, "FrankenPHP; fwd=uri-miss", resp.Header.Get("Cache-Status"))
+		assert.Empty(t, resp.Header.Get("Cache-Tag"))
+
+		body, resp = testGet(url, handler, t)
+		assert.Equal(t, "generation 1", body)
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Cache-Status"), "FrankenPHP; hit; ttl="))
+		assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+		assert.NotEmpty(t, resp.Header.Get("Age"))
+
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Accept-Language", "fr")
+		body, _ = testRequest(req, handler, t)
+		assert.Equal(t, "generation 2", body)
+
+		req = httptest.NewRequest(http.MethodPost, url, nil)
+		body, _ = testRequest(req, handler, t)
+		assert.Equal(t, "generation 3", body)
+
+		assert.Equal(t, 2, frankenphp.PurgeResponseCache(fmt.Sprintf("page-%d", i)))
+
+		body, _ = testGet(url, handler, t)
+		assert.Equal(t, "generation 4", body)
+
+		body, _ = testGet(url+"&cache_control=private", handler, t)
+		assert.Equal(t, "generation 5", body)
+		body, _ = testGet(url+"&cache_control=private", handler, t)
+		assert.Equal(t, "generation 6", body)
+
+		// responses varying on headers that aren't part of the key are not stored
+		body, _ = testGet(url+"&vary=Accept-Encoding", handler, t)
+		assert.Equal(t, "generation 7", body)
+		body, _ = testGet(url+"&vary=Accept-Encoding", handler, t)
+		assert.Equal(t, "generation 8", body)
+
+		body, _ = testGet(url+"&vary=accept-language", handler, t)
+		assert.Equal(t, "generation 9", body)
+		body, _ = testGet(url+"&vary=accept-language", handler, t)
+		assert.Equal(t, "generation 9", body)
+	}, opts)
+}
+
+func TestRequestCoalescing_module(t *testing.T) {
+	testRequestCoalescing(t, &testOptions{nbParallelRequests: 1, requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestCoalescing(nil)}})
+}
+func TestRequestCoalescing_worker(t *testing.T) {
+	testRequestCoalescing(t, &testOptions{workerScript: "request-coalescing.php", nbParallelRequests: 1, requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestCoalescing(nil)}})
+}
+func testRequestCoalescing(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		concurrentGet := func(url string) []string {
+			bodies := make([]string, 5)
+
+			var wg sync.WaitGroup
+			wg.Add(len(bodies))
+			for j := range bodies {
+				go func() {
+					bodies[j], _ = testGet(url, handler, t)
+					wg.Done()
+				}()
+			}
+			wg.Wait()
+
+			slices.Sort(bodies)
+
+			return bodies
+		}
+
+		url := fmt.Sprintf("http://example.com/request-coalescing.php?i=%d&run=%s", i, t.Name())
+		assert.Equal(t, slices.Repeat([]string{"generation 1"}, 5), concurrentGet(url))
+
+		// private responses are not shared, the waiting requests are handled by PHP
+		url = fmt.Sprintf("http://example.com/request-coalescing.php?i=%d&run=%s-private&cache_control=private", i, t.Name())
+		assert.Equal(t, []string{"generation 1", "generation 2", "generation 3", "generation 4", "generation 5"}, concurrentGet(url))
+	}, opts)
+}
+
+func TestESI_module(t *testing.T) {
+	testESI(t, &testOptions{requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestESI(true)}})
+}
+func TestESI_worker(t *testing.T) {
+	testESI(t, &testOptions{workerScript: "esi.php", requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestESI(true)}})
+}
+func testESI(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		body, resp := testGet(fmt.Sprintf("http://example.com/esi.php?i=%d", i), handler, t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `frankenphp="ESI/1.0":<p>fragment a</p><p>fragment b</p>fragment altfragment c`, body)
+		assert.Empty(t, resp.Header.Get("Surrogate-Control"))
+
+		// responses without a Surrogate-Control header aren't processed
+		body, _ = testGet(fmt.Sprintf("http://example.com/esi.php?i=%d&unmarked=1", i), handler, t)
+		assert.Equal(t, `<esi:include src="/esi.php?fragment=a" />`, body)
+	}, opts)
+}
+
+func TestSubRequest_module(t *testing.T) {
+	testSubRequest(t, &testOptions{nbParallelRequests: 1})
+}
+func TestSubRequest_worker(t *testing.T) {
+	testSubRequest(t, &testOptions{workerScript: "subrequest.php", nbParallelRequests: 1})
+}
+func testSubRequest(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		body, _ := testGet(fmt.Sprintf("http://example.com/subrequest.php?i=%d", i), handler, t)
+
+		assert.Equal(t, "a:200:a:GET a  \nb:200:b:POST b hello bar\n"+`invalid request "0": sub-requests must target the same host: "https://example.org/"`, body)
+	}, opts)
+}
+
+func TestSubRequestWithASingleThread_module(t *testing.T) {
+	testSubRequestWithASingleThread(t, &testOptions{initOpts: []frankenphp.Option{frankenphp.WithNumThreads(1)}})
+}
+func TestSubRequestWithASingleThread_worker(t *testing.T) {
+	testSubRequestWithASingleThread(t, &testOptions{workerScript: "subrequest.php", nbWorkers: 1, initOpts: []frankenphp.Option{frankenphp.WithNumThreads(2)}})
+}
+func testSubRequestWithASingleThread(t *testing.T, opts *testOptions) {
+	opts.nbParallelRequests = 1
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		// the only thread able to handle the sub-request is the one sending it
+		body, _ := testGet("http://example.com/subrequest.php?self=1", handler, t)
+
+		assert.Equal(t, "no PHP thread is available to handle the sub-request", body)
+	}, opts)
+}
+
+func TestFastCGI_module(t *testing.T) { testFastCGI(t, &testOptions{}) }
+func TestFastCGI_worker(t *testing.T) {
+	testFastCGI(t, &testOptions{workerScript: "fastcgi.php"})
+}
+func testFastCGI(t *testing.T, opts *testOptions) {
+	cwd, _ := os.Getwd()
+	testDataDir := cwd + "/testdata/"
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		assert.NoError(t, frankenphp.ServeFastCGI(ln, frankenphp.WithRequestDocumentRoot(testDataDir, false), frankenphp.WithRequestEnv(map[string]string{"APP_ENV": "dev"})))
+	}()
+
+	runTest(t, func(_ func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		// the connection is kept open between the requests
+		for j := range 2 {
+			query := fmt.Sprintf("i=%d", i)
+			body := fmt.Sprintf("request %d", j)
+			response := fastCGIDo(t, conn, uint16(j+1), map[string]string{
+				"REQUEST_METHOD":  "POST",
+				"REQUEST_URI":     "/fastcgi.php/foo?" + query,
+				"QUERY_STRING":    query,
+				"SERVER_PROTOCOL": "HTTP/1.1",
+				"SCRIPT_FILENAME": testDataDir + "fastcgi.php",
+				"SCRIPT_NAME":     "/fastcgi.php",
+				"PATH_INFO":       "/foo",
+				"DOCUMENT_ROOT":   testDataDir,
+				"CONTENT_TYPE":    "text/plain",
+				"CONTENT_LENGTH":  strconv.Itoa(len(body)),
+				"HTTP_HOST":       "example.com",
+				"HTTP_X_FOO":      "bar",
+				"APP_ENV":         "prod",
+				"REMOTE_ADDR":     "127.0.0.1",
+				"REMOTE_PORT":     "12345",
+			}, body)
+
+			assert.Contains(t, response, "Status: 200 OK\r\n")
+			assert.Contains(t, response, "X-Script-Name: /fastcgi.php\r\n")
+			assert.True(t, strings.HasSuffix(response, "\r\n\r\n"+strings.Join([]string{
+				"POST /fastcgi.php/foo?" + query,
+				"SCRIPT_NAME: /fastcgi.php",
+				"PATH_INFO: /foo",
+				"APP_ENV: prod",
+				"X-Foo: bar",
+				fmt.Sprintf("i: %d", i),
+				"body: " + body,
+			}, "\n")), response)
+		}
+
+		// the client can't execute files outside of the document root or not ending with the split path extensions
+		for j, params := range []map[string]string{
+			{"SCRIPT_FILENAME": cwd + "/frankenphp.stub.php"},
+			{"SCRIPT_FILENAME": testDataDir + "../frankenphp.stub.php"},
+			{"SCRIPT_FILENAME": testDataDir + "files/static.txt"},
+			{"DOCUMENT_ROOT": cwd},
+		} {
+			params["REQUEST_METHOD"] = "GET"
+			params["REQUEST_URI"] = "/fastcgi.php"
+			params["SERVER_PROTOCOL"] = "HTTP/1.1"
+
+			response := fastCGIDo(t, conn, uint16(j+3), params, "")
+			assert.True(t, strings.HasPrefix(response, "Status: 403 Forbidden\r\n"), response)
+		}
+	}, opts)
+}
+
+// fastCGIDo sends a request on a FastCGI connection kept open and returns the content of the stdout stream
+func fastCGIDo(t *testing.T, conn net.Conn, id uint16, params map[string]string, body string) string {
+	writeRecord := func(typ uint8, content []byte) {
+		h := []byte{1, typ, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint16(h[2:], id)
+		binary.BigEndian.PutUint16(h[4:], uint16(len(content)))
+		_, err := conn.Write(append(h, content...))
+		assert.NoError(t, err)
+	}
+
+	// responder role, with the keep connection flag
+	writeRecord(1, []byte{0, 1, 1, 0, 0, 0, 0, 0})
+
+	var p bytes.Buffer
+	for k, v := range params {
+		for _, n := range []int{len(k), len(v)} {
+			if n < 128 {
+				p.WriteByte(byte(n))
+			} else {
+				_ = binary.Write(&p, binary.BigEndian, uint32(n)|1<<31)
+			}
+		}
+		p.WriteString(k)
+		p.WriteString(v)
+	}
+	writeRecord(4, p.Bytes())
+	writeRecord(4, nil)
+	writeRecord(5, []byte(body))
+	writeRecord(5, nil)
+
+	var stdout bytes.Buffer
+	for {
+		h := make([]byte, 8)
+		if _, err := io.ReadFull(conn, h); !assert.NoError(t, err) {
+			return stdout.String()
+		}
+
+		contentLength := int(binary.BigEndian.Uint16(h[4:]))
+		content := make([]byte, contentLength+int(h[6]))
+		if _, err := io.ReadFull(conn, content); !assert.NoError(t, err) {
+			return stdout.String()
+		}
+
+		assert.Equal(t, id, binary.BigEndian.Uint16(h[2:]))
+		switch h[1] {
+		case 3: // end request
+			return stdout.String()
+		case 6: // stdout
+			stdout.Write(content[:contentLength])
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package grpc handles gRPC calls with FrankenPHP workers.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/dunglas/frankenphp"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// EXPERIMENTAL: NewServer creates a gRPC server handling all unary calls with the worker named workerName.
+//
+// The callback passed to frankenphp_handle_request() receives an array having the "method" (e.g. "/helloworld.Greeter/SayHello"),
+// "message" (the serialized request message) and "metadata" keys, and must return the serialized response message as a string.
+// To fail the call, the callback returns an array having the "code" (a gRPC status code other than OK) and "message" keys.
+// Exceptions are converted to gRPC errors too: the code of the exception is used as status code if it is a valid one,
+// otherwise the status code is Unknown. As with frankenphp.Call, the worker script is restarted when the callback throws,
+// so throwing should be kept for unexpected errors.
+//
+// Streaming calls are rejected with the Unimplemented status code.
+// Unexpected errors are logged with logger, slog.Default() is used if it is nil.
+func NewServer(workerName string, logger *slog.Logger, opts ...gogrpc.ServerOption) *gogrpc.Server {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	opts = append(slices.Clone(opts), gogrpc.ForceServerCodec(rawCodec{}), gogrpc.UnknownServiceHandler(handler(workerName, logger)))
+
+	return gogrpc.NewServer(opts...)
+}
+
+// handler passes the raw messages of unary calls to the worker
+func handler(workerName string, logger *slog.Logger) gogrpc.StreamHandler {
+	return func(_ any, stream gogrpc.ServerStream) error {
+		method, _ := gogrpc.MethodFromServerStream(stream)
+
+		var message []byte
+		if err := stream.RecvMsg(&message); err != nil {
+			return err
+		}
+
+		// the client closes its side of the stream after the message of unary calls
+		var next []byte
+		if err := stream.RecvMsg(&next); err == nil {
+			return status.Error(codes.Unimplemented, "streaming calls are not supported")
+		} else if !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		ctx := stream.Context()
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		result, err := frankenphp.Call(ctx, workerName, callParameters(method, message, md))
+		if err != nil {
+			return callError(ctx, logger, method, err)
+		}
+
+		switch result := result.(type) {
+		case string:
+			return stream.SendMsg([]byte(result))
+		case frankenphp.AssociativeArray:
+			st, err := returnedStatus(result)
+			if err != nil {
+				return callError(ctx, logger, method, err)
+			}
+
+			return st.Err()
+		}
+
+		return callError(ctx, logger, method, fmt.Errorf("the callback must return the serialized response message as a string or a status as an array, got %T", result))
+	}
+}
+
+// returnedStatus converts the status returned by the callback, an array having the "code" and "message" keys
+func returnedStatus(s frankenphp.AssociativeArray) (*status.Status, error) {
+	code, ok := s.Map["code"].(int64)
+	if !ok || code <= int64(codes.OK) || code > int64(codes.Unauthenticated) {
+		return nil, fmt.Errorf(`the "code" of the returned status must be a gRPC status code other than OK, got %v`, s.Map["code"])
+	}
+
+	var message string
+	if m, ok := s.Map["message"]; ok {
+		if message, ok = m.(string); !ok {
+			return nil, fmt.Errorf(`the "message" of the returned status must be a string, got %T`, m)
+		}
+	}
+
+	return status.New(codes.Code(code), message), nil
+}
+
+// callParameters is what the callback passed to frankenphp_handle_request() receives
+func callParameters(method string, message []byte, md metadata.MD) frankenphp.AssociativeArray {
+	names := make([]string, 0, len(md))
+	for name := range md {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	m := frankenphp.AssociativeArray{Map: make(map[string]any, len(names)), Order: names}
+	for _, name := range names {
+		values := make([]any, 0, len(md[name]))
+		for _, v := range md[name] {
+			values = append(values, v)
+		}
+		m.Map[name] = values
+	}
+
+	return frankenphp.AssociativeArray{
+		Map:   map[string]any{"method": method, "message": string(message), "metadata": m},
+		Order: []string{"method", "message", "metadata"},
+	}
+}
+
+// callError converts the error returned by frankenphp.Call to a gRPC status
+func callError(ctx context.Context, logger *slog.Logger, method string, err error) error {
+	var phpException *frankenphp.PHPException
+	switch {
+	case errors.As(err, &phpException):
+		code := codes.Unknown
+		if phpException.Code > int64(codes.OK) && phpException.Code <= int64(codes.Unauthenticated) {
+			code = codes.Code(phpException.Code)
+		}
+
+		return status.Error(code, phpException.Message)
+
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+
+	case errors.Is(err, frankenphp.ErrMaxWaitTimeExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	logger.LogAttrs(ctx, slog.LevelError, "unable to handle the gRPC call", slog.String("method", method), slog.Any("error", err))
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// rawCodec passes the messages as is, they are (de)serialized by the PHP script
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+
+	*b = slices.Clone(data)
+
+	return nil
+}
+
+// Name is used in the content-type of the responses, messages are usually serialized with Protocol Buffers
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,108 @@
+package grpc_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/dunglas/frankenphp"
+	frankenphpgrpc "github.com/dunglas/frankenphp/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// rawCodec sends the messages as is, as the test script doesn't use Protocol Buffers
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	return []byte(*v.(*string)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	*v.(*string) = string(data)
+
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+func TestGRPC(t *testing.T) {
+	cwd, _ := os.Getwd()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(logger),
+		frankenphp.WithWorkers("grpc", cwd+"/../testdata/worker-grpc.php", 1),
+	))
+	defer frankenphp.Shutdown()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := frankenphpgrpc.NewServer("grpc", logger)
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{})))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	invoke := func(message string) (string, error) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-foo", "bar")
+
+		var response string
+		err := conn.Invoke(ctx, "/test.Greeter/SayHello", &message, &response)
+
+		return response, err
+	}
+
+	for i := range 3 {
+		response, err := invoke(fmt.Sprintf("world %d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("/test.Greeter/SayHello: Hello world %d bar", i), response)
+	}
+
+	_, err = invoke("not-found")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "no greeting for you", status.Convert(err).Message())
+
+	// the code of the exception isn't a gRPC status code
+	_, err = invoke("fail")
+	assert.Equal(t, codes.Unknown, status.Code(err))
+
+	// the worker script must have been restarted
+	response, err := invoke("again")
+	require.NoError(t, err)
+	assert.Equal(t, "/test.Greeter/SayHello: Hello again bar", response)
+
+	// returning a status doesn't restart the worker script
+	_, err = invoke("denied")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "permission denied", status.Convert(err).Message())
+
+	response, err = invoke("handled")
+	require.NoError(t, err)
+	assert.Equal(t, "3", response)
+
+	stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ClientStreams: true}, "/test.Greeter/SayHelloStream")
+	require.NoError(t, err)
+	for _, message := range []string{"a", "b"} {
+		require.NoError(t, stream.SendMsg(&message))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var streamResponse string
+	assert.Equal(t, codes.Unimplemented, status.Code(stream.RecvMsg(&streamResponse)))
+}
@@ -0,0 +1,342 @@
+package frankenphp
+
+import (
+	"cmp"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/dunglas/frankenphp/internal/fastabs"
+)
+
+// EXPERIMENTAL: Handler serves a PHP app with net/http, as the php_server directive of the Caddy module does:
+//
+//   - requests for directories having an index file are redirected to the path with a trailing slash
+//   - requests are rewritten to the first existing of the requested file, the index file of the requested directory
+//     and the index file of the document root (the front controller)
+//   - PHP files, according to the split path, are executed, the other files are served as static files
+//   - requests matching the paths of a worker are handled by this worker
+//
+// FrankenPHP must be started with Init or Server.Start before serving requests.
+type Handler struct {
+	root           string
+	indexFiles     []string
+	splitPath      []string
+	env            PreparedEnv
+	workers        []handlerWorker
+	strict         bool
+	staticFiles    bool
+	requestOptions []RequestOption
+}
+
+// handlerWorker is a worker handling the requests matching paths
+type handlerWorker struct {
+	name  string
+	match []string
+}
+
+// HandlerOption instances allow to configure a Handler.
+type HandlerOption func(h *Handler) error
+
+// NewHandler creates a handler serving the PHP app stored in the root directory.
+// If root is empty, the embedded app or the current directory is used.
+func NewHandler(root string, options ...HandlerOption) (*Handler, error) {
+	h := &Handler{indexFiles: []string{"index.php"}, splitPath: []string{".php"}, staticFiles: true}
+	for _, o := range options {
+		if err := o(h); err != nil {
+			return nil, err
+		}
+	}
+
+	if root == "" {
+		root = cmp.Or(EmbeddedAppPath, ".")
+	}
+
+	var err error
+	if h.root, err = fastabs.FastAbs(root); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// WithHandlerIndexFiles sets the index files of the directories, the first existing one is used.
+// The last one is also the front controller handling the requests that don't match a file.
+// Without files, requests are never rewritten. Default: index.php.
+func WithHandlerIndexFiles(files ...string) HandlerOption {
+	return func(h *Handler) error {
+		h.indexFiles = files
+
+		return nil
+	}
+}
+
+// WithHandlerSplitPath sets the extensions of the PHP files, the path is split after them to compute PATH_INFO. Default: .php.
+func WithHandlerSplitPath(splitPath []string) HandlerOption {
+	return func(h *Handler) error {
+		h.splitPath = splitPath
+
+		return nil
+	}
+}
+
+// WithHandlerEnv sets extra environment variables passed to the scripts.
+func WithHandlerEnv(env map[string]string) HandlerOption {
+	return func(h *Handler) error {
+		h.env = PrepareEnv(env)
+
+		return nil
+	}
+}
+
+// WithHandlerWorker makes the worker named name handle the requests matching one of the path patterns,
+// as the match option of the workers of the Caddy module.
+// Patterns can start or end with a * wildcard, or use the syntax of path.Match.
+// The worker is resolved when serving the requests: FrankenPHP must then be running with a worker with this name,
+// configured with WithWorkers, or the matching requests get a 500 response.
+func WithHandlerWorker(name string, match ...string) HandlerOption {
+	return func(h *Handler) error {
+		for _, pattern := range match {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return err
+			}
+		}
+
+		h.workers = append(h.workers, handlerWorker{name: name, match: match})
+
+		return nil
+	}
+}
+
+// WithHandlerStrict returns a 404 response instead of executing PHP when the resolved script doesn't exist.
+func WithHandlerStrict(strict bool) HandlerOption {
+	return func(h *Handler) error {
+		h.strict = strict
+
+		return nil
+	}
+}
+
+// WithHandlerStaticFiles enables or disables serving the files that aren't PHP scripts. Default: enabled.
+func WithHandlerStaticFiles(enabled bool) HandlerOption {
+	return func(h *Handler) error {
+		h.staticFiles = enabled
+
+		return nil
+	}
+}
+
+// WithHandlerRequestOptions sets extra options for the requests handled by PHP, such as WithRequestResponseCache.
+func WithHandlerRequestOptions(options ...RequestOption) HandlerOption {
+	return func(h *Handler) error {
+		h.requestOptions = append(h.requestOptions, options...)
+
+		return nil
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upath := r.URL.Path
+	if !strings.HasPrefix(upath, "/") {
+		upath = "/" + upath
+	}
+
+	// worker paths aren't rewritten, but static files still take precedence
+	for _, hw := range h.workers {
+		if !hw.matches(upath) {
+			continue
+		}
+
+		if h.staticFiles && !h.isPHPPath(upath) && h.isFile(upath) {
+			h.serveFile(w, r, upath)
+
+			return
+		}
+
+		h.servePHP(w, r, upath, hw.name)
+
+		return
+	}
+
+	target := upath
+	if len(h.indexFiles) > 0 {
+		if !strings.HasSuffix(upath, "/") && h.hasIndexFile(upath) {
+			u := *r.URL
+			u.Path = upath + "/"
+			u.RawPath = ""
+			http.Redirect(w, r, u.RequestURI(), http.StatusPermanentRedirect)
+
+			return
+		}
+
+		target = h.tryFiles(upath)
+	}
+
+	if h.isPHPPath(target) {
+		h.servePHP(w, r, target, "")
+
+		return
+	}
+
+	if h.staticFiles {
+		h.serveFile(w, r, target)
+
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// tryFiles returns the first existing of the requested file, the index of the requested directory and the index of the root,
+// or the index of the root if none exists
+func (h *Handler) tryFiles(upath string) string {
+	var candidates []string
+	if h.staticFiles || h.isPHPPath(upath) {
+		candidates = append(candidates, upath)
+	}
+	for _, index := range h.indexFiles {
+		candidates = append(candidates, path.Join(upath, index))
+	}
+	for _, index := range h.indexFiles {
+		candidates = append(candidates, path.Join("/", index))
+	}
+
+	for _, candidate := range candidates {
+		if h.exists(candidate) {
+			return candidate
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+// exists checks if the file exists, the PATH_INFO part of PHP paths is ignored
+func (h *Handler) exists(upath string) bool {
+	if pos := splitPos(upath, h.splitPath); pos > 0 {
+		upath = upath[:pos]
+	}
+
+	return h.isFile(upath)
+}
+
+func (h *Handler) hasIndexFile(dir string) bool {
+	for _, index := range h.indexFiles {
+		if h.isFile(path.Join(dir, index)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Handler) isPHPPath(upath string) bool {
+	return splitPos(upath, h.splitPath) > -1
+}
+
+func (h *Handler) isFile(upath string) bool {
+	fi, err := os.Stat(sanitizedPathJoin(h.root, upath))
+
+	return err == nil && !fi.IsDir()
+}
+
+func (h *Handler) serveFile(w http.ResponseWriter, r *http.Request, upath string) {
+	f, err := os.Open(sanitizedPathJoin(h.root, upath))
+	if err != nil {
+		http.NotFound(w, r)
+
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+}
+
+// servePHP executes the script at upath, or the worker named workerName
+func (h *Handler) servePHP(w http.ResponseWriter, r *http.Request, upath string, workerName string) {
+	pr := r
+	if upath != r.URL.Path {
+		u := *r.URL
+		u.Path = upath
+		u.RawPath = ""
+
+		pr = new(http.Request)
+		*pr = *r
+		pr.URL = &u
+	}
+
+	opts := append([]RequestOption{
+		WithRequestDocumentRoot(h.root, false),
+		WithRequestSplitPath(h.splitPath),
+		WithRequestPreparedEnv(h.env),
+		WithOriginalRequest(r),
+		WithWorkerName(workerName),
+	}, h.requestOptions...)
+
+	fr, err := NewRequestWithContext(pr, opts...)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	fc, _ := fromContext(fr.Context())
+	if workerName != "" && fc.worker == nil {
+		if fc.logger != nil {
+			fc.logger.LogAttrs(r.Context(), slog.LevelError, "the handler worker is not running", slog.String("worker", workerName))
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	if h.strict && fc.worker == nil {
+		if fi, err := os.Stat(fc.scriptFilename); err != nil || fi.IsDir() {
+			http.NotFound(w, r)
+
+			return
+		}
+	}
+
+	// the response may already have been sent
+	if err := ServeHTTP(w, fr); err != nil && !fc.isDone {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// matches checks the path against the patterns, case-insensitively
+func (hw handlerWorker) matches(upath string) bool {
+	upath = strings.ToLower(upath)
+
+	for _, pattern := range hw.match {
+		pattern = strings.ToLower(pattern)
+
+		switch {
+		case pattern == "*":
+			return true
+		case strings.Count(pattern, "*") == 1 && strings.HasSuffix(pattern, "*"):
+			if strings.HasPrefix(upath, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		case strings.Count(pattern, "*") == 1 && strings.HasPrefix(pattern, "*"):
+			if strings.HasSuffix(upath, strings.TrimPrefix(pattern, "*")) {
+				return true
+			}
+		default:
+			if ok, _ := path.Match(pattern, upath); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
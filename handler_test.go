@@ -0,0 +1,108 @@
+package frankenphp_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	cwd, _ := os.Getwd()
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithWorkers("counter", cwd+"/testdata/worker-with-counter.php", 1),
+	))
+	defer frankenphp.Shutdown()
+
+	h, err := frankenphp.NewHandler("testdata", frankenphp.WithHandlerWorker("counter", "/api/*"))
+	require.NoError(t, err)
+
+	unknown, err := frankenphp.NewHandler("testdata", frankenphp.WithHandlerWorker("unknown", "/api/*"))
+	require.NoError(t, err)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		return w
+	}
+
+	w := get("/?i=0")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "I am by birth a Genevese (0)", w.Body.String())
+
+	// the front controller handles the paths that don't match a file
+	w = get("/blog/my-post?i=1")
+	assert.Equal(t, "I am by birth a Genevese (1)", w.Body.String())
+
+	w = get("/files/static.txt")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Hello from file", w.Body.String())
+
+	w = get("/dirindex?a=b")
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "/dirindex/?a=b", w.Header().Get("Location"))
+
+	w = get("/dirindex/")
+	assert.Equal(t, "Hello from directory index.php", w.Body.String())
+
+	w = get("/hello.php")
+	assert.Equal(t, "Hello from PHP", w.Body.String())
+
+	assert.Equal(t, "requests:1", get("/api/users").Body.String())
+	assert.Equal(t, "requests:2", get("/API/orders").Body.String())
+
+	w = httptest.NewRecorder()
+	unknown.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandlerStrict(t *testing.T) {
+	require.NoError(t, frankenphp.Init(frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	defer frankenphp.Shutdown()
+
+	h, err := frankenphp.NewHandler("testdata", frankenphp.WithHandlerIndexFiles(), frankenphp.WithHandlerStaticFiles(false), frankenphp.WithHandlerStrict(true))
+	require.NoError(t, err)
+
+	for target, status := range map[string]int{
+		"/hello.php":            http.StatusOK,
+		"/hello.php/path/info":  http.StatusOK,
+		"/not-found.php":        http.StatusNotFound,
+		"/blog/my-post":         http.StatusNotFound,
+		"/files/static.txt":     http.StatusNotFound,
+		"/dirindex":             http.StatusNotFound,
+		"/dirindex/index.php/x": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		assert.Equal(t, status, w.Code, target)
+	}
+}
+
+func TestHandlerWorkerIsResolvedWhenServing(t *testing.T) {
+	h, err := frankenphp.NewHandler("testdata", frankenphp.WithHandlerWorker("counter", "/api/*"))
+	require.NoError(t, err)
+
+	cwd, _ := os.Getwd()
+	for range 2 {
+		require.NoError(t, frankenphp.Init(
+			frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			frankenphp.WithWorkers("counter", cwd+"/testdata/worker-with-counter.php", 1),
+		))
+
+		// the worker of the current run handles the request after a restart
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+		assert.Equal(t, "requests:1", w.Body.String())
+
+		frankenphp.Shutdown()
+	}
+}
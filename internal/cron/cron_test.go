@@ -0,0 +1,56 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2025, time.March, 14, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.March, 14, 10, 8, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2025, time.March, 14, 10, 10, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2025, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2025, time.March, 15, 2, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", time.Date(2025, time.March, 14, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan,jul *", time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// the day of month and the day of week are OR-ed when both are restricted
+		{"0 0 20 * 1", time.Date(2025, time.March, 17, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			s, err := Parse(test.spec)
+			require.NoError(t, err)
+
+			next, err := s.Next(from)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, next)
+		})
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+
+	_, err = s.Next(time.Now())
+	assert.ErrorIs(t, err, ErrNoActivation)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "foo * * * *"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,278 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+var (
+	errLockAlreadyHeld = errors.New("the lock is already held by this thread")
+	errInvalidPermits  = errors.New("the number of permits must be greater than 0")
+	errInvalidLimit    = errors.New("the limit and the interval must be greater than 0")
+)
+
+// named primitives shared by all PHP threads, a lock is a semaphore with a single permit
+var (
+	semaphoresMu sync.Mutex
+	semaphores   = make(map[string]*semaphore)
+
+	countersMu        sync.Mutex
+	counters          = make(map[string]*counter)
+	countersLastSweep time.Time
+
+	rateLimitersMu        sync.Mutex
+	rateLimiters          = make(map[string]*rateLimiter)
+	rateLimitersLastSweep time.Time
+)
+
+const (
+	// counterIdleTTL is the duration after which a counter that has not been used is forgotten
+	counterIdleTTL = 24 * time.Hour
+	// sweepInterval is the minimum duration between two evictions of idle counters and full rate limiters
+	sweepInterval = time.Minute
+)
+
+type semaphore struct {
+	permits int
+	slots   chan struct{}
+	// number of threads holding or waiting for the semaphore, it is deleted when it drops to 0
+	refs int
+}
+
+// acquireSemaphore blocks until a permit is available, the timeout expires or the thread is drained.
+// A negative timeout waits forever.
+func (thread *phpThread) acquireSemaphore(name string, permits int, timeout time.Duration) (bool, error) {
+	if permits < 1 {
+		return false, errInvalidPermits
+	}
+
+	semaphoresMu.Lock()
+	s, ok := semaphores[name]
+	if !ok {
+		s = &semaphore{permits: permits, slots: make(chan struct{}, permits)}
+		semaphores[name] = s
+	} else if s.permits != permits {
+		semaphoresMu.Unlock()
+
+		return false, fmt.Errorf("%q already exists with %d permits", name, s.permits)
+	} else if permits == 1 && thread.locks[name] > 0 {
+		// locks are not reentrant, waiting for a lock held by the same thread would never succeed
+		semaphoresMu.Unlock()
+
+		return false, errLockAlreadyHeld
+	}
+	s.refs++
+	semaphoresMu.Unlock()
+
+	acquired := false
+	if timeout == 0 {
+		select {
+		case s.slots <- struct{}{}:
+			acquired = true
+		default:
+		}
+	} else {
+		var timer <-chan time.Time
+		if timeout > 0 {
+			t := time.NewTimer(timeout)
+			defer t.Stop()
+			timer = t.C
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+			acquired = true
+		case <-timer:
+		case <-thread.drainChan:
+		}
+	}
+
+	if !acquired {
+		unrefSemaphore(name, s)
+
+		return false, nil
+	}
+
+	if thread.locks == nil {
+		thread.locks = make(map[string]int)
+	}
+	thread.locks[name]++
+
+	return true, nil
+}
+
+// releaseSemaphore gives back a permit acquired by the thread
+func (thread *phpThread) releaseSemaphore(name string) bool {
+	if thread.locks[name] == 0 {
+		return false
+	}
+
+	if thread.locks[name]--; thread.locks[name] == 0 {
+		delete(thread.locks, name)
+	}
+
+	semaphoresMu.Lock()
+	s := semaphores[name]
+	semaphoresMu.Unlock()
+
+	<-s.slots
+	unrefSemaphore(name, s)
+
+	return true
+}
+
+// releaseAllSemaphores is called when a request ends or a script stops,
+// a thread that crashed or forgot to unlock cannot block the others
+func (thread *phpThread) releaseAllSemaphores() {
+	for name, n := range thread.locks {
+		for range n {
+			thread.releaseSemaphore(name)
+		}
+	}
+}
+
+func unrefSemaphore(name string, s *semaphore) {
+	semaphoresMu.Lock()
+	if s.refs--; s.refs == 0 {
+		delete(semaphores, name)
+	}
+	semaphoresMu.Unlock()
+}
+
+type counter struct {
+	value    int64
+	lastUsed time.Time
+}
+
+func counterAdd(name string, delta int64) int64 {
+	now := time.Now()
+
+	countersMu.Lock()
+	defer countersMu.Unlock()
+
+	if now.Sub(countersLastSweep) >= sweepInterval {
+		evictIdleCounters(now)
+	}
+
+	c, ok := counters[name]
+	if !ok {
+		c = &counter{}
+		counters[name] = c
+	}
+	c.value += delta
+	c.lastUsed = now
+
+	// a missing counter is worth 0, there is no need to keep it
+	if c.value == 0 {
+		delete(counters, name)
+	}
+
+	return c.value
+}
+
+// evictIdleCounters must be called with countersMu locked
+func evictIdleCounters(now time.Time) {
+	countersLastSweep = now
+
+	for name, c := range counters {
+		if now.Sub(c.lastUsed) >= counterIdleTTL {
+			delete(counters, name)
+		}
+	}
+}
+
+// rateLimiter is a token bucket holding up to limit tokens and refilled at a rate of limit tokens per interval
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will be full again, the limiter can then be forgotten as a new one would behave the same
+	full time.Time
+}
+
+func rateLimit(name string, limit int, interval time.Duration) (bool, error) {
+	if limit < 1 || interval <= 0 {
+		return false, errInvalidLimit
+	}
+
+	now := time.Now()
+
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	if now.Sub(rateLimitersLastSweep) >= sweepInterval {
+		evictFullRateLimiters(now)
+	}
+
+	l, ok := rateLimiters[name]
+	if !ok {
+		l = &rateLimiter{tokens: float64(limit), last: now}
+		rateLimiters[name] = l
+	}
+
+	rate := float64(limit) / interval.Seconds()
+	l.tokens = math.Min(float64(limit), l.tokens+now.Sub(l.last).Seconds()*rate)
+	l.last = now
+
+	allowed := l.tokens >= 1
+	if allowed {
+		l.tokens--
+	}
+	l.full = now.Add(time.Duration((float64(limit) - l.tokens) / rate * float64(time.Second)))
+
+	return allowed, nil
+}
+
+// evictFullRateLimiters must be called with rateLimitersMu locked
+func evictFullRateLimiters(now time.Time) {
+	rateLimitersLastSweep = now
+
+	for name, l := range rateLimiters {
+		if !now.Before(l.full) {
+			delete(rateLimiters, name)
+		}
+	}
+}
+
+func secondsToDuration(seconds C.double) time.Duration {
+	if seconds < 0 {
+		return -1
+	}
+
+	return time.Duration(float64(seconds) * float64(time.Second))
+}
+
+//export go_frankenphp_semaphore_acquire
+func go_frankenphp_semaphore_acquire(threadIndex C.uintptr_t, name *C.zend_string, permits C.zend_long, timeout C.double) (C.bool, *C.zend_string) {
+	acquired, err := phpThreads[threadIndex].acquireSemaphore(GoString(unsafe.Pointer(name)), int(permits), secondsToDuration(timeout))
+	if err != nil {
+		return C.bool(false), (*C.zend_string)(PHPString(err.Error(), false))
+	}
+
+	return C.bool(acquired), nil
+}
+
+//export go_frankenphp_semaphore_release
+func go_frankenphp_semaphore_release(threadIndex C.uintptr_t, name *C.zend_string) C.bool {
+	return C.bool(phpThreads[threadIndex].releaseSemaphore(GoString(unsafe.Pointer(name))))
+}
+
+//export go_frankenphp_counter_add
+func go_frankenphp_counter_add(name *C.zend_string, delta C.zend_long) C.zend_long {
+	return C.zend_long(counterAdd(GoString(unsafe.Pointer(name)), int64(delta)))
+}
+
+//export go_frankenphp_rate_limit
+func go_frankenphp_rate_limit(name *C.zend_string, limit C.zend_long, interval C.double) (C.bool, *C.zend_string) {
+	allowed, err := rateLimit(GoString(unsafe.Pointer(name)), int(limit), secondsToDuration(interval))
+	if err != nil {
+		return C.bool(false), (*C.zend_string)(PHPString(err.Error(), false))
+	}
+
+	return C.bool(allowed), nil
+}
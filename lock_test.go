@@ -0,0 +1,143 @@
+package frankenphp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLockTestThread(threadIndex int) *phpThread {
+	thread := newPHPThread(threadIndex)
+	thread.drainChan = make(chan struct{})
+
+	return thread
+}
+
+func TestLockIsReleasedWhenTheScriptEnds(t *testing.T) {
+	thread1 := newLockTestThread(0)
+	thread2 := newLockTestThread(1)
+
+	acquired, err := thread1.acquireSemaphore("lock", 1, 0)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	acquired, err = thread2.acquireSemaphore("lock", 1, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	_, err = thread1.acquireSemaphore("lock", 1, 0)
+	assert.ErrorIs(t, err, errLockAlreadyHeld)
+
+	done := make(chan bool)
+	go func() {
+		acquired, _ := thread2.acquireSemaphore("lock", 1, -1)
+		done <- acquired
+	}()
+
+	thread1.releaseAllSemaphores()
+	assert.True(t, <-done)
+	assert.False(t, thread1.releaseSemaphore("lock"))
+	assert.True(t, thread2.releaseSemaphore("lock"))
+
+	assert.Empty(t, semaphores)
+}
+
+func TestSemaphore(t *testing.T) {
+	thread1 := newLockTestThread(0)
+	thread2 := newLockTestThread(1)
+
+	for _, thread := range []*phpThread{thread1, thread1, thread2} {
+		acquired, err := thread.acquireSemaphore("semaphore", 3, 0)
+		require.NoError(t, err)
+		require.True(t, acquired)
+	}
+
+	acquired, err := thread2.acquireSemaphore("semaphore", 3, 0)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	_, err = thread2.acquireSemaphore("semaphore", 2, 0)
+	assert.Error(t, err)
+
+	thread1.releaseAllSemaphores()
+	thread2.releaseAllSemaphores()
+
+	assert.Empty(t, semaphores)
+}
+
+func TestWaitingForALockStopsWhenTheThreadIsDrained(t *testing.T) {
+	thread1 := newLockTestThread(0)
+	thread2 := newLockTestThread(1)
+
+	acquired, _ := thread1.acquireSemaphore("drained", 1, 0)
+	require.True(t, acquired)
+
+	close(thread2.drainChan)
+	acquired, err := thread2.acquireSemaphore("drained", 1, -1)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	thread1.releaseAllSemaphores()
+}
+
+func TestCounterAdd(t *testing.T) {
+	assert.Equal(t, int64(2), counterAdd("counter", 2))
+	assert.Equal(t, int64(1), counterAdd("counter", -1))
+	assert.Equal(t, int64(1), counterAdd("counter", 0))
+}
+
+func TestIdleCountersAreEvicted(t *testing.T) {
+	counterAdd("reset", 1)
+	counterAdd("reset", -1)
+	counterAdd("idle", 1)
+	counterAdd("active", 1)
+
+	countersMu.Lock()
+	defer countersMu.Unlock()
+
+	assert.NotContains(t, counters, "reset")
+
+	counters["active"].lastUsed = time.Now().Add(counterIdleTTL)
+	evictIdleCounters(time.Now().Add(counterIdleTTL))
+
+	assert.NotContains(t, counters, "idle")
+	assert.Equal(t, int64(1), counters["active"].value)
+
+	delete(counters, "active")
+}
+
+func TestFullRateLimitersAreEvicted(t *testing.T) {
+	_, _ = rateLimit("refilled", 2, time.Second)
+	_, _ = rateLimit("refilling", 2, time.Hour)
+
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	evictFullRateLimiters(time.Now().Add(time.Second))
+
+	assert.NotContains(t, rateLimiters, "refilled")
+	assert.Contains(t, rateLimiters, "refilling")
+
+	delete(rateLimiters, "refilling")
+}
+
+func TestRateLimit(t *testing.T) {
+	for range 3 {
+		allowed, err := rateLimit("limiter", 3, 50*time.Millisecond)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, _ := rateLimit("limiter", 3, 50*time.Millisecond)
+	assert.False(t, allowed)
+
+	time.Sleep(20 * time.Millisecond)
+
+	allowed, _ = rateLimit("limiter", 3, 50*time.Millisecond)
+	assert.True(t, allowed)
+
+	_, err := rateLimit("limiter", 0, time.Second)
+	assert.ErrorIs(t, err, errInvalidLimit)
+}
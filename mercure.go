@@ -0,0 +1,102 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// EXPERIMENTAL: MercureUpdate is an update published with mercure_publish()
+type MercureUpdate struct {
+	// Topics contains the canonical IRI of the update followed by its alternate IRIs
+	Topics  []string
+	Data    string
+	Private bool
+	// ID is empty if the hub must generate it
+	ID    string
+	Type  string
+	Retry uint64
+}
+
+// EXPERIMENTAL: MercurePublisher dispatches an update to a Mercure hub and returns the ID of the update
+type MercurePublisher func(MercureUpdate) (string, error)
+
+var (
+	errMercureNotConfigured = errors.New("no Mercure hub is configured")
+	errMercureInvalidTopics = errors.New("the topics must be a non-empty string or a non-empty array of strings")
+	errMercureInvalidRetry  = errors.New("the retry must be greater than or equal to 0")
+
+	mercurePublisher MercurePublisher
+)
+
+func publishMercureUpdate(u MercureUpdate) (string, error) {
+	if mercurePublisher == nil {
+		return "", errMercureNotConfigured
+	}
+
+	return mercurePublisher(u)
+}
+
+// mercureTopics converts the topics passed to mercure_publish(), a string or an array of strings
+func mercureTopics(value any) ([]string, error) {
+	var values []any
+	switch v := value.(type) {
+	case string:
+		values = []any{v}
+	case []any:
+		values = v
+	case AssociativeArray:
+		for _, k := range v.Order {
+			values = append(values, v.Map[k])
+		}
+	}
+
+	topics := make([]string, 0, len(values))
+	for _, v := range values {
+		topic, ok := v.(string)
+		if !ok || topic == "" {
+			return nil, errMercureInvalidTopics
+		}
+
+		topics = append(topics, topic)
+	}
+
+	if len(topics) == 0 {
+		return nil, errMercureInvalidTopics
+	}
+
+	return topics, nil
+}
+
+//export go_mercure_publish
+func go_mercure_publish(topics *C.zval, data *C.zend_string, private C.bool, id *C.zend_string, typ *C.zend_string, retry C.zend_long) (*C.zend_string, *C.zend_string) {
+	fail := func(err error) (*C.zend_string, *C.zend_string) {
+		return nil, (*C.zend_string)(PHPString(err.Error(), false))
+	}
+
+	if retry < 0 {
+		return fail(errMercureInvalidRetry)
+	}
+
+	t, err := mercureTopics(GoValue(unsafe.Pointer(topics)))
+	if err != nil {
+		return fail(err)
+	}
+
+	u := MercureUpdate{
+		Topics:  t,
+		Data:    GoString(unsafe.Pointer(data)),
+		Private: bool(private),
+		ID:      GoString(unsafe.Pointer(id)),
+		Type:    GoString(unsafe.Pointer(typ)),
+		Retry:   uint64(retry),
+	}
+
+	updateID, err := publishMercureUpdate(u)
+	if err != nil {
+		return fail(err)
+	}
+
+	return (*C.zend_string)(PHPString(updateID, false)), nil
+}
@@ -0,0 +1,32 @@
+package frankenphp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMercureTopics(t *testing.T) {
+	topics, err := mercureTopics("https://example.com/books/1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/books/1"}, topics)
+
+	topics, err = mercureTopics([]any{"https://example.com/books/1", "https://example.com/books"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/books/1", "https://example.com/books"}, topics)
+
+	topics, err = mercureTopics(AssociativeArray{Map: map[string]any{"b": "second", "a": "first"}, Order: []string{"b", "a"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second", "first"}, topics)
+
+	for _, invalid := range []any{"", []any{}, []any{"topic", int64(1)}} {
+		_, err = mercureTopics(invalid)
+		assert.ErrorIs(t, err, errMercureInvalidTopics)
+	}
+}
+
+func TestPublishMercureUpdateWithoutPublisher(t *testing.T) {
+	_, err := publishMercureUpdate(MercureUpdate{Topics: []string{"foo"}})
+	assert.ErrorIs(t, err, errMercureNotConfigured)
+}
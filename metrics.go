@@ -42,6 +42,22 @@ type Metrics interface {
 	DequeuedRequest()
 }
 
+// CacheMetrics is optionally implemented by Metrics to collect the lookups of the shared cache.
+// It is separate from Metrics to not break the existing implementations.
+type CacheMetrics interface {
+	// CacheHit collects lookups of the shared cache that found a value
+	CacheHit()
+	// CacheMiss collects lookups of the shared cache that found nothing
+	CacheMiss()
+}
+
+// PubSubMetrics is optionally implemented by Metrics to collect the messages dropped by the pub/sub hub.
+// It is separate from Metrics to not break the existing implementations.
+type PubSubMetrics interface {
+	// DroppedMessage collects published messages that were not delivered because the buffer of a subscriber was full
+	DroppedMessage()
+}
+
 type nullMetrics struct{}
 
 func (n nullMetrics) StartWorker(string) {
@@ -94,6 +110,9 @@ type PrometheusMetrics struct {
 	workerRequestCount *prometheus.CounterVec
 	workerQueueDepth   *prometheus.GaugeVec
 	queueDepth         prometheus.Gauge
+	cacheHits          prometheus.Counter
+	cacheMisses        prometheus.Counter
+	droppedMessages    prometheus.Counter
 	mu                 sync.Mutex
 }
 
@@ -292,10 +311,25 @@ func (m *PrometheusMetrics) DequeuedRequest() {
 	m.queueDepth.Dec()
 }
 
+func (m *PrometheusMetrics) CacheHit() {
+	m.cacheHits.Inc()
+}
+
+func (m *PrometheusMetrics) CacheMiss() {
+	m.cacheMisses.Inc()
+}
+
+func (m *PrometheusMetrics) DroppedMessage() {
+	m.droppedMessages.Inc()
+}
+
 func (m *PrometheusMetrics) Shutdown() {
 	m.registry.Unregister(m.totalThreads)
 	m.registry.Unregister(m.busyThreads)
 	m.registry.Unregister(m.queueDepth)
+	m.registry.Unregister(m.cacheHits)
+	m.registry.Unregister(m.cacheMisses)
+	m.registry.Unregister(m.droppedMessages)
 
 	if m.totalWorkers != nil {
 		m.registry.Unregister(m.totalWorkers)
@@ -349,6 +383,18 @@ func (m *PrometheusMetrics) Shutdown() {
 		Name: "frankenphp_queue_depth",
 		Help: "Number of regular queued requests",
 	})
+	m.cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "frankenphp_cache_hits",
+		Help: "Number of shared cache lookups that found a value",
+	})
+	m.cacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "frankenphp_cache_misses",
+		Help: "Number of shared cache lookups that found nothing",
+	})
+	m.droppedMessages = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "frankenphp_pubsub_dropped_messages",
+		Help: "Number of published messages dropped because a subscriber was too slow",
+	})
 
 	if err := m.registry.Register(m.totalThreads); err != nil &&
 		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
@@ -364,6 +410,21 @@ func (m *PrometheusMetrics) Shutdown() {
 		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 		panic(err)
 	}
+
+	if err := m.registry.Register(m.cacheHits); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.cacheMisses); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.droppedMessages); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
 }
 
 func NewPrometheusMetrics(registry prometheus.Registerer) *PrometheusMetrics {
@@ -385,6 +446,18 @@ func NewPrometheusMetrics(registry prometheus.Registerer) *PrometheusMetrics {
 			Name: "frankenphp_queue_depth",
 			Help: "Number of regular queued requests",
 		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_cache_hits",
+			Help: "Number of shared cache lookups that found a value",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_cache_misses",
+			Help: "Number of shared cache lookups that found nothing",
+		}),
+		droppedMessages: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_pubsub_dropped_messages",
+			Help: "Number of published messages dropped because a subscriber was too slow",
+		}),
 		totalWorkers:       nil,
 		busyWorkers:        nil,
 		workerRequestTime:  nil,
@@ -410,5 +483,20 @@ func NewPrometheusMetrics(registry prometheus.Registerer) *PrometheusMetrics {
 		panic(err)
 	}
 
+	if err := m.registry.Register(m.cacheHits); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.cacheMisses); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.droppedMessages); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
 	return m
 }
@@ -3,6 +3,7 @@ package frankenphp
 import (
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -193,3 +194,103 @@ func TestPrometheusMetrics_TestStopReasonCrash(t *testing.T) {
 
 	}
 }
+
+func TestPrometheusMetrics_Cache(t *testing.T) {
+	m := NewPrometheusMetrics(nil)
+	m.CacheHit()
+	m.CacheHit()
+	m.CacheMiss()
+
+	require.NoError(t, testutil.CollectAndCompare(m.cacheHits, strings.NewReader(`
+		# HELP frankenphp_cache_hits Number of shared cache lookups that found a value
+		# TYPE frankenphp_cache_hits counter
+		frankenphp_cache_hits 2
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.cacheMisses, strings.NewReader(`
+		# HELP frankenphp_cache_misses Number of shared cache lookups that found nothing
+		# TYPE frankenphp_cache_misses counter
+		frankenphp_cache_misses 1
+	`)))
+}
+
+// externalMetrics implements Metrics without the optional interfaces, as implementations written before them
+type externalMetrics struct {
+	nullMetrics
+}
+
+func TestOptionalCacheMetrics(t *testing.T) {
+	var m Metrics = NewPrometheusMetrics(nil)
+	_, ok := m.(CacheMetrics)
+	require.True(t, ok)
+	_, ok = m.(PubSubMetrics)
+	require.True(t, ok)
+
+	activeServer.Store(&Server{metrics: externalMetrics{}})
+	defer activeServer.Store(nil)
+
+	c := newCache(1024)
+	c.metrics = externalMetrics{}
+	_, found := c.get("missing")
+	require.False(t, found)
+
+	s := newSubscriber()
+	s.messages = make(chan Message)
+	s.subscribe("topic")
+	defer s.close()
+	require.Zero(t, publish("topic", "dropped"))
+}
+
+// requestCountingMetrics counts the requests that have been started but not stopped
+type requestCountingMetrics struct {
+	nullMetrics
+	started atomic.Int64
+}
+
+func (m *requestCountingMetrics) StartRequest() {
+	m.started.Add(1)
+}
+
+func (m *requestCountingMetrics) StopRequest() {
+	m.started.Add(-1)
+}
+
+func TestRequestsNotHandledByAThreadAreStopped(t *testing.T) {
+	m := &requestCountingMetrics{}
+	s := &Server{metrics: m, maxWaitTime: time.Millisecond}
+
+	// no thread is available
+	previous := regularRequestChan
+	regularRequestChan = make(chan *frankenPHPContext)
+	defer func() {
+		regularRequestChan = previous
+	}()
+
+	// max wait time exceeded
+	require.False(t, s.handleRequestWithRegularPHPThreads(newFrankenPHPContext(), nil))
+
+	// cancelled
+	cancel := make(chan struct{})
+	close(cancel)
+	s.maxWaitTime = 0
+	require.False(t, s.handleRequestWithRegularPHPThreads(newFrankenPHPContext(), cancel))
+
+	// sub-request
+	fc := newFrankenPHPContext()
+	fc.isSubRequest = true
+	require.False(t, s.handleRequestWithRegularPHPThreads(fc, nil))
+
+	require.Zero(t, m.started.Load())
+}
+
+func TestPrometheusMetrics_DroppedMessage(t *testing.T) {
+	m := NewPrometheusMetrics(nil)
+	m.DroppedMessage()
+	m.DroppedMessage()
+	m.DroppedMessage()
+
+	require.NoError(t, testutil.CollectAndCompare(m.droppedMessages, strings.NewReader(`
+		# HELP frankenphp_pubsub_dropped_messages Number of published messages dropped because a subscriber was too slow
+		# TYPE frankenphp_pubsub_dropped_messages counter
+		frankenphp_pubsub_dropped_messages 3
+	`)))
+}
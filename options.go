@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log/slog"
 	"time"
+
+	"github.com/dunglas/frankenphp/internal/cron"
 )
 
 // defaultMaxConsecutiveFailures is the default maximum number of consecutive failures before panicking
@@ -19,13 +21,20 @@ type WorkerOption func(*workerOpt) error
 //
 // If you change this, also update the Caddy module and the documentation.
 type opt struct {
-	numThreads  int
-	maxThreads  int
-	workers     []workerOpt
-	logger      *slog.Logger
-	metrics     Metrics
-	phpIni      map[string]string
-	maxWaitTime time.Duration
+	numThreads   int
+	maxThreads   int
+	workers      []workerOpt
+	logger       *slog.Logger
+	metrics      Metrics
+	phpIni       map[string]string
+	maxWaitTime  time.Duration
+	queueDir     string
+	schedule     []scheduleOpt
+	cacheMaxSize int64
+	mercure      MercurePublisher
+
+	responseCacheMaxSize int64
+	responseCacheDir     string
 }
 
 type workerOpt struct {
@@ -35,6 +44,14 @@ type workerOpt struct {
 	env                    PreparedEnv
 	watch                  []string
 	maxConsecutiveFailures int
+	queues                 []string
+}
+
+type scheduleOpt struct {
+	spec     string
+	schedule *cron.Schedule
+	fileName string
+	worker   string
 }
 
 // WithNumThreads configures the number of PHP threads to start.
@@ -116,6 +133,15 @@ func WithWorkerMaxFailures(maxFailures int) WorkerOption {
 	}
 }
 
+// WithWorkerQueues sets the job queues consumed by the worker, see frankenphp_queue_push()
+func WithWorkerQueues(queues ...string) WorkerOption {
+	return func(w *workerOpt) error {
+		w.queues = queues
+
+		return nil
+	}
+}
+
 // WithLogger configures the global logger to use.
 func WithLogger(l *slog.Logger) Option {
 	return func(o *opt) error {
@@ -141,3 +167,87 @@ func WithMaxWaitTime(maxWaitTime time.Duration) Option {
 		return nil
 	}
 }
+
+// WithQueueDir configures the directory storing the logs of the job queues, queues are disabled if empty.
+func WithQueueDir(dir string) Option {
+	return func(o *opt) error {
+		o.queueDir = dir
+
+		return nil
+	}
+}
+
+// WithScheduledScript runs the script on a regular thread according to the cron expression spec.
+// A run is skipped if the previous one is still running.
+func WithScheduledScript(spec string, fileName string) Option {
+	return func(o *opt) error {
+		s, err := cron.Parse(spec)
+		if err != nil {
+			return err
+		}
+
+		o.schedule = append(o.schedule, scheduleOpt{spec: spec, schedule: s, fileName: fileName})
+
+		return nil
+	}
+}
+
+// WithScheduledWorker calls the worker named workerName according to the cron expression spec,
+// its callback receives an array containing the "schedule" and the "scheduled_at" timestamp.
+// A run is skipped if the previous one is still running.
+func WithScheduledWorker(spec string, workerName string) Option {
+	return func(o *opt) error {
+		s, err := cron.Parse(spec)
+		if err != nil {
+			return err
+		}
+
+		o.schedule = append(o.schedule, scheduleOpt{spec: spec, schedule: s, worker: workerName})
+
+		return nil
+	}
+}
+
+// WithCacheMaxSize sets the approximate memory limit in bytes of the cache shared by all PHP threads.
+func WithCacheMaxSize(maxSize int64) Option {
+	return func(o *opt) error {
+		if maxSize <= 0 {
+			return fmt.Errorf("the cache size must be positive, got %d", maxSize)
+		}
+
+		o.cacheMaxSize = maxSize
+
+		return nil
+	}
+}
+
+// WithMercurePublisher sets the function dispatching the updates published by mercure_publish().
+func WithMercurePublisher(publisher MercurePublisher) Option {
+	return func(o *opt) error {
+		o.mercure = publisher
+
+		return nil
+	}
+}
+
+// WithResponseCacheMaxSize sets the approximate size limit in bytes of the response cache.
+func WithResponseCacheMaxSize(maxSize int64) Option {
+	return func(o *opt) error {
+		if maxSize <= 0 {
+			return fmt.Errorf("the response cache size must be positive, got %d", maxSize)
+		}
+
+		o.responseCacheMaxSize = maxSize
+
+		return nil
+	}
+}
+
+// WithResponseCacheDir sets the directory where the bodies of the cached responses are stored, instead of memory.
+func WithResponseCacheDir(dir string) Option {
+	return func(o *opt) error {
+		o.responseCacheDir = dir
+
+		return nil
+	}
+}
@@ -28,6 +28,9 @@ type phpMainThread struct {
 	commonHeaders   map[string]*C.zend_string
 	knownServerKeys map[string]*C.zend_string
 	sandboxedEnv    map[string]*C.zend_string
+
+	// server is the server owning the runtime, the code called by PHP without a request uses its logger
+	server *Server
 }
 
 var (
@@ -38,7 +41,7 @@ var (
 // initPHPThreads starts the main PHP thread,
 // a fixed number of inactive PHP threads
 // and reserves a fixed number of possible PHP threads
-func initPHPThreads(numThreads int, numMaxThreads int, phpIni map[string]string) (*phpMainThread, error) {
+func (s *Server) initPHPThreads(numThreads int, numMaxThreads int, phpIni map[string]string) (*phpMainThread, error) {
 	mainThread = &phpMainThread{
 		state:        newThreadState(),
 		done:         make(chan struct{}),
@@ -46,6 +49,7 @@ func initPHPThreads(numThreads int, numMaxThreads int, phpIni map[string]string)
 		maxThreads:   numMaxThreads,
 		phpIni:       phpIni,
 		sandboxedEnv: initializeEnv(),
+		server:       s,
 	}
 
 	// initialize the first thread
@@ -171,7 +175,7 @@ func (mainThread *phpMainThread) setAutomaticMaxThreads() {
 	maxAllowedThreads := totalSysMemory / uint64(perThreadMemoryLimit)
 	mainThread.maxThreads = int(maxAllowedThreads)
 
-	logger.LogAttrs(context.Background(), slog.LevelDebug, "Automatic thread limit", slog.Int("perThreadMemoryLimitMB", int(perThreadMemoryLimit/1024/1024)), slog.Int("maxThreads", mainThread.maxThreads))
+	mainThread.server.logger.LogAttrs(context.Background(), slog.LevelDebug, "Automatic thread limit", slog.Int("perThreadMemoryLimitMB", int(perThreadMemoryLimit/1024/1024)), slog.Int("maxThreads", mainThread.maxThreads))
 }
 
 //export go_frankenphp_shutdown_main_thread
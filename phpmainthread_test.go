@@ -19,8 +19,7 @@ import (
 var testDataPath, _ = filepath.Abs("./testdata")
 
 func TestStartAndStopTheMainThreadWithOneInactiveThread(t *testing.T) {
-	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
-	_, err := initPHPThreads(1, 1, nil) // boot 1 thread
+	_, err := newTestServer().initPHPThreads(1, 1, nil) // boot 1 thread
 	assert.NoError(t, err)
 
 	assert.Len(t, phpThreads, 1)
@@ -32,9 +31,8 @@ func TestStartAndStopTheMainThreadWithOneInactiveThread(t *testing.T) {
 }
 
 func TestTransitionRegularThreadToWorkerThread(t *testing.T) {
-	workers = nil
-	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
-	_, err := initPHPThreads(1, 1, nil)
+	s := newTestServer()
+	_, err := s.initPHPThreads(1, 1, nil)
 	assert.NoError(t, err)
 
 	// transition to regular thread
@@ -42,7 +40,7 @@ func TestTransitionRegularThreadToWorkerThread(t *testing.T) {
 	assert.IsType(t, &regularThread{}, phpThreads[0].handler)
 
 	// transition to worker thread
-	worker := getDummyWorker("transition-worker-1.php")
+	worker := getDummyWorker(s, "transition-worker-1.php")
 	convertToWorkerThread(phpThreads[0], worker)
 	assert.IsType(t, &workerThread{}, phpThreads[0].handler)
 	assert.Len(t, worker.threads, 1)
@@ -57,12 +55,11 @@ func TestTransitionRegularThreadToWorkerThread(t *testing.T) {
 }
 
 func TestTransitionAThreadBetween2DifferentWorkers(t *testing.T) {
-	workers = nil
-	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
-	_, err := initPHPThreads(1, 1, nil)
+	s := newTestServer()
+	_, err := s.initPHPThreads(1, 1, nil)
 	assert.NoError(t, err)
-	firstWorker := getDummyWorker("transition-worker-1.php")
-	secondWorker := getDummyWorker("transition-worker-2.php")
+	firstWorker := getDummyWorker(s, "transition-worker-1.php")
+	secondWorker := getDummyWorker(s, "transition-worker-2.php")
 
 	// convert to first worker thread
 	convertToWorkerThread(phpThreads[0], firstWorker)
@@ -151,13 +148,12 @@ func TestTransitionThreadsWhileDoingRequests(t *testing.T) {
 }
 
 func TestFinishBootingAWorkerScript(t *testing.T) {
-	workers = nil
-	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
-	_, err := initPHPThreads(1, 1, nil)
+	s := newTestServer()
+	_, err := s.initPHPThreads(1, 1, nil)
 	assert.NoError(t, err)
 
 	// boot the worker
-	worker := getDummyWorker("transition-worker-1.php")
+	worker := getDummyWorker(s, "transition-worker-1.php")
 	convertToWorkerThread(phpThreads[0], worker)
 	phpThreads[0].state.waitFor(stateReady)
 
@@ -174,35 +170,37 @@ func TestFinishBootingAWorkerScript(t *testing.T) {
 }
 
 func TestReturnAnErrorIf2WorkersHaveTheSameFileName(t *testing.T) {
-	workers = []*worker{}
-	w, err1 := newWorker(workerOpt{fileName: "filename.php", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
-	workers = append(workers, w)
-	_, err2 := newWorker(workerOpt{fileName: "filename.php", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
+	s := newTestServer()
+	w, err1 := s.newWorker(workerOpt{fileName: "filename.php", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
+	s.workers = append(s.workers, w)
+	_, err2 := s.newWorker(workerOpt{fileName: "filename.php", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
 
 	assert.NoError(t, err1)
 	assert.Error(t, err2, "two workers cannot have the same filename")
 }
 
 func TestReturnAnErrorIf2ModuleWorkersHaveTheSameName(t *testing.T) {
-	workers = []*worker{}
-	w, err1 := newWorker(workerOpt{fileName: "filename.php", name: "workername", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
-	workers = append(workers, w)
-	_, err2 := newWorker(workerOpt{fileName: "filename2.php", name: "workername", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
+	s := newTestServer()
+	w, err1 := s.newWorker(workerOpt{fileName: "filename.php", name: "workername", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
+	s.workers = append(s.workers, w)
+	_, err2 := s.newWorker(workerOpt{fileName: "filename2.php", name: "workername", maxConsecutiveFailures: defaultMaxConsecutiveFailures})
 
 	assert.NoError(t, err1)
 	assert.Error(t, err2, "two workers cannot have the same name")
 }
 
-func getDummyWorker(fileName string) *worker {
-	if workers == nil {
-		workers = []*worker{}
-	}
-	worker, _ := newWorker(workerOpt{
+// newTestServer creates a server having the state needed by the PHP threads without starting it
+func newTestServer() *Server {
+	return &Server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), metrics: nullMetrics{}}
+}
+
+func getDummyWorker(s *Server, fileName string) *worker {
+	worker, _ := s.newWorker(workerOpt{
 		fileName:               testDataPath + "/" + fileName,
 		num:                    1,
 		maxConsecutiveFailures: defaultMaxConsecutiveFailures,
 	})
-	workers = append(workers, worker)
+	s.workers = append(s.workers, worker)
 	return worker
 }
 
@@ -229,9 +227,13 @@ func allPossibleTransitions(worker1Path string, worker2Path string) []func(*phpT
 				thread.boot()
 			}
 		},
-		func(thread *phpThread) { convertToWorkerThread(thread, getWorkerByPath(worker1Path)) },
+		func(thread *phpThread) {
+			convertToWorkerThread(thread, activeServer.Load().getWorkerByPath(worker1Path))
+		},
 		convertToInactiveThread,
-		func(thread *phpThread) { convertToWorkerThread(thread, getWorkerByPath(worker2Path)) },
+		func(thread *phpThread) {
+			convertToWorkerThread(thread, activeServer.Load().getWorkerByPath(worker2Path))
+		},
 		convertToInactiveThread,
 	}
 }
@@ -22,6 +22,9 @@ type phpThread struct {
 	handler      threadHandler
 	state        *threadState
 	sandboxedEnv map[string]*C.zend_string
+	// semaphores held and topics subscribed to by the script, only accessed from the PHP thread
+	locks      map[string]int
+	subscriber *subscriber
 }
 
 // interface that defines how the callbacks from the C thread should be handled
@@ -44,7 +47,7 @@ func newPHPThread(threadIndex int) *phpThread {
 func (thread *phpThread) boot() {
 	// thread must be in reserved state to boot
 	if !thread.state.compareAndSwap(stateReserved, stateBooting) && !thread.state.compareAndSwap(stateBootRequested, stateBooting) {
-		logger.Error("thread is not in reserved state: " + thread.state.name())
+		mainThread.server.logger.Error("thread is not in reserved state: " + thread.state.name())
 		panic("thread is not in reserved state: " + thread.state.name())
 	}
 
@@ -56,7 +59,7 @@ func (thread *phpThread) boot() {
 
 	// start the actual posix thread - TODO: try this with go threads instead
 	if !C.frankenphp_new_php_thread(C.uintptr_t(thread.threadIndex)) {
-		logger.LogAttrs(context.Background(), slog.LevelError, "unable to create thread", slog.Int("thread", thread.threadIndex))
+		mainThread.server.logger.LogAttrs(context.Background(), slog.LevelError, "unable to create thread", slog.Int("thread", thread.threadIndex))
 		panic("unable to create thread")
 	}
 
@@ -149,6 +152,8 @@ func go_frankenphp_before_script_execution(threadIndex C.uintptr_t) *C.char {
 //export go_frankenphp_after_script_execution
 func go_frankenphp_after_script_execution(threadIndex C.uintptr_t, exitStatus C.int) {
 	thread := phpThreads[threadIndex]
+	thread.releaseAllSemaphores()
+	thread.unsubscribeAll()
 	if exitStatus < 0 {
 		panic(ErrScriptExecution)
 	}
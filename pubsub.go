@@ -0,0 +1,230 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// defaultSubscriptionBufferSize is the number of messages a subscriber can hold before new ones are dropped
+const defaultSubscriptionBufferSize = 100
+
+// EXPERIMENTAL: Message is a value published on a topic
+type Message struct {
+	Topic string
+	// Value is shared by all subscribers and must not be modified
+	Value any
+}
+
+// EXPERIMENTAL: Subscription receives the messages published on its topics
+type Subscription struct {
+	// C receives the messages, it is closed by Unsubscribe
+	C <-chan Message
+
+	subscriber *subscriber
+}
+
+type subscriber struct {
+	messages chan Message
+	// topics is guarded by pubsubMu
+	topics    map[string]struct{}
+	closeOnce sync.Once
+}
+
+var (
+	pubsubMu sync.RWMutex
+	topics   = make(map[string]map[*subscriber]struct{})
+)
+
+func newSubscriber() *subscriber {
+	return &subscriber{
+		messages: make(chan Message, defaultSubscriptionBufferSize),
+		topics:   make(map[string]struct{}),
+	}
+}
+
+func (s *subscriber) subscribe(topic string) {
+	pubsubMu.Lock()
+	defer pubsubMu.Unlock()
+
+	if topics[topic] == nil {
+		topics[topic] = make(map[*subscriber]struct{})
+	}
+	topics[topic][s] = struct{}{}
+	s.topics[topic] = struct{}{}
+}
+
+func (s *subscriber) unsubscribe(topic string) {
+	pubsubMu.Lock()
+	defer pubsubMu.Unlock()
+
+	s.removeTopic(topic)
+}
+
+// close removes all the subscriptions and closes the channel
+// close is idempotent, it may be called both by Unsubscribe and when the thread is released
+func (s *subscriber) close() {
+	s.closeOnce.Do(func() {
+		pubsubMu.Lock()
+		defer pubsubMu.Unlock()
+
+		for topic := range s.topics {
+			s.removeTopic(topic)
+		}
+
+		close(s.messages)
+	})
+}
+
+// removeTopic must be called with pubsubMu locked
+func (s *subscriber) removeTopic(topic string) {
+	delete(s.topics, topic)
+	delete(topics[topic], s)
+
+	if len(topics[topic]) == 0 {
+		delete(topics, topic)
+	}
+}
+
+// EXPERIMENTAL: Subscribe returns a subscription receiving the messages published on the given topics,
+// by Go code or by PHP scripts calling frankenphp_publish().
+//
+// Messages are dropped when more than 100 messages are waiting to be received.
+func Subscribe(topics ...string) *Subscription {
+	s := newSubscriber()
+	for _, topic := range topics {
+		s.subscribe(topic)
+	}
+
+	return &Subscription{C: s.messages, subscriber: s}
+}
+
+// EXPERIMENTAL: Unsubscribe stops receiving messages and closes C, calling it again has no effect
+func (s *Subscription) Unsubscribe() {
+	s.subscriber.close()
+}
+
+// EXPERIMENTAL: Publish sends a value to all the subscribers of a topic without blocking,
+// including PHP threads that called frankenphp_subscribe().
+// The value must be convertible to PHP with PHPValue.
+// It returns the number of subscribers the message has been delivered to.
+func Publish(topic string, value any) (int, error) {
+	if err := checkPHPValue(value); err != nil {
+		return 0, err
+	}
+
+	return publish(topic, value), nil
+}
+
+func publish(topic string, value any) int {
+	pubsubMu.RLock()
+	defer pubsubMu.RUnlock()
+
+	m := Message{Topic: topic, Value: value}
+	var pm PubSubMetrics
+	if s := activeServer.Load(); s != nil {
+		pm, _ = s.metrics.(PubSubMetrics)
+	}
+
+	delivered := 0
+	for s := range topics[topic] {
+		select {
+		case s.messages <- m:
+			delivered++
+		default:
+			if pm != nil {
+				pm.DroppedMessage()
+			}
+		}
+	}
+
+	return delivered
+}
+
+// receiveMessage waits for a message sent to the topics the thread subscribed to.
+// A negative timeout waits forever.
+func (thread *phpThread) receiveMessage(timeout time.Duration) (Message, bool) {
+	if thread.subscriber == nil {
+		return Message{}, false
+	}
+
+	if timeout == 0 {
+		select {
+		case m := <-thread.subscriber.messages:
+			return m, true
+		default:
+			return Message{}, false
+		}
+	}
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	select {
+	case m := <-thread.subscriber.messages:
+		return m, true
+	case <-timer:
+	case <-thread.drainChan:
+	}
+
+	return Message{}, false
+}
+
+// unsubscribeAll is called when the script stops
+func (thread *phpThread) unsubscribeAll() {
+	if thread.subscriber == nil {
+		return
+	}
+
+	thread.subscriber.close()
+	thread.subscriber = nil
+}
+
+//export go_frankenphp_publish
+func go_frankenphp_publish(topic *C.zend_string, value *C.zval) C.zend_long {
+	return C.zend_long(publish(GoString(unsafe.Pointer(topic)), GoValue(unsafe.Pointer(value))))
+}
+
+//export go_frankenphp_subscribe
+func go_frankenphp_subscribe(threadIndex C.uintptr_t, topic *C.zend_string) {
+	thread := phpThreads[threadIndex]
+	if thread.subscriber == nil {
+		thread.subscriber = newSubscriber()
+	}
+
+	thread.subscriber.subscribe(GoString(unsafe.Pointer(topic)))
+}
+
+//export go_frankenphp_unsubscribe
+func go_frankenphp_unsubscribe(threadIndex C.uintptr_t, topic *C.zend_string) {
+	thread := phpThreads[threadIndex]
+	if thread.subscriber == nil {
+		return
+	}
+
+	thread.subscriber.unsubscribe(GoString(unsafe.Pointer(topic)))
+}
+
+//export go_frankenphp_receive
+func go_frankenphp_receive(threadIndex C.uintptr_t, timeout C.double) (C.bool, unsafe.Pointer) {
+	thread := phpThreads[threadIndex]
+
+	m, ok := thread.receiveMessage(secondsToDuration(timeout))
+	if !ok {
+		return C.bool(false), nil
+	}
+
+	ptr := PHPValue(AssociativeArray{
+		Map:   map[string]any{"topic": m.Topic, "value": m.Value},
+		Order: []string{"topic", "value"},
+	})
+	thread.Pin(ptr)
+
+	return C.bool(true), ptr
+}
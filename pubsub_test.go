@@ -0,0 +1,97 @@
+package frankenphp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishToGoSubscribers(t *testing.T) {
+	sub1 := Subscribe("foo", "bar")
+	sub2 := Subscribe("foo")
+
+	n, err := Publish("foo", "value")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = Publish("bar", int64(42))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.Equal(t, Message{Topic: "foo", Value: "value"}, <-sub1.C)
+	assert.Equal(t, Message{Topic: "bar", Value: int64(42)}, <-sub1.C)
+	assert.Equal(t, Message{Topic: "foo", Value: "value"}, <-sub2.C)
+
+	sub1.Unsubscribe()
+	sub2.Unsubscribe()
+
+	_, open := <-sub1.C
+	assert.False(t, open)
+	assert.Empty(t, topics)
+
+	n, err = Publish("foo", "value")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestPublishDropsMessagesWhenTheBufferIsFull(t *testing.T) {
+	sub := Subscribe("full")
+	defer sub.Unsubscribe()
+
+	for range defaultSubscriptionBufferSize {
+		n, _ := Publish("full", "value")
+		require.Equal(t, 1, n)
+	}
+
+	n, err := Publish("full", "dropped")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, sub.C, defaultSubscriptionBufferSize)
+}
+
+func TestUnsubscribeIsIdempotent(t *testing.T) {
+	sub := Subscribe("idempotent")
+
+	sub.Unsubscribe()
+	assert.NotPanics(t, sub.Unsubscribe)
+
+	_, open := <-sub.C
+	assert.False(t, open)
+
+	n, err := Publish("idempotent", "value")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestPublishRejectsValuesNotConvertibleToPHP(t *testing.T) {
+	_, err := Publish("foo", struct{}{})
+	assert.Error(t, err)
+}
+
+func TestPHPThreadReceivesMessages(t *testing.T) {
+	thread := newPHPThread(0)
+	thread.drainChan = make(chan struct{})
+
+	_, ok := thread.receiveMessage(-1)
+	assert.False(t, ok, "a thread without subscriptions must not wait")
+
+	thread.subscriber = newSubscriber()
+	thread.subscriber.subscribe("thread")
+
+	_, ok = thread.receiveMessage(0)
+	assert.False(t, ok)
+
+	_, _ = Publish("thread", "value")
+	m, ok := thread.receiveMessage(-1)
+	require.True(t, ok)
+	assert.Equal(t, "value", m.Value)
+
+	close(thread.drainChan)
+	_, ok = thread.receiveMessage(-1)
+	assert.False(t, ok)
+
+	thread.unsubscribeAll()
+	assert.Nil(t, thread.subscriber)
+	assert.Empty(t, topics)
+}
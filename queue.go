@@ -0,0 +1,941 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// defaultQueueMaxAttempts is the number of times a job is executed before being moved to the dead-letter store
+const defaultQueueMaxAttempts = 3
+
+var (
+	ErrQueueNotEnabled = errors.New("the job queue is not enabled, configure a queue directory")
+	ErrQueueNotFound   = errors.New("queue not found")
+
+	queueNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+	// the delay before retrying a failed job doubles after each failure, starting at 2*queueMinBackoff
+	queueMinBackoff = 500 * time.Millisecond
+	queueMaxBackoff = 5 * time.Minute
+
+	// the log is compacted at runtime once it contains more records of finished jobs than records of live jobs,
+	// and at least queueCompactMinRecords of them
+	queueCompactMinRecords = 1000
+
+	queueDir     string
+	queues       map[string]*jobQueue
+	queuesMu     sync.Mutex
+	queuesWg     sync.WaitGroup
+	cancelQueues context.CancelFunc
+)
+
+// EXPERIMENTAL: QueueJob is a job pushed by frankenphp_queue_push()
+type QueueJob struct {
+	ID          string    `json:"id"`
+	Queue       string    `json:"queue"`
+	Payload     any       `json:"payload"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	CreatedAt   time.Time `json:"created_at"`
+	AvailableAt time.Time `json:"available_at"`
+	LastError   string    `json:"last_error,omitempty"`
+
+	rawPayload json.RawMessage
+	seq        uint64
+	running    bool
+	backoff    *exponentialBackoff
+}
+
+// EXPERIMENTAL: QueueInfo summarizes the state of a queue
+type QueueInfo struct {
+	Name    string `json:"name"`
+	Worker  string `json:"worker,omitempty"`
+	Pending int    `json:"pending"`
+	Running int    `json:"running"`
+	Dead    int    `json:"dead"`
+}
+
+// queueRecord is a line of the on-disk log of a queue, replaying the log restores the queue
+type queueRecord struct {
+	Op          string          `json:"op"`
+	ID          string          `json:"id"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Attempts    int             `json:"attempts,omitempty"`
+	MaxAttempts int             `json:"max_attempts,omitempty"`
+	CreatedAt   time.Time       `json:"created_at,omitzero"`
+	AvailableAt time.Time       `json:"available_at,omitzero"`
+	Error       string          `json:"error,omitempty"`
+}
+
+const (
+	queueOpPush   = "push"
+	queueOpRetry  = "retry"
+	queueOpAck    = "ack"
+	queueOpDead   = "dead"
+	queueOpRevive = "revive"
+	queueOpPurge  = "purge"
+)
+
+type jobQueue struct {
+	name   string
+	worker *worker
+	logger *slog.Logger
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	// records is the number of records in the log
+	records int
+	seq     uint64
+	pending []*QueueJob
+	dead    []*QueueJob
+	notify  chan struct{}
+}
+
+func (s *Server) initQueues(dir string, opt []workerOpt) error {
+	queues = make(map[string]*jobQueue)
+	queueDir = dir
+
+	consumers := make(map[string]*worker)
+	// workers are created in the same order as their options
+	for i, o := range opt {
+		for _, name := range o.queues {
+			if w, ok := consumers[name]; ok {
+				return fmt.Errorf("queue %q is already consumed by worker %q", name, w.name)
+			}
+
+			consumers[name] = s.workers[i]
+		}
+	}
+
+	if dir == "" {
+		if len(consumers) > 0 {
+			return ErrQueueNotEnabled
+		}
+
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("unable to create the queue directory: %w", err)
+	}
+
+	// load the queues left by a previous run even if nothing consumes them anymore
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if _, err := getQueue(strings.TrimSuffix(filepath.Base(f), ".log"), s.logger); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelQueues = cancel
+
+	for name, w := range consumers {
+		q, err := getQueue(name, s.logger)
+		if err != nil {
+			return err
+		}
+
+		q.worker = w
+		queuesWg.Add(1)
+		go func() {
+			defer queuesWg.Done()
+			q.consume(ctx)
+		}()
+	}
+
+	return nil
+}
+
+// drainQueues stops dispatching jobs to workers, jobs that are already running are allowed to finish
+func drainQueues() {
+	if cancelQueues != nil {
+		cancelQueues()
+		cancelQueues = nil
+	}
+}
+
+// closeQueues waits for the running jobs to be recorded and closes the logs
+func closeQueues() {
+	queuesWg.Wait()
+
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+
+	for _, q := range queues {
+		q.mu.Lock()
+		if q.file != nil {
+			_ = q.file.Close()
+			q.file = nil
+		}
+		q.mu.Unlock()
+	}
+
+	queues = nil
+	queueDir = ""
+}
+
+// getQueue returns the queue with the given name, loading or creating its log if necessary,
+// logger is used by the queue if it is created
+func getQueue(name string, logger *slog.Logger) (*jobQueue, error) {
+	if !queueNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid queue name %q", name)
+	}
+
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+
+	if queueDir == "" {
+		return nil, ErrQueueNotEnabled
+	}
+
+	if q, ok := queues[name]; ok {
+		return q, nil
+	}
+
+	q := &jobQueue{name: name, logger: logger, notify: make(chan struct{}, 1)}
+	if err := q.open(filepath.Join(queueDir, name+".log")); err != nil {
+		return nil, err
+	}
+	queues[name] = q
+
+	return q, nil
+}
+
+func lookupQueue(name string) (*jobQueue, error) {
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+
+	if queueDir == "" {
+		return nil, ErrQueueNotEnabled
+	}
+
+	q, ok := queues[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrQueueNotFound, name)
+	}
+
+	return q, nil
+}
+
+// open replays the log, rewrites it without the finished jobs and opens it for appending
+func (q *jobQueue) open(path string) error {
+	if err := q.replay(path); err != nil {
+		return err
+	}
+
+	q.path = path
+
+	return q.compact()
+}
+
+// compact rewrites the log without the records of the finished jobs
+func (q *jobQueue) compact() error {
+	tmp := q.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	records := 0
+	w := bufio.NewWriter(f)
+	for _, job := range q.pending {
+		if err := writeQueueRecord(w, job.record()); err != nil {
+			_ = f.Close()
+			return err
+		}
+		records++
+	}
+	for _, job := range q.dead {
+		if err := writeQueueRecord(w, job.record()); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := writeQueueRecord(w, queueRecord{Op: queueOpDead, ID: job.ID, Error: job.LastError}); err != nil {
+			_ = f.Close()
+			return err
+		}
+		records += 2
+	}
+
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if q.file != nil {
+		_ = q.file.Close()
+	}
+	q.file = file
+	q.records = records
+
+	return nil
+}
+
+// compactIfNeeded compacts the log once the records of the finished jobs outnumber the ones of the live jobs,
+// it must be called with the lock held, after the state of the queue has been updated
+func (q *jobQueue) compactIfNeeded() {
+	if q.file == nil {
+		return
+	}
+
+	live := len(q.pending) + 2*len(q.dead)
+	if finished := q.records - live; finished < queueCompactMinRecords || finished <= live {
+		return
+	}
+
+	if err := q.compact(); err != nil {
+		q.logger.LogAttrs(context.Background(), slog.LevelError, "unable to compact the queue log", slog.String("queue", q.name), slog.Any("error", err))
+	}
+}
+
+func (q *jobQueue) replay(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	jobs := make(map[string]*QueueJob)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var rec queueRecord
+			if jsonErr := json.Unmarshal(line, &rec); jsonErr != nil {
+				// most likely a partial write during a crash
+				q.logger.LogAttrs(context.Background(), slog.LevelWarn, "skipping corrupted queue record", slog.String("queue", q.name), slog.Any("error", jsonErr))
+			} else if jobErr := q.apply(jobs, rec); jobErr != nil {
+				q.logger.LogAttrs(context.Background(), slog.LevelWarn, "skipping invalid queue record", slog.String("queue", q.name), slog.Any("error", jobErr))
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, job := range jobs {
+		job.backoff = newJobBackoff(job.MaxAttempts)
+		for range job.Attempts {
+			job.backoff.recordFailure()
+		}
+	}
+
+	sortJobs := func(jobs []*QueueJob) {
+		slices.SortFunc(jobs, func(a, b *QueueJob) int { return cmp.Compare(a.seq, b.seq) })
+	}
+	sortJobs(q.pending)
+	sortJobs(q.dead)
+
+	return nil
+}
+
+func (q *jobQueue) apply(jobs map[string]*QueueJob, rec queueRecord) error {
+	if rec.Op == queueOpPush {
+		payload, err := unmarshalQueuePayload(rec.Payload)
+		if err != nil {
+			return err
+		}
+
+		q.seq++
+		job := &QueueJob{
+			ID:          rec.ID,
+			Queue:       q.name,
+			Payload:     payload,
+			Attempts:    rec.Attempts,
+			MaxAttempts: rec.MaxAttempts,
+			CreatedAt:   rec.CreatedAt,
+			AvailableAt: rec.AvailableAt,
+			LastError:   rec.Error,
+			rawPayload:  rec.Payload,
+			seq:         q.seq,
+		}
+		jobs[job.ID] = job
+		q.pending = append(q.pending, job)
+
+		return nil
+	}
+
+	job, ok := jobs[rec.ID]
+	if !ok {
+		return fmt.Errorf("unknown job %q", rec.ID)
+	}
+
+	switch rec.Op {
+	case queueOpRetry:
+		job.Attempts = rec.Attempts
+		job.AvailableAt = rec.AvailableAt
+		job.LastError = rec.Error
+	case queueOpAck:
+		q.pending = removeJob(q.pending, job)
+		delete(jobs, job.ID)
+	case queueOpDead:
+		job.LastError = rec.Error
+		q.pending = removeJob(q.pending, job)
+		q.dead = append(q.dead, job)
+	case queueOpRevive:
+		job.Attempts = 0
+		job.AvailableAt = rec.AvailableAt
+		q.dead = removeJob(q.dead, job)
+		q.pending = append(q.pending, job)
+	case queueOpPurge:
+		q.dead = removeJob(q.dead, job)
+		delete(jobs, job.ID)
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+
+	return nil
+}
+
+func (q *jobQueue) push(payload any, delay time.Duration, maxAttempts int) (string, error) {
+	raw, err := json.Marshal(marshalQueuePayload(payload))
+	if err != nil {
+		return "", fmt.Errorf("unable to encode the payload: %w", err)
+	}
+
+	now := time.Now()
+	job := &QueueJob{
+		ID:          newJobID(),
+		Queue:       q.name,
+		Payload:     payload,
+		MaxAttempts: maxAttempts,
+		CreatedAt:   now,
+		AvailableAt: now.Add(delay),
+		rawPayload:  raw,
+		backoff:     newJobBackoff(maxAttempts),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := q.append(job.record(), true); err != nil {
+		return "", err
+	}
+
+	q.seq++
+	job.seq = q.seq
+	q.pending = append(q.pending, job)
+	q.signal()
+
+	return job.ID, nil
+}
+
+// append writes a record to the log, only pushes are synced to disk:
+// losing other records means that a job may run again, which is allowed as delivery is at-least-once
+func (q *jobQueue) append(rec queueRecord, sync bool) error {
+	if q.file == nil {
+		return ErrNotRunning
+	}
+
+	if err := writeQueueRecord(q.file, rec); err != nil {
+		return err
+	}
+	q.records++
+
+	if sync {
+		return q.file.Sync()
+	}
+
+	return nil
+}
+
+func (q *jobQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next marks the next available job as running and returns it,
+// if no job is available yet, it returns how long to wait for the next one (-1 if there is none)
+func (q *jobQueue) next() (*QueueJob, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Duration(-1)
+	for _, job := range q.pending {
+		if job.running {
+			continue
+		}
+
+		if !job.AvailableAt.After(now) {
+			job.running = true
+
+			return job, 0
+		}
+
+		if d := job.AvailableAt.Sub(now); wait == -1 || d < wait {
+			wait = d
+		}
+	}
+
+	return nil, wait
+}
+
+// consume dispatches the available jobs to the worker until ctx is done
+func (q *jobQueue) consume(ctx context.Context) {
+	for {
+		job, wait := q.next()
+		if job == nil {
+			var timer <-chan time.Time
+			if wait >= 0 {
+				timer = time.After(wait)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.notify:
+			case <-timer:
+			}
+
+			continue
+		}
+
+		fc := newFrankenPHPContext()
+		fc.server = q.worker.server
+		fc.logger = q.logger
+		fc.worker = q.worker
+		fc.handlerParameters = job.handlerParameters()
+		fc.ctx, fc.cancel = context.WithCancel(ctx)
+
+		if !q.worker.queueRequest(fc, ctx.Done()) {
+			q.mu.Lock()
+			job.running = false
+			q.mu.Unlock()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			continue
+		}
+
+		q.worker.server.metrics.StartWorkerRequest(q.worker.name)
+
+		queuesWg.Add(1)
+		go func() {
+			defer queuesWg.Done()
+
+			<-fc.done
+			q.worker.server.metrics.StopWorkerRequest(q.worker.name, time.Since(fc.startedAt))
+			q.complete(job, fc.handlerError)
+		}()
+	}
+}
+
+// complete acknowledges a successful job, schedules a failed job for a retry or moves it to the dead-letter store
+func (q *jobQueue) complete(job *QueueJob, jobErr error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job.running = false
+
+	var rec queueRecord
+	switch {
+	case jobErr == nil:
+		q.pending = removeJob(q.pending, job)
+		rec = queueRecord{Op: queueOpAck, ID: job.ID}
+	case job.backoff.recordFailure():
+		job.Attempts++
+		job.LastError = jobErr.Error()
+		q.pending = removeJob(q.pending, job)
+		q.dead = append(q.dead, job)
+		rec = queueRecord{Op: queueOpDead, ID: job.ID, Error: job.LastError}
+
+		q.logger.LogAttrs(context.Background(), slog.LevelError, "job moved to the dead-letter store", slog.String("queue", q.name), slog.String("id", job.ID), slog.Int("attempts", job.Attempts), slog.Any("error", jobErr))
+	default:
+		job.Attempts++
+		job.LastError = jobErr.Error()
+		job.AvailableAt = time.Now().Add(job.backoff.backoff)
+		rec = queueRecord{Op: queueOpRetry, ID: job.ID, Attempts: job.Attempts, AvailableAt: job.AvailableAt, Error: job.LastError}
+
+		q.logger.LogAttrs(context.Background(), slog.LevelWarn, "job failed, retrying", slog.String("queue", q.name), slog.String("id", job.ID), slog.Int("attempts", job.Attempts), slog.Time("retry_at", job.AvailableAt), slog.Any("error", jobErr))
+	}
+
+	if err := q.append(rec, false); err != nil {
+		q.logger.LogAttrs(context.Background(), slog.LevelError, "unable to write to the queue log", slog.String("queue", q.name), slog.Any("error", err))
+	}
+
+	q.compactIfNeeded()
+	q.signal()
+}
+
+func (q *jobQueue) info() QueueInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	info := QueueInfo{Name: q.name, Pending: len(q.pending), Dead: len(q.dead)}
+	if q.worker != nil {
+		info.Worker = q.worker.name
+	}
+	for _, job := range q.pending {
+		if job.running {
+			info.Running++
+		}
+	}
+
+	return info
+}
+
+// record returns the push record restoring the job in its current state
+func (job *QueueJob) record() queueRecord {
+	return queueRecord{
+		Op:          queueOpPush,
+		ID:          job.ID,
+		Payload:     job.rawPayload,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		CreatedAt:   job.CreatedAt,
+		AvailableAt: job.AvailableAt,
+		Error:       job.LastError,
+	}
+}
+
+// handlerParameters is what the callback passed to frankenphp_handle_request() receives
+func (job *QueueJob) handlerParameters() AssociativeArray {
+	return AssociativeArray{
+		Map: map[string]any{
+			"id":      job.ID,
+			"queue":   job.Queue,
+			"attempt": int64(job.Attempts + 1),
+			"payload": job.Payload,
+		},
+		Order: []string{"id", "queue", "attempt", "payload"},
+	}
+}
+
+func newJobBackoff(maxAttempts int) *exponentialBackoff {
+	return &exponentialBackoff{
+		minBackoff:             queueMinBackoff,
+		maxBackoff:             queueMaxBackoff,
+		maxConsecutiveFailures: maxAttempts,
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func removeJob(jobs []*QueueJob, job *QueueJob) []*QueueJob {
+	return slices.DeleteFunc(jobs, func(j *QueueJob) bool { return j == job })
+}
+
+func writeQueueRecord(w io.Writer, rec queueRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+
+	return err
+}
+
+// marshalQueuePayload converts a value returned by GoValue to a JSON tree that doesn't lose PHP types:
+// floats are wrapped in {"f": ...}, associative arrays in {"a": [[key, value], ...]} to keep their order
+// and binary strings, which aren't valid UTF-8, in {"b": base64}.
+// Integer keys are stored as numeric strings and become integers again once converted back to a PHP array.
+func marshalQueuePayload(v any) any {
+	switch v := v.(type) {
+	case string:
+		if !utf8.ValidString(v) {
+			return map[string]any{"b": base64.StdEncoding.EncodeToString([]byte(v))}
+		}
+
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return map[string]any{"f": v}
+	case map[string]any:
+		return marshalQueuePayload(AssociativeArray{Map: v})
+	case AssociativeArray:
+		keys := v.Order
+		if keys == nil {
+			keys = slices.Sorted(maps.Keys(v.Map))
+		}
+
+		entries := make([][2]any, 0, len(keys))
+		for _, k := range keys {
+			entries = append(entries, [2]any{marshalQueuePayload(k), marshalQueuePayload(v.Map[k])})
+		}
+
+		return map[string]any{"a": entries}
+	case []any:
+		values := make([]any, len(v))
+		for i, val := range v {
+			values[i] = marshalQueuePayload(val)
+		}
+
+		return values
+	default:
+		return v
+	}
+}
+
+func unmarshalQueuePayload(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return decodeQueuePayload(v)
+}
+
+func decodeQueuePayload(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.Int64()
+	case []any:
+		for i, val := range v {
+			var err error
+			if v[i], err = decodeQueuePayload(val); err != nil {
+				return nil, err
+			}
+		}
+
+		return v, nil
+	case map[string]any:
+		if f, ok := v["f"].(json.Number); ok {
+			return f.Float64()
+		}
+
+		if b, ok := v["b"].(string); ok {
+			s, err := base64.StdEncoding.DecodeString(b)
+
+			return string(s), err
+		}
+
+		entries, ok := v["a"].([]any)
+		if !ok {
+			return nil, errors.New("invalid payload")
+		}
+
+		arr := AssociativeArray{Map: make(map[string]any, len(entries)), Order: make([]string, 0, len(entries))}
+		for _, e := range entries {
+			entry, ok := e.([]any)
+			if !ok || len(entry) != 2 {
+				return nil, errors.New("invalid payload")
+			}
+			k, err := decodeQueuePayload(entry[0])
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, errors.New("invalid payload")
+			}
+
+			val, err := decodeQueuePayload(entry[1])
+			if err != nil {
+				return nil, err
+			}
+
+			arr.Map[key] = val
+			arr.Order = append(arr.Order, key)
+		}
+
+		return arr, nil
+	default:
+		return v, nil
+	}
+}
+
+// EXPERIMENTAL: Queues returns the state of all known queues
+func Queues() []QueueInfo {
+	queuesMu.Lock()
+	qs := slices.Collect(maps.Values(queues))
+	queuesMu.Unlock()
+
+	infos := make([]QueueInfo, 0, len(qs))
+	for _, q := range qs {
+		infos = append(infos, q.info())
+	}
+	slices.SortFunc(infos, func(a, b QueueInfo) int { return strings.Compare(a.Name, b.Name) })
+
+	return infos
+}
+
+// EXPERIMENTAL: DeadJobs returns the jobs of a queue that failed too many times
+func DeadJobs(queue string) ([]QueueJob, error) {
+	q, err := lookupQueue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]QueueJob, 0, len(q.dead))
+	for _, job := range q.dead {
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, nil
+}
+
+// EXPERIMENTAL: RetryDeadJobs moves the dead jobs of a queue back to the queue and returns how many were moved
+func RetryDeadJobs(queue string) (int, error) {
+	q, err := lookupQueue(queue)
+	if err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for _, job := range slices.Clone(q.dead) {
+		if err := q.append(queueRecord{Op: queueOpRevive, ID: job.ID, AvailableAt: now}, false); err != nil {
+			return n, err
+		}
+
+		job.Attempts = 0
+		job.AvailableAt = now
+		job.backoff.recordSuccess()
+		q.dead = removeJob(q.dead, job)
+		q.pending = append(q.pending, job)
+		n++
+	}
+
+	q.compactIfNeeded()
+	q.signal()
+
+	return n, nil
+}
+
+// EXPERIMENTAL: PurgeDeadJobs deletes the dead jobs of a queue and returns how many were deleted
+func PurgeDeadJobs(queue string) (int, error) {
+	q, err := lookupQueue(queue)
+	if err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := 0
+	for _, job := range slices.Clone(q.dead) {
+		if err := q.append(queueRecord{Op: queueOpPurge, ID: job.ID}, false); err != nil {
+			return n, err
+		}
+
+		q.dead = removeJob(q.dead, job)
+		n++
+	}
+
+	q.compactIfNeeded()
+
+	return n, nil
+}
+
+//export go_frankenphp_queue_push
+func go_frankenphp_queue_push(threadIndex C.uintptr_t, queue *C.zend_string, payload *C.zval, opts *C.zval) (*C.zend_string, *C.zend_string) {
+	fail := func(err error) (*C.zend_string, *C.zend_string) {
+		return nil, (*C.zend_string)(PHPString(err.Error(), false))
+	}
+
+	var (
+		delay       time.Duration
+		maxAttempts = defaultQueueMaxAttempts
+	)
+
+	if opts != nil {
+		for k, v := range GoMap(unsafe.Pointer(opts)) {
+			switch k {
+			case "delay":
+				switch d := v.(type) {
+				case int64:
+					delay = time.Duration(d) * time.Second
+				case float64:
+					delay = time.Duration(d * float64(time.Second))
+				default:
+					return fail(errors.New(`the "delay" option must be a number of seconds`))
+				}
+			case "max_attempts":
+				n, ok := v.(int64)
+				if !ok || n < 1 {
+					return fail(errors.New(`the "max_attempts" option must be a positive integer`))
+				}
+				maxAttempts = int(n)
+			default:
+				return fail(fmt.Errorf("unknown option %q", k))
+			}
+		}
+	}
+
+	q, err := getQueue(GoString(unsafe.Pointer(queue)), mainThread.server.logger)
+	if err != nil {
+		return fail(err)
+	}
+
+	id, err := q.push(GoValue(unsafe.Pointer(payload)), delay, maxAttempts)
+	if err != nil {
+		q.logger.LogAttrs(context.Background(), slog.LevelError, "unable to push the job", slog.String("queue", q.name), slog.Any("error", err))
+
+		return fail(err)
+	}
+
+	return (*C.zend_string)(PHPString(id, false)), nil
+}
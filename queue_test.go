@@ -0,0 +1,154 @@
+package frankenphp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueuePayloadRoundTrip(t *testing.T) {
+	payload := AssociativeArray{
+		Map: map[string]any{
+			"int":    int64(42),
+			"float":  float64(1),
+			"string": "foo",
+			"null":   nil,
+			"bool":   true,
+			"list":   []any{int64(1), "bar", nil, 2.5},
+			"nested": AssociativeArray{Map: map[string]any{"b": int64(2), "a": int64(1)}, Order: []string{"b", "a"}},
+			"binary": "\xff\x00\xfe",
+			"sparse": AssociativeArray{Map: map[string]any{"10": "b", "3": "a", "\xff": "binary key"}, Order: []string{"10", "3", "\xff"}},
+		},
+		Order: []string{"string", "int", "float", "null", "bool", "list", "nested", "binary", "sparse"},
+	}
+
+	raw, err := json.Marshal(marshalQueuePayload(payload))
+	require.NoError(t, err)
+
+	decoded, err := unmarshalQueuePayload(raw)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestQueue(t *testing.T) {
+	minBackoff, maxBackoff := queueMinBackoff, queueMaxBackoff
+	queueMinBackoff, queueMaxBackoff = time.Millisecond, 10*time.Millisecond
+	defer func() {
+		queueMinBackoff, queueMaxBackoff = minBackoff, maxBackoff
+	}()
+
+	cwd, _ := os.Getwd()
+	testDataDir := cwd + "/testdata/"
+	dir := t.TempDir()
+
+	start := func() {
+		require.NoError(t, Init(
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithQueueDir(dir),
+			WithWorkers("queue", testDataDir+"queue-worker.php", 1, WithWorkerQueues("jobs")),
+		))
+	}
+	push := func(query string) string {
+		req, err := NewRequestWithContext(httptest.NewRequest("GET", "http://example.com/queue-push.php?"+query, nil), WithRequestDocumentRoot(testDataDir, false))
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, ServeHTTP(w, req))
+
+		return w.Body.String()
+	}
+
+	start()
+
+	out := filepath.Join(dir, "out.json")
+	id := push("file=" + url.QueryEscape(out))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(out)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var job map[string]any
+	require.NoError(t, json.Unmarshal(b, &job))
+	assert.Equal(t, id, job["id"])
+	assert.Equal(t, "jobs", job["queue"])
+	assert.Equal(t, float64(1), job["attempt"])
+	assert.Equal(t, 1.5, job["payload"].(map[string]any)["ratio"])
+	// binary strings and integer keys survive the journal
+	assert.Equal(t, map[string]any{"binary": true, "sparse": true}, job["checks"])
+
+	failedID := push("fail=1")
+	var dead []QueueJob
+	assert.Eventually(t, func() bool {
+		dead, _ = DeadJobs("jobs")
+		return len(dead) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, failedID, dead[0].ID)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, "job failed")
+
+	Shutdown()
+
+	// the dead-letter store survives restarts
+	start()
+	defer Shutdown()
+
+	assert.Equal(t, []QueueInfo{{Name: "jobs", Worker: "queue", Dead: 1}}, Queues())
+
+	n, err := PurgeDeadJobs("jobs")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []QueueInfo{{Name: "jobs", Worker: "queue"}}, Queues())
+
+	_, err = DeadJobs("unknown")
+	assert.ErrorIs(t, err, ErrQueueNotFound)
+}
+
+func TestQueueCompactsTheLogAtRuntime(t *testing.T) {
+	minRecords := queueCompactMinRecords
+	queueCompactMinRecords = 4
+	defer func() {
+		queueCompactMinRecords = minRecords
+	}()
+
+	dir := t.TempDir()
+	queues = make(map[string]*jobQueue)
+	queueDir = dir
+	defer closeQueues()
+
+	q, err := getQueue("jobs", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	_, err = q.push("live", time.Hour, defaultQueueMaxAttempts)
+	require.NoError(t, err)
+
+	for range 20 {
+		_, err := q.push("done", 0, defaultQueueMaxAttempts)
+		require.NoError(t, err)
+
+		job, _ := q.next()
+		require.NotNil(t, job)
+		q.complete(job, nil)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "jobs.log"))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, bytes.Count(b, []byte("\n")), 2*queueCompactMinRecords+1)
+
+	// the compacted log still restores the live job
+	replayed := &jobQueue{name: "jobs"}
+	require.NoError(t, replayed.replay(filepath.Join(dir, "jobs.log")))
+	require.Len(t, replayed.pending, 1)
+	assert.Equal(t, "live", replayed.pending[0].Payload)
+}
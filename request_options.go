@@ -127,10 +127,21 @@ func WithRequestLogger(logger *slog.Logger) RequestOption {
 // WithWorkerName sets the worker that should handle the request
 func WithWorkerName(name string) RequestOption {
 	return func(o *frankenPHPContext) error {
-		if name != "" {
-			o.worker = getWorkerByName(name)
+		if s := activeServer.Load(); s != nil && name != "" {
+			o.worker = s.getWorkerByName(name)
 		}
 
 		return nil
 	}
 }
+
+// WithRequestResponseBuffering buffers the output of the script and sends it to the client once the script has finished,
+// PHP threads are then not held by slow clients. Up to maxMemory bytes are kept in memory, the rest is written to a temporary file.
+// Explicit flushes still send the buffered output to the client immediately.
+func WithRequestResponseBuffering(maxMemory int64) RequestOption {
+	return func(o *frankenPHPContext) error {
+		o.responseBufferMaxMemory = maxMemory
+
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package frankenphp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+)
+
+// bufferedResponseWriter stores the output of PHP scripts so that PHP threads are not held by slow clients.
+// The output is kept in memory up to maxMemory bytes, then spills to a temporary file.
+// Flushes write the buffered output to the client.
+//
+// It must only be used by the PHP thread until the script has finished, then by the goroutine handling the request.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+
+	maxMemory int64
+	status    int
+	// headerWritten is true once the status code has been sent to the client
+	headerWritten bool
+	memory        bytes.Buffer
+	file          *os.File
+	// passthrough is true once the buffer has been drained, the next writes are sent directly to the client
+	passthrough bool
+}
+
+// writerOnly hides the ReadFrom method of the buffered writer to prevent infinite recursion
+type writerOnly struct {
+	io.Writer
+}
+
+func newBufferedResponseWriter(w http.ResponseWriter, maxMemory int64) *bufferedResponseWriter {
+	return &bufferedResponseWriter{ResponseWriter: w, maxMemory: maxMemory}
+}
+
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	// informational responses such as Early Hints are useless if delayed
+	if w.passthrough || (statusCode >= 100 && statusCode < 200) {
+		w.ResponseWriter.WriteHeader(statusCode)
+
+		return
+	}
+
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if w.file == nil && int64(w.memory.Len()+len(p)) > w.maxMemory {
+		f, err := os.CreateTemp("", "frankenphp-response-")
+		if err != nil {
+			return 0, err
+		}
+		w.file = f
+
+		if _, err := w.memory.WriteTo(f); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.file != nil {
+		return w.file.Write(p)
+	}
+
+	return w.memory.Write(p)
+}
+
+func (w *bufferedResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok && w.passthrough {
+		return rf.ReadFrom(r)
+	}
+
+	return io.Copy(writerOnly{w}, r)
+}
+
+// FlushError sends the buffered output to the client and flushes it
+func (w *bufferedResponseWriter) FlushError() error {
+	if err := w.drain(); err != nil {
+		return err
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *bufferedResponseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *bufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// drain sends the buffered output to the client
+func (w *bufferedResponseWriter) drain() error {
+	if w.status != 0 && !w.headerWritten {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.headerWritten = true
+	}
+
+	if w.file != nil {
+		defer w.removeFile()
+
+		if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		_, err := io.Copy(w.ResponseWriter, w.file)
+
+		return err
+	}
+
+	_, err := w.memory.WriteTo(w.ResponseWriter)
+
+	return err
+}
+
+// finish sends the remaining output once the script has finished, and switches to passthrough mode
+func (w *bufferedResponseWriter) finish() error {
+	err := w.drain()
+	w.passthrough = true
+
+	return err
+}
+
+func (w *bufferedResponseWriter) removeFile() {
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+	w.file = nil
+}
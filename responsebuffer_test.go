@@ -0,0 +1,100 @@
+package frankenphp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedResponseWriterKeepsSmallResponsesInMemory(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newBufferedResponseWriter(rec, 10)
+
+	w.Header().Set("Foo", "bar")
+	w.WriteHeader(http.StatusCreated)
+	_, err := w.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	assert.Nil(t, w.file)
+	assert.Empty(t, rec.Body.String())
+	assert.False(t, rec.Flushed)
+
+	require.NoError(t, w.finish())
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "bar", rec.Result().Header.Get("Foo"))
+	assert.Equal(t, "hello", rec.Body.String())
+
+	_, err = w.Write([]byte(" world"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", rec.Body.String())
+}
+
+func TestBufferedResponseWriterSpillsToDisk(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newBufferedResponseWriter(rec, 10)
+
+	for range 10 {
+		_, err := w.Write([]byte("hello"))
+		require.NoError(t, err)
+	}
+
+	require.NotNil(t, w.file)
+	name := w.file.Name()
+	assert.Empty(t, rec.Body.String())
+
+	require.NoError(t, w.finish())
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, strings.Repeat("hello", 10), rec.Body.String())
+	assert.Nil(t, w.file)
+
+	_, err := os.Stat(name)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBufferedResponseWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newBufferedResponseWriter(rec, 10)
+
+	_, _ = w.Write([]byte("He"))
+	require.NoError(t, http.NewResponseController(w).Flush())
+
+	assert.Equal(t, "He", rec.Body.String())
+	assert.True(t, rec.Flushed)
+
+	_, _ = w.Write([]byte("llo"))
+	assert.Equal(t, "He", rec.Body.String())
+
+	require.NoError(t, w.finish())
+	assert.Equal(t, "Hello", rec.Body.String())
+}
+
+type statusCodesRecorder struct {
+	*httptest.ResponseRecorder
+	codes []int
+}
+
+func (r *statusCodesRecorder) WriteHeader(code int) {
+	r.codes = append(r.codes, code)
+	r.ResponseRecorder.WriteHeader(code)
+}
+
+func TestBufferedResponseWriterSendsInformationalResponsesImmediately(t *testing.T) {
+	rec := &statusCodesRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := newBufferedResponseWriter(rec, 10)
+
+	w.WriteHeader(http.StatusEarlyHints)
+	assert.Equal(t, []int{http.StatusEarlyHints}, rec.codes)
+
+	w.WriteHeader(http.StatusNotFound)
+	assert.Equal(t, []int{http.StatusEarlyHints}, rec.codes)
+
+	require.NoError(t, w.finish())
+	assert.Equal(t, []int{http.StatusEarlyHints, http.StatusNotFound}, rec.codes)
+}
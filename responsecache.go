@@ -0,0 +1,575 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// defaultResponseCacheMaxSize is the default approximate size limit of the response cache
+const defaultResponseCacheMaxSize = 64 << 20
+
+// responseCacheStatus is the name of the cache in the Cache-Status header (RFC 9211)
+const responseCacheStatus = "FrankenPHP"
+
+var (
+	responseCacheStore = newResponseCache(defaultResponseCacheMaxSize, "")
+	// responseCacheRevalidations tracks the requests refreshing stale entries in the background
+	responseCacheRevalidations sync.WaitGroup
+)
+
+// cacheableStatusCodes are the status codes that can be stored, see RFC 9110, section 15.1
+var cacheableStatusCodes = map[int]struct{}{
+	http.StatusOK:                   {},
+	http.StatusNonAuthoritativeInfo: {},
+	http.StatusNoContent:            {},
+	http.StatusMultipleChoices:      {},
+	http.StatusMovedPermanently:     {},
+	http.StatusPermanentRedirect:    {},
+	http.StatusNotFound:             {},
+	http.StatusMethodNotAllowed:     {},
+	http.StatusGone:                 {},
+	http.StatusRequestURITooLong:    {},
+	http.StatusNotImplemented:       {},
+}
+
+// responseCache stores full responses generated by PHP scripts, least recently used entries are evicted when it is full.
+// Bodies are stored in memory, or in files if a directory is set.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	tags    map[string]map[*list.Element]struct{}
+	size    int64
+	maxSize int64
+	dir     string
+}
+
+type responseCacheEntry struct {
+	key    string
+	status int
+	header http.Header
+	// body is nil if the body is stored in file
+	body     []byte
+	file     string
+	size     int64
+	storedAt time.Time
+	// ttl is the duration during which the entry is fresh
+	ttl time.Duration
+	// staleWhileRevalidate is the duration during which the stale entry can be served while it is refreshed
+	staleWhileRevalidate time.Duration
+	tags                 []string
+	revalidating         atomic.Bool
+}
+
+func newResponseCache(maxSize int64, dir string) *responseCache {
+	return &responseCache{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		tags:    make(map[string]map[*list.Element]struct{}),
+		maxSize: maxSize,
+		dir:     dir,
+	}
+}
+
+// WithRequestResponseCache stores the responses of the request in the response cache,
+// using the method, the URL and the values of the given request headers as key.
+// Responses are stored according to the Cache-Control header set by the script.
+func WithRequestResponseCache(vary []string) RequestOption {
+	return func(o *frankenPHPContext) error {
+		o.cacheResponse = true
+		o.responseCacheVary = vary
+
+		return nil
+	}
+}
+
+// EXPERIMENTAL: PurgeResponseCache removes the cached responses having one of the given tags,
+// or all cached responses if no tags are given. It returns the number of removed responses.
+func PurgeResponseCache(tags ...string) int {
+	return responseCacheStore.purge(tags...)
+}
+
+// get returns the entry and whether it is stale, expired entries are removed
+func (c *responseCache) get(key string) (*responseCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*responseCacheEntry)
+	age := time.Since(entry.storedAt)
+	if age >= entry.ttl+entry.staleWhileRevalidate {
+		c.remove(e)
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(e)
+
+	return entry, age >= entry.ttl
+}
+
+// set stores the entry, replacing the previous entry with the same key
+func (c *responseCache) set(entry *responseCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[entry.key]; ok {
+		c.remove(e)
+	}
+
+	e := c.lru.PushFront(entry)
+	c.entries[entry.key] = e
+	c.size += entry.size
+	for _, tag := range entry.tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[*list.Element]struct{})
+		}
+		c.tags[tag][e] = struct{}{}
+	}
+
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *responseCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
+
+func (c *responseCache) purge(tags ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(tags) == 0 {
+		n := len(c.entries)
+		for c.lru.Len() > 0 {
+			c.remove(c.lru.Back())
+		}
+
+		return n
+	}
+
+	n := 0
+	for _, tag := range tags {
+		for e := range c.tags[tag] {
+			c.remove(e)
+			n++
+		}
+	}
+
+	return n
+}
+
+// remove must be called with mu locked
+func (c *responseCache) remove(e *list.Element) {
+	entry := e.Value.(*responseCacheEntry)
+
+	c.lru.Remove(e)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], e)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+
+	// responses being sent keep their file descriptor open
+	if entry.file != "" {
+		_ = os.Remove(entry.file)
+	}
+}
+
+// newEntry creates an entry, the body is written to a file if the cache has a directory
+func (c *responseCache) newEntry(key string, status int, header http.Header, body []byte) (*responseCacheEntry, error) {
+	entry := &responseCacheEntry{key: key, status: status, header: header, storedAt: time.Now()}
+
+	entry.size = int64(len(body))
+	for k, values := range header {
+		entry.size += int64(len(k))
+		for _, v := range values {
+			entry.size += int64(len(v))
+		}
+	}
+
+	if c.dir == "" {
+		entry.body = body
+
+		return entry, nil
+	}
+
+	f, err := os.CreateTemp(c.dir, "response-")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(body); err != nil {
+		_ = os.Remove(f.Name())
+
+		return nil, err
+	}
+	entry.file = f.Name()
+
+	return entry, nil
+}
+
+// responseCacheKey returns the key of the request, HEAD requests share the entries of GET requests
+func (fc *frankenPHPContext) responseCacheKey() string {
+	return fc.requestFingerprint(http.MethodGet, fc.responseCacheVary)
+}
+
+// requestFingerprint identifies the requests having the same method, URL and values for the given headers
+func (fc *frankenPHPContext) requestFingerprint(method string, vary []string) string {
+	// the request may have been rewritten, for instance to index.php
+	r := fc.originalRequest
+	if r == nil {
+		r = fc.request
+	}
+
+	var b strings.Builder
+
+	b.WriteString(method)
+	b.WriteByte(0)
+	b.WriteString(r.Host)
+
+	requestURI := r.RequestURI
+	if requestURI == "" {
+		requestURI = r.URL.RequestURI()
+	}
+	b.WriteString(requestURI)
+
+	for _, name := range vary {
+		b.WriteByte(0)
+		b.WriteString(http.CanonicalHeaderKey(name))
+		b.WriteByte('=')
+		b.WriteString(strings.Join(fc.request.Header.Values(name), ", "))
+	}
+
+	return b.String()
+}
+
+// isResponseCacheable checks if the request can be served from the cache
+func (fc *frankenPHPContext) isResponseCacheable() bool {
+	if fc.request.Method != http.MethodGet && fc.request.Method != http.MethodHead {
+		return false
+	}
+
+	// responses to authenticated requests are private
+	return fc.request.Header.Get("Authorization") == ""
+}
+
+// serveCachedResponse writes the cached response if any, stale responses are refreshed in the background
+func (fc *frankenPHPContext) serveCachedResponse() bool {
+	key := fc.responseCacheKey()
+
+	entry, stale := responseCacheStore.get(key)
+	if entry == nil {
+		return false
+	}
+
+	var body io.Reader = bytes.NewReader(entry.body)
+	if entry.file != "" {
+		f, err := os.Open(entry.file)
+		if err != nil {
+			// the entry has been evicted in the meantime
+			return false
+		}
+		defer f.Close()
+
+		body = f
+	}
+
+	age := time.Since(entry.storedAt)
+
+	h := fc.responseWriter.Header()
+	for k, v := range entry.header {
+		h[k] = slices.Clone(v)
+	}
+	h.Set("Age", strconv.Itoa(int(age.Seconds())))
+	h.Set("Cache-Status", responseCacheStatus+"; hit; ttl="+strconv.Itoa(int((entry.ttl-age).Seconds())))
+
+	fc.responseWriter.WriteHeader(entry.status)
+	if fc.request.Method != http.MethodHead {
+		if _, err := io.Copy(fc.responseWriter, body); err != nil {
+			fc.logger.LogAttrs(context.Background(), slog.LevelWarn, "write error", slog.Any("error", err))
+		}
+	}
+
+	if stale && entry.revalidating.CompareAndSwap(false, true) {
+		fc.revalidateCachedResponse(key)
+	}
+
+	return true
+}
+
+// revalidateCachedResponse handles a copy of the request in the background to refresh the cached response
+func (fc *frankenPHPContext) revalidateCachedResponse(key string) {
+	r := fc.request.Clone(context.Background())
+	r.Method = http.MethodGet
+	r.Body = http.NoBody
+
+	rfc := newFrankenPHPContext()
+	rfc.documentRoot = fc.documentRoot
+	rfc.splitPath = fc.splitPath
+	rfc.env = fc.env
+	rfc.logger = fc.logger
+	rfc.server = fc.server
+	rfc.request = r
+	rfc.originalRequest = fc.originalRequest
+	rfc.worker = fc.worker
+	rfc.docURI = fc.docURI
+	rfc.pathInfo = fc.pathInfo
+	rfc.scriptName = fc.scriptName
+	rfc.scriptFilename = fc.scriptFilename
+	rfc.cacheResponse = true
+	rfc.responseCacheVary = fc.responseCacheVary
+	rfc.headless = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rfc.ctx = context.WithValue(ctx, contextKey, rfc)
+	rfc.cancel = cancel
+
+	recorder := newResponseCacheRecorder(&headlessResponseWriter{header: make(http.Header)})
+	rfc.responseWriter = recorder
+
+	responseCacheRevalidations.Add(1)
+	go func() {
+		defer responseCacheRevalidations.Done()
+
+		fc.server.dispatch(rfc)
+		rfc.serveHandedOffResponse()
+		rfc.storeResponse(key, recorder)
+	}()
+}
+
+// storeResponse stores the recorded response if its Cache-Control header allows it
+func (fc *frankenPHPContext) storeResponse(key string, recorder *responseCacheRecorder) {
+	if fc.request.Method != http.MethodGet {
+		return
+	}
+
+	ttl, staleWhileRevalidate, ok := responseCacheLifetime(recorder.status, recorder.header)
+	if !ok || !isVaryKeyed(recorder.header, fc.responseCacheVary) || recorder.overflow || fc.stream != nil || fc.sentFile != nil || len(fc.trailers) != 0 || fc.clientHasClosed() {
+		responseCacheStore.delete(key)
+
+		return
+	}
+
+	entry, err := responseCacheStore.newEntry(key, recorder.status, recorder.header, recorder.body.Bytes())
+	if err != nil {
+		fc.logger.LogAttrs(context.Background(), slog.LevelError, "unable to store the response in the cache", slog.Any("error", err))
+
+		return
+	}
+
+	entry.ttl = ttl
+	entry.staleWhileRevalidate = staleWhileRevalidate
+	entry.tags = append(recorder.tags, fc.responseCacheTags...)
+
+	responseCacheStore.set(entry)
+}
+
+// isVaryKeyed checks that the request headers listed in the Vary header of the response are part of the key
+// built from the vary request headers, otherwise the response negotiated for a client could be served to clients sending other values
+func isVaryKeyed(header http.Header, vary []string) bool {
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			if !slices.ContainsFunc(vary, func(h string) bool { return strings.EqualFold(h, name) }) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// responseCacheLifetime parses the Cache-Control header of a response, it returns false if the response must not be stored
+func responseCacheLifetime(status int, header http.Header) (ttl time.Duration, staleWhileRevalidate time.Duration, ok bool) {
+	if _, cacheable := cacheableStatusCodes[status]; !cacheable {
+		return 0, 0, false
+	}
+
+	if header.Get("Set-Cookie") != "" || header.Get("Vary") == "*" {
+		return 0, 0, false
+	}
+
+	var maxAge, sMaxAge time.Duration = -1, -1
+	for _, directive := range strings.Split(strings.Join(header.Values("Cache-Control"), ","), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return 0, 0, false
+		case "max-age":
+			maxAge = parseDeltaSeconds(value)
+		case "s-maxage":
+			sMaxAge = parseDeltaSeconds(value)
+		case "stale-while-revalidate":
+			staleWhileRevalidate = max(parseDeltaSeconds(value), 0)
+		}
+	}
+
+	ttl = maxAge
+	if sMaxAge >= 0 {
+		ttl = sMaxAge
+	}
+
+	return ttl, staleWhileRevalidate, ttl > 0
+}
+
+// parseDeltaSeconds returns -1 if the value is invalid
+func parseDeltaSeconds(value string) time.Duration {
+	n, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || n < 0 {
+		return -1
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// drainResponseCache waits for the background revalidations
+func drainResponseCache() {
+	responseCacheRevalidations.Wait()
+}
+
+// responseCacheRecorder sends the response to the client while recording it
+type responseCacheRecorder struct {
+	http.ResponseWriter
+
+	status   int
+	header   http.Header
+	tags     []string
+	body     bytes.Buffer
+	overflow bool
+}
+
+func newResponseCacheRecorder(w http.ResponseWriter) *responseCacheRecorder {
+	return &responseCacheRecorder{ResponseWriter: w}
+}
+
+func (w *responseCacheRecorder) WriteHeader(statusCode int) {
+	if w.status != 0 || (statusCode >= 100 && statusCode < 200) {
+		w.ResponseWriter.WriteHeader(statusCode)
+
+		return
+	}
+
+	w.status = statusCode
+
+	h := w.ResponseWriter.Header()
+	for _, v := range h.Values("Cache-Tag") {
+		for tag := range strings.SplitSeq(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				w.tags = append(w.tags, tag)
+			}
+		}
+	}
+	h.Del("Cache-Tag")
+
+	w.header = h.Clone()
+	h.Set("Cache-Status", responseCacheStatus+"; fwd=uri-miss")
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseCacheRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.overflow {
+		if int64(w.body.Len()+len(p)) > responseCacheStore.maxSize {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(p)
+		}
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseCacheRecorder) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseCacheRecorder) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *responseCacheRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// headlessResponseWriter discards the responses generated in the background
+type headlessResponseWriter struct {
+	header http.Header
+}
+
+func (w *headlessResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *headlessResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *headlessResponseWriter) WriteHeader(int) {}
+
+func (w *headlessResponseWriter) Flush() {}
+
+//export go_frankenphp_response_cache_tag
+func go_frankenphp_response_cache_tag(threadIndex C.uintptr_t, tag *C.zend_string) {
+	fc := phpThreads[threadIndex].getRequestContext()
+	if fc == nil || fc.isDone {
+		return
+	}
+
+	if t := GoString(unsafe.Pointer(tag)); t != "" {
+		fc.responseCacheTags = append(fc.responseCacheTags, t)
+	}
+}
+
+// go_frankenphp_response_cache_purge purges all responses if tag is NULL
+//
+//export go_frankenphp_response_cache_purge
+func go_frankenphp_response_cache_purge(tag *C.zend_string) C.zend_long {
+	if tag == nil {
+		return C.zend_long(PurgeResponseCache())
+	}
+
+	return C.zend_long(PurgeResponseCache(GoString(unsafe.Pointer(tag))))
+}
@@ -0,0 +1,128 @@
+package frankenphp
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCacheLifetime(t *testing.T) {
+	for _, tc := range []struct {
+		status               int
+		header               http.Header
+		ttl                  time.Duration
+		staleWhileRevalidate time.Duration
+		cacheable            bool
+	}{
+		{http.StatusOK, http.Header{"Cache-Control": {"public, max-age=60"}}, time.Minute, 0, true},
+		{http.StatusOK, http.Header{"Cache-Control": {"max-age=60, s-maxage=10"}}, 10 * time.Second, 0, true},
+		{http.StatusNotFound, http.Header{"Cache-Control": {"max-age=60", "stale-while-revalidate=30"}}, time.Minute, 30 * time.Second, true},
+		{http.StatusOK, http.Header{}, 0, 0, false},
+		{http.StatusOK, http.Header{"Cache-Control": {"max-age=0"}}, 0, 0, false},
+		{http.StatusOK, http.Header{"Cache-Control": {"private, max-age=60"}}, 0, 0, false},
+		{http.StatusOK, http.Header{"Cache-Control": {"no-store"}}, 0, 0, false},
+		{http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}, "Set-Cookie": {"foo=bar"}}, 0, 0, false},
+		{http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"*"}}, 0, 0, false},
+		{http.StatusInternalServerError, http.Header{"Cache-Control": {"max-age=60"}}, 0, 0, false},
+	} {
+		ttl, staleWhileRevalidate, cacheable := responseCacheLifetime(tc.status, tc.header)
+
+		assert.Equal(t, tc.cacheable, cacheable, tc.header)
+		if tc.cacheable {
+			assert.Equal(t, tc.ttl, ttl)
+			assert.Equal(t, tc.staleWhileRevalidate, staleWhileRevalidate)
+		}
+	}
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	vary := []string{"Accept-Language", "X-Tenant"}
+
+	assert.True(t, isVaryKeyed(http.Header{}, vary))
+	assert.True(t, isVaryKeyed(http.Header{"Vary": {"accept-language, X-Tenant"}}, vary))
+	assert.False(t, isVaryKeyed(http.Header{"Vary": {"Accept-Language", "Accept-Encoding"}}, vary))
+	assert.False(t, isVaryKeyed(http.Header{"Vary": {"*"}}, vary))
+}
+
+func newTestResponseCacheEntry(t *testing.T, c *responseCache, key string, body string, ttl time.Duration, tags ...string) *responseCacheEntry {
+	entry, err := c.newEntry(key, http.StatusOK, http.Header{}, []byte(body))
+	require.NoError(t, err)
+
+	entry.ttl = ttl
+	entry.tags = tags
+
+	return entry
+}
+
+func TestResponseCacheStaleEntries(t *testing.T) {
+	c := newResponseCache(1024, "")
+
+	entry := newTestResponseCacheEntry(t, c, "key", "body", time.Minute)
+	entry.staleWhileRevalidate = time.Minute
+	c.set(entry)
+
+	e, stale := c.get("key")
+	assert.Same(t, entry, e)
+	assert.False(t, stale)
+
+	entry.storedAt = time.Now().Add(-90 * time.Second)
+	e, stale = c.get("key")
+	assert.Same(t, entry, e)
+	assert.True(t, stale)
+
+	entry.storedAt = time.Now().Add(-3 * time.Minute)
+	e, _ = c.get("key")
+	assert.Nil(t, e)
+	assert.Empty(t, c.entries)
+}
+
+func TestResponseCachePurge(t *testing.T) {
+	c := newResponseCache(1024, "")
+
+	c.set(newTestResponseCacheEntry(t, c, "a", "a", time.Minute, "foo", "bar"))
+	c.set(newTestResponseCacheEntry(t, c, "b", "b", time.Minute, "bar"))
+	c.set(newTestResponseCacheEntry(t, c, "c", "c", time.Minute))
+
+	assert.Equal(t, 2, c.purge("bar", "foo"))
+	assert.Len(t, c.entries, 1)
+	assert.Empty(t, c.tags)
+
+	assert.Equal(t, 1, c.purge())
+	assert.Empty(t, c.entries)
+	assert.Zero(t, c.size)
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsedEntries(t *testing.T) {
+	c := newResponseCache(10, "")
+
+	c.set(newTestResponseCacheEntry(t, c, "a", "aaaa", time.Minute))
+	c.set(newTestResponseCacheEntry(t, c, "b", "bbbb", time.Minute))
+	c.get("a")
+	c.set(newTestResponseCacheEntry(t, c, "c", "cccc", time.Minute))
+
+	assert.Contains(t, c.entries, "a")
+	assert.NotContains(t, c.entries, "b")
+	assert.Contains(t, c.entries, "c")
+}
+
+func TestResponseCacheOnDisk(t *testing.T) {
+	c := newResponseCache(1024, t.TempDir())
+
+	entry := newTestResponseCacheEntry(t, c, "key", "body", time.Minute)
+	require.NotEmpty(t, entry.file)
+	assert.Nil(t, entry.body)
+	c.set(entry)
+
+	body, err := os.ReadFile(entry.file)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+
+	c.purge()
+
+	_, err = os.Stat(entry.file)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,287 @@
+package frankenphp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dunglas/frankenphp/internal/cron"
+	"github.com/dunglas/frankenphp/internal/fastabs"
+)
+
+// scheduledTask runs a script on a regular thread or calls a worker according to a cron expression
+type scheduledTask struct {
+	spec     string
+	schedule *cron.Schedule
+	fileName string
+	worker   *worker
+	server   *Server
+
+	mu           sync.Mutex
+	running      bool
+	nextRun      time.Time
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastError    error
+	skipped      int
+}
+
+// EXPERIMENTAL: ScheduledTaskState is the status of a task started with WithScheduledScript or WithScheduledWorker
+type ScheduledTaskState struct {
+	Spec                     string    `json:"spec"`
+	Script                   string    `json:"script,omitempty"`
+	Worker                   string    `json:"worker,omitempty"`
+	Running                  bool      `json:"running"`
+	NextRun                  time.Time `json:"next_run,omitzero"`
+	LastRun                  time.Time `json:"last_run,omitzero"`
+	LastDurationMilliseconds int64     `json:"last_duration_ms"`
+	LastError                string    `json:"last_error,omitempty"`
+	Skipped                  int       `json:"skipped"`
+}
+
+var (
+	scheduledTasks []*scheduledTask
+	cancelSchedule context.CancelFunc
+	scheduleWg     *sync.WaitGroup
+
+	// scheduleRunCtx is passed to the running tasks, it is cancelled when they take too long to finish while draining
+	scheduleRunCtx    context.Context
+	cancelScheduleRun context.CancelFunc
+
+	// scheduleDrainTimeout is how long draining waits for the running tasks before cancelling them
+	scheduleDrainTimeout = 10 * time.Second
+)
+
+func (s *Server) initSchedule(opt []scheduleOpt) error {
+	scheduledTasks = make([]*scheduledTask, 0, len(opt))
+
+	for _, o := range opt {
+		t := &scheduledTask{spec: o.spec, schedule: o.schedule, server: s}
+
+		if o.worker != "" {
+			if t.worker = s.getWorkerByName(o.worker); t.worker == nil {
+				return fmt.Errorf("%w: %q", ErrWorkerNotFound, o.worker)
+			}
+		} else {
+			var err error
+			if t.fileName, err = fastabs.FastAbs(o.fileName); err != nil {
+				return fmt.Errorf("scheduled script filename is invalid %q: %w", o.fileName, err)
+			}
+		}
+
+		scheduledTasks = append(scheduledTasks, t)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelSchedule = cancel
+	scheduleRunCtx, cancelScheduleRun = context.WithCancel(context.Background())
+	scheduleWg = &sync.WaitGroup{}
+
+	for _, t := range scheduledTasks {
+		scheduleWg.Add(1)
+		go func() {
+			defer scheduleWg.Done()
+			t.loop(ctx, scheduleRunCtx, scheduleWg)
+		}()
+	}
+
+	return nil
+}
+
+// drainSchedule stops starting tasks and waits for the running ones to finish.
+// Tasks still running after scheduleDrainTimeout are cancelled and left behind:
+// calls to workers return immediately, scripts are aborted at their next output.
+func (s *Server) drainSchedule() {
+	if cancelSchedule == nil {
+		return
+	}
+
+	cancelSchedule()
+	cancelSchedule = nil
+	defer cancelScheduleRun()
+
+	done := make(chan struct{})
+	go func() {
+		scheduleWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(scheduleDrainTimeout):
+		s.logger.LogAttrs(context.Background(), slog.LevelWarn, "scheduled tasks are still running, cancelling them", slog.Duration("timeout", scheduleDrainTimeout))
+	}
+}
+
+func (t *scheduledTask) loop(ctx, runCtx context.Context, wg *sync.WaitGroup) {
+	for {
+		next, err := t.schedule.Next(time.Now())
+		if err != nil {
+			t.server.logger.LogAttrs(ctx, slog.LevelError, "scheduled task will never run", slog.String("schedule", t.spec), slog.Any("error", err))
+
+			return
+		}
+
+		t.mu.Lock()
+		t.nextRun = next
+		t.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+
+		t.mu.Lock()
+		if t.running {
+			t.skipped++
+			t.mu.Unlock()
+
+			t.server.logger.LogAttrs(ctx, slog.LevelWarn, "previous run of the scheduled task is still running, skipping", slog.String("schedule", t.spec), slog.String("task", t.name()))
+
+			continue
+		}
+		t.running = true
+		t.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.run(runCtx, next)
+		}()
+	}
+}
+
+func (t *scheduledTask) run(ctx context.Context, scheduledAt time.Time) {
+	start := time.Now()
+
+	var err error
+	if t.worker != nil {
+		err = t.callWorker(ctx, scheduledAt)
+	} else {
+		err = t.runScript(ctx)
+	}
+
+	duration := time.Since(start)
+
+	t.mu.Lock()
+	t.running = false
+	t.lastRun = start
+	t.lastDuration = duration
+	t.lastError = err
+	t.mu.Unlock()
+
+	if err != nil {
+		t.server.logger.LogAttrs(context.Background(), slog.LevelError, "scheduled task failed", slog.String("schedule", t.spec), slog.String("task", t.name()), slog.Any("error", err))
+
+		return
+	}
+
+	t.server.logger.LogAttrs(context.Background(), slog.LevelDebug, "scheduled task finished", slog.String("schedule", t.spec), slog.String("task", t.name()), slog.Duration("duration", duration))
+}
+
+// callWorker passes the schedule and the activation time to the callback of the worker
+func (t *scheduledTask) callWorker(ctx context.Context, scheduledAt time.Time) error {
+	_, err := t.server.Call(ctx, t.worker.name, AssociativeArray{
+		Map:   map[string]any{"schedule": t.spec, "scheduled_at": scheduledAt.Unix()},
+		Order: []string{"schedule", "scheduled_at"},
+	})
+
+	return err
+}
+
+// runScript executes the script on a regular thread as if it was requested with a GET request
+func (t *scheduledTask) runScript(ctx context.Context) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/"+filepath.Base(t.fileName), nil)
+	if err != nil {
+		return err
+	}
+
+	fr, err := NewRequestWithContext(r, WithRequestDocumentRoot(filepath.Dir(t.fileName), false))
+	if err != nil {
+		return err
+	}
+
+	rw := &scheduleResponseWriter{header: http.Header{}, status: http.StatusOK}
+	if err := t.server.ServeHTTP(rw, fr); err != nil {
+		return err
+	}
+
+	if rw.body.Len() > 0 {
+		t.server.logger.LogAttrs(context.Background(), slog.LevelDebug, "scheduled script output", slog.String("task", t.name()), slog.String("output", rw.body.String()))
+	}
+
+	if rw.status >= http.StatusInternalServerError {
+		return fmt.Errorf("script returned status %d", rw.status)
+	}
+
+	return nil
+}
+
+func (t *scheduledTask) name() string {
+	if t.worker != nil {
+		return t.worker.name
+	}
+
+	return t.fileName
+}
+
+func (t *scheduledTask) state() ScheduledTaskState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := ScheduledTaskState{
+		Spec:                     t.spec,
+		Script:                   t.fileName,
+		Running:                  t.running,
+		NextRun:                  t.nextRun,
+		LastRun:                  t.lastRun,
+		LastDurationMilliseconds: t.lastDuration.Milliseconds(),
+		Skipped:                  t.skipped,
+	}
+	if t.worker != nil {
+		s.Worker = t.worker.name
+	}
+	if t.lastError != nil {
+		s.LastError = t.lastError.Error()
+	}
+
+	return s
+}
+
+// EXPERIMENTAL: ScheduledTasks returns the state of the scheduled tasks
+func ScheduledTasks() []ScheduledTaskState {
+	states := make([]ScheduledTaskState, 0, len(scheduledTasks))
+	for _, t := range scheduledTasks {
+		states = append(states, t.state())
+	}
+
+	return states
+}
+
+// scheduleResponseWriter collects the response of a scheduled script
+type scheduleResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *scheduleResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *scheduleResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *scheduleResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
@@ -0,0 +1,88 @@
+package frankenphp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTasks(t *testing.T) {
+	cwd, _ := os.Getwd()
+	testDataDir := cwd + "/testdata/"
+
+	require.NoError(t, Init(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithWorkers("call", testDataDir+"worker-call.php", 1),
+		WithScheduledScript("*/5 * * * *", testDataDir+"hello.php"),
+		WithScheduledWorker("@daily", "call"),
+	))
+	defer Shutdown()
+
+	states := ScheduledTasks()
+	require.Len(t, states, 2)
+	assert.Equal(t, testDataDir+"hello.php", states[0].Script)
+	assert.Equal(t, "call", states[1].Worker)
+	assert.Eventually(t, func() bool {
+		return !ScheduledTasks()[0].NextRun.IsZero()
+	}, time.Second, 10*time.Millisecond)
+	assert.Zero(t, ScheduledTasks()[0].NextRun.Minute()%5)
+
+	for _, task := range scheduledTasks {
+		task.running = true
+		task.run(context.Background(), time.Now())
+	}
+
+	for _, state := range ScheduledTasks() {
+		assert.False(t, state.Running)
+		assert.False(t, state.LastRun.IsZero())
+		assert.Empty(t, state.LastError)
+	}
+}
+
+func TestScheduledWorkerMustExist(t *testing.T) {
+	err := Init(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithScheduledWorker("@daily", "unknown"),
+	)
+	defer Shutdown()
+
+	assert.ErrorIs(t, err, ErrWorkerNotFound)
+}
+
+func TestScheduleRejectsInvalidExpressions(t *testing.T) {
+	assert.Error(t, WithScheduledScript("* * *", "index.php")(&opt{}))
+}
+
+func TestDrainScheduleCancelsLongRunningTasks(t *testing.T) {
+	drainTimeout := scheduleDrainTimeout
+	scheduleDrainTimeout = 50 * time.Millisecond
+	defer func() {
+		scheduleDrainTimeout = drainTimeout
+	}()
+
+	require.NoError(t, Init(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithScheduledScript("@daily", "testdata/hello.php"),
+	))
+	defer Shutdown()
+
+	// a task that only stops once it is cancelled
+	runCtx := scheduleRunCtx
+	scheduleWg.Add(1)
+	go func() {
+		defer scheduleWg.Done()
+		<-runCtx.Done()
+	}()
+
+	start := time.Now()
+	activeServer.Load().drainSchedule()
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, runCtx.Err(), context.Canceled)
+}
@@ -0,0 +1,176 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+var (
+	errSendFileNotAllowed = errors.New("the file is not in a directory allowed to be sent")
+	errSendFileDirectory  = errors.New("directories cannot be sent")
+)
+
+// sentFile is a file served by Go after the PHP script has finished handling the request
+type sentFile struct {
+	file *os.File
+	// name is used to detect the content type and for the Content-Disposition header
+	name string
+	// attachment is true if the browser must download the file
+	attachment bool
+}
+
+// WithRequestSendFileDirs sets the directories containing the files that can be sent with frankenphp_send_file().
+// Sending files is disabled if no directories are set.
+func WithRequestSendFileDirs(dirs []string) RequestOption {
+	return func(o *frankenPHPContext) error {
+		o.sendFileDirs = dirs
+
+		return nil
+	}
+}
+
+// openFileToSend opens the file if it is in one of the allowed directories, symbolic links are resolved before the check
+func (fc *frankenPHPContext) openFileToSend(path string) (*os.File, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := false
+	for _, dir := range fc.sendFileDirs {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+
+		if rel, err := filepath.Rel(realDir, realPath); err == nil && filepath.IsLocal(rel) {
+			allowed = true
+
+			break
+		}
+	}
+
+	if !allowed {
+		return nil, errSendFileNotAllowed
+	}
+
+	f, err := os.Open(realPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi, err := f.Stat(); err != nil || fi.IsDir() {
+		_ = f.Close()
+		if err == nil {
+			err = errSendFileDirectory
+		}
+
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// serveSentFile sends the file with support for range and conditional requests
+func (fc *frankenPHPContext) serveSentFile() {
+	f := fc.sentFile
+	defer f.file.Close()
+
+	fi, err := f.file.Stat()
+	if err != nil {
+		fc.logger.LogAttrs(context.Background(), slog.LevelError, "unable to send the file", slog.String("file", f.file.Name()), slog.Any("error", err))
+		http.Error(fc.responseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	h := fc.responseWriter.Header()
+	if f.attachment {
+		// FormatMediaType returns an empty string on failure
+		if cd := mime.FormatMediaType("attachment", map[string]string{"filename": f.name}); cd != "" {
+			h.Set("Content-Disposition", cd)
+		} else {
+			h.Set("Content-Disposition", "attachment")
+		}
+	}
+
+	// ServeContent handles If-None-Match only if an ETag is set, keep the one set by the script if any
+	if h.Get("ETag") == "" {
+		h.Set("ETag", fmt.Sprintf(`W/"%x-%x"`, fi.Size(), fi.ModTime().UnixNano()))
+	}
+
+	http.ServeContent(fc.responseWriter, fc.request, f.name, fi.ModTime(), f.file)
+}
+
+//export go_frankenphp_send_file
+func go_frankenphp_send_file(threadIndex C.uintptr_t, path *C.char, opts *C.zval, headers *C.zend_llist) *C.zend_string {
+	fail := func(err error) *C.zend_string {
+		return (*C.zend_string)(PHPString(err.Error(), false))
+	}
+
+	fc := phpThreads[threadIndex].getRequestContext()
+	if fc == nil || fc.isDone || fc.responseWriter == nil || fc.request == nil {
+		return fail(errors.New("the response has already been sent"))
+	}
+	if fc.headless {
+		return fail(errors.New("the response is not sent to a client"))
+	}
+
+	s := &sentFile{}
+	var contentType string
+	if opts != nil {
+		for k, v := range GoMap(unsafe.Pointer(opts)) {
+			switch k {
+			case "filename":
+				name, ok := v.(string)
+				if !ok || name == "" || strings.ContainsAny(name, "/\\") {
+					return fail(errors.New(`the "filename" option must be a file name`))
+				}
+				s.name = name
+				s.attachment = true
+			case "content_type":
+				var ok bool
+				if contentType, ok = v.(string); !ok || contentType == "" {
+					return fail(errors.New(`the "content_type" option must be a non-empty string`))
+				}
+			default:
+				return fail(fmt.Errorf("unknown option %q", k))
+			}
+		}
+	}
+
+	f, err := fc.openFileToSend(C.GoString(path))
+	if err != nil {
+		return fail(err)
+	}
+
+	s.file = f
+	if s.name == "" {
+		s.name = filepath.Base(f.Name())
+	}
+	fc.sentFile = s
+
+	// keep the headers set by the script, the status code and the content are set by Go
+	for current := headers.head; current != nil; current = current.next {
+		h := (*C.sapi_header_struct)(unsafe.Pointer(&(current.data)))
+
+		addHeader(fc, h.header, C.int(h.header_len))
+	}
+	if contentType != "" {
+		fc.responseWriter.Header().Set("Content-Type", contentType)
+	}
+
+	go_frankenphp_finish_php_request(threadIndex)
+
+	return nil
+}
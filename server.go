@@ -0,0 +1,34 @@
+package frankenphp
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Server is an instance of FrankenPHP: it holds its options, its workers, its logger and its metrics.
+//
+// The package-level functions such as Init, ServeHTTP and Shutdown are wrappers over the running server.
+// As the PHP runtime is global to the process, only one server can be running at a time.
+type Server struct {
+	options []Option
+
+	logger      *slog.Logger
+	metrics     Metrics
+	maxWaitTime time.Duration
+	workers     []*worker
+}
+
+// NewServer creates a server, the options are applied when it is started.
+func NewServer(options ...Option) *Server {
+	return &Server{options: options}
+}
+
+// Running returns true if the server owns the PHP runtime.
+func (s *Server) Running() bool {
+	return activeServer.Load() == s
+}
+
+// Logger returns the logger of the server, or nil if it has never been started.
+func (s *Server) Logger() *slog.Logger {
+	return s.logger
+}
@@ -0,0 +1,98 @@
+package frankenphp_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	cwd, _ := os.Getwd()
+	newServer := func() *frankenphp.Server {
+		return frankenphp.NewServer(
+			frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 1),
+		)
+	}
+
+	s := newServer()
+	assert.False(t, s.Running())
+	require.NoError(t, s.Start())
+	defer frankenphp.Shutdown()
+	assert.True(t, s.Running())
+	assert.NotNil(t, s.Logger())
+
+	// the PHP runtime is global to the process
+	other := newServer()
+	assert.ErrorIs(t, other.Start(), frankenphp.ErrAlreadyStarted)
+	assert.ErrorIs(t, frankenphp.Init(), frankenphp.ErrAlreadyStarted)
+	assert.Empty(t, other.DebugState().ThreadDebugStates)
+
+	req := httptest.NewRequest("GET", "http://example.com/index.php?i=0", nil)
+	fr, err := frankenphp.NewRequestWithContext(req, frankenphp.WithRequestDocumentRoot(cwd+"/testdata/", false))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, s.ServeHTTP(w, fr))
+	assert.Equal(t, "I am by birth a Genevese (0)", w.Body.String())
+	assert.ErrorIs(t, other.ServeHTTP(httptest.NewRecorder(), fr), frankenphp.ErrNotRunning)
+
+	ret, err := s.Call(context.Background(), "call", map[string]any{"a": 1, "b": 2})
+	require.NoError(t, err)
+	assert.Equal(t, frankenphp.AssociativeArray{Map: map[string]any{"sum": int64(3)}, Order: []string{"sum"}}, ret)
+
+	_, err = other.Call(context.Background(), "call", nil)
+	assert.ErrorIs(t, err, frankenphp.ErrNotRunning)
+
+	assert.NotEmpty(t, s.DebugState().ThreadDebugStates)
+	s.RestartWorkers()
+
+	s.Shutdown()
+	assert.False(t, s.Running())
+
+	// another server can be started once the previous one has been shut down
+	require.NoError(t, other.Start())
+	assert.True(t, other.Running())
+
+	ret, err = frankenphp.Call(context.Background(), "call", map[string]any{"a": 2, "b": 2})
+	require.NoError(t, err)
+	assert.Equal(t, frankenphp.AssociativeArray{Map: map[string]any{"sum": int64(4)}, Order: []string{"sum"}}, ret)
+
+	frankenphp.Shutdown()
+	assert.False(t, other.Running())
+}
+
+func TestServerFailingToStart(t *testing.T) {
+	cwd, _ := os.Getwd()
+	logger := frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// invalid options
+	s := frankenphp.NewServer(logger, frankenphp.WithNumThreads(1), frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 2))
+	require.Error(t, s.Start())
+	assert.False(t, s.Running())
+
+	// invalid workers, detected once the PHP threads are started
+	s = frankenphp.NewServer(
+		logger,
+		frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 1),
+		frankenphp.WithWorkers("call", cwd+"/testdata/index.php", 1),
+	)
+	require.Error(t, s.Start())
+	assert.False(t, s.Running())
+
+	// the runtime has been released
+	s = frankenphp.NewServer(logger, frankenphp.WithWorkers("call", cwd+"/testdata/worker-call.php", 1))
+	require.NoError(t, s.Start())
+	defer s.Shutdown()
+
+	ret, err := s.Call(context.Background(), "call", map[string]any{"a": 1, "b": 1})
+	require.NoError(t, err)
+	assert.Equal(t, frankenphp.AssociativeArray{Map: map[string]any{"sum": int64(2)}, Order: []string{"sum"}}, ret)
+}
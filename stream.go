@@ -0,0 +1,122 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// detachedStream is a response kept open by Go after the PHP script has finished handling the request
+type detachedStream struct {
+	channel    string
+	subscriber *subscriber
+	// timeout is 0 if the stream must never time out
+	timeout time.Duration
+	// limit is the number of messages to write before closing the stream, 0 means unlimited
+	limit  int64
+	closed chan struct{}
+}
+
+var (
+	streamsMu sync.Mutex
+	streams   = make(map[*detachedStream]struct{})
+	streamsWg sync.WaitGroup
+)
+
+// serveDetachedStream writes the messages published on the channel of the stream until the client disconnects,
+// the stream times out, the limit of messages is reached or FrankenPHP shuts down
+func (fc *frankenPHPContext) serveDetachedStream() {
+	s := fc.stream
+	defer func() {
+		streamsMu.Lock()
+		delete(streams, s)
+		streamsMu.Unlock()
+
+		s.subscriber.close()
+		streamsWg.Done()
+	}()
+
+	var timer <-chan time.Time
+	if s.timeout > 0 {
+		t := time.NewTimer(s.timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	rc := http.NewResponseController(fc.responseWriter)
+	_ = rc.Flush()
+
+	for written := int64(0); s.limit <= 0 || written < s.limit; written++ {
+		select {
+		case m := <-s.subscriber.messages:
+			data, ok := m.Value.(string)
+			if !ok {
+				fc.logger.LogAttrs(context.Background(), slog.LevelWarn, "only strings can be written to a detached stream, message ignored", slog.String("channel", s.channel), slog.String("type", fmt.Sprintf("%T", m.Value)))
+				written--
+
+				continue
+			}
+
+			if _, err := fc.responseWriter.Write([]byte(data)); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-fc.request.Context().Done():
+			return
+		case <-timer:
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// drainStreams closes all detached streams and waits for their handlers to return
+func drainStreams() {
+	streamsMu.Lock()
+	for s := range streams {
+		close(s.closed)
+	}
+	clear(streams)
+	streamsMu.Unlock()
+
+	streamsWg.Wait()
+}
+
+//export go_frankenphp_stream_detach
+func go_frankenphp_stream_detach(threadIndex C.uintptr_t, channel *C.zend_string, timeout C.double, limit C.zend_long) C.bool {
+	thread := phpThreads[threadIndex]
+	fc := thread.getRequestContext()
+	if fc == nil || fc.isDone || fc.headless || fc.responseWriter == nil || fc.request == nil {
+		return C.bool(false)
+	}
+
+	s := &detachedStream{
+		channel:    GoString(unsafe.Pointer(channel)),
+		subscriber: newSubscriber(),
+		timeout:    secondsToDuration(timeout),
+		limit:      int64(limit),
+		closed:     make(chan struct{}),
+	}
+	// subscribe before the response is handed off to not miss messages published in between
+	s.subscriber.subscribe(s.channel)
+
+	streamsMu.Lock()
+	streams[s] = struct{}{}
+	streamsWg.Add(1)
+	streamsMu.Unlock()
+
+	fc.stream = s
+
+	go_frankenphp_finish_php_request(threadIndex)
+
+	return C.bool(true)
+}
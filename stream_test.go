@@ -0,0 +1,79 @@
+package frankenphp
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDetachedStreamTestContext(channel string, timeout time.Duration, limit int64) (*frankenPHPContext, *httptest.ResponseRecorder) {
+	fc := newFrankenPHPContext()
+	fc.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	fc.request = httptest.NewRequest("GET", "/", nil)
+
+	w := httptest.NewRecorder()
+	fc.responseWriter = w
+
+	fc.stream = &detachedStream{
+		channel:    channel,
+		subscriber: newSubscriber(),
+		timeout:    timeout,
+		limit:      limit,
+		closed:     make(chan struct{}),
+	}
+	fc.stream.subscriber.subscribe(channel)
+
+	streamsMu.Lock()
+	streams[fc.stream] = struct{}{}
+	streamsWg.Add(1)
+	streamsMu.Unlock()
+
+	return fc, w
+}
+
+func TestDetachedStreamWritesStringMessagesUntilTheLimit(t *testing.T) {
+	fc, w := newDetachedStreamTestContext("limit", 0, 2)
+
+	for _, v := range []any{"foo", int64(42), "bar", "baz"} {
+		n, err := Publish("limit", v)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	}
+
+	fc.serveDetachedStream()
+
+	assert.Equal(t, "foobar", w.Body.String())
+	assert.True(t, w.Flushed)
+	assert.Empty(t, topics)
+	assert.Empty(t, streams)
+}
+
+func TestDetachedStreamTimesOut(t *testing.T) {
+	fc, w := newDetachedStreamTestContext("timeout", 10*time.Millisecond, 0)
+
+	fc.serveDetachedStream()
+
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, topics)
+}
+
+func TestDrainStreams(t *testing.T) {
+	fc, _ := newDetachedStreamTestContext("drain", 0, 0)
+
+	done := make(chan struct{})
+	go func() {
+		fc.serveDetachedStream()
+		close(done)
+	}()
+
+	drainStreams()
+	<-done
+
+	assert.Empty(t, streams)
+	assert.Empty(t, topics)
+}
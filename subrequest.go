@@ -0,0 +1,359 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// maxSubRequestDepth limits the nesting of sub-requests
+const maxSubRequestDepth = 5
+
+type subRequestDepthKeyStruct struct{}
+
+var subRequestDepthKey = subRequestDepthKeyStruct{}
+
+var (
+	errSubRequestOtherHost = errors.New("sub-requests must target the same host")
+	errSubRequestTooDeep   = errors.New("too many nested sub-requests")
+	errSubRequestNoThread  = errors.New("no PHP thread is available to handle the sub-request")
+)
+
+// subRequest is a request passed to frankenphp_subrequest()
+type subRequest struct {
+	method string
+	url    *url.URL
+	header http.Header
+	body   string
+}
+
+// resolveSubRequestURL resolves the URL relative to the URL of the current request, it must be on the same host
+func (fc *frankenPHPContext) resolveSubRequestURL(target string) (*url.URL, error) {
+	r := fc.originalRequest
+	if r == nil {
+		r = fc.request
+	}
+
+	ref, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	base := *r.URL
+	base.Scheme = "http"
+	base.Host = r.Host
+
+	u := base.ResolveReference(ref)
+	if u.Host != r.Host {
+		return nil, fmt.Errorf("%w: %q", errSubRequestOtherHost, target)
+	}
+
+	return &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}, nil
+}
+
+// newSubRequest creates an internal request handled with the document root, the environment and the options of the current request.
+// If the URL doesn't match a PHP file, the script that handled the current request is used, as for front controllers.
+func (fc *frankenPHPContext) newSubRequest(method string, u *url.URL, header http.Header, body io.Reader) (*http.Request, error) {
+	depth, _ := fc.request.Context().Value(subRequestDepthKey).(int)
+	if depth >= maxSubRequestDepth {
+		return nil, errSubRequestTooDeep
+	}
+
+	sr, err := http.NewRequestWithContext(context.WithValue(fc.request.Context(), subRequestDepthKey, depth+1), method, u.RequestURI(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	sr.Proto = fc.request.Proto
+	sr.ProtoMajor = fc.request.ProtoMajor
+	sr.ProtoMinor = fc.request.ProtoMinor
+	sr.Host = fc.request.Host
+	sr.RemoteAddr = fc.request.RemoteAddr
+	sr.TLS = fc.request.TLS
+	sr.RequestURI = u.RequestURI()
+	sr.Header = header
+
+	sfc := newFrankenPHPContext()
+	sfc.request = sr
+	sfc.documentRoot = fc.documentRoot
+	sfc.splitPath = fc.splitPath
+	sfc.env = fc.env
+	sfc.logger = fc.logger
+	sfc.cacheResponse = fc.cacheResponse
+	sfc.responseCacheVary = fc.responseCacheVary
+	sfc.coalesce = fc.coalesce
+	sfc.coalescingVary = fc.coalescingVary
+	sfc.esi = fc.esi
+
+	splitCgiPath(sfc, fc.server)
+	if sfc.worker == nil {
+		if fi, err := os.Stat(sfc.scriptFilename); err != nil || fi.IsDir() {
+			sfc.docURI = fc.docURI
+			sfc.pathInfo = ""
+			sfc.scriptName = fc.scriptName
+			sfc.scriptFilename = fc.scriptFilename
+			sfc.worker = fc.worker
+		}
+	}
+
+	ctx, cancel := context.WithCancel(sr.Context())
+	sfc.ctx = context.WithValue(ctx, contextKey, sfc)
+	sfc.cancel = cancel
+
+	return sr.WithContext(sfc.ctx), nil
+}
+
+// rejectSubRequest is called when no thread is immediately available to handle the sub-request
+func (fc *frankenPHPContext) rejectSubRequest() {
+	fc.handlerError = errSubRequestNoThread
+	fc.reject(http.StatusServiceUnavailable, "Service Unavailable")
+}
+
+// serveSubRequests handles the sub-requests in parallel and waits for their responses,
+// it returns early with the error of ctx if it is done before
+func (s *Server) serveSubRequests(ctx context.Context, requests []*http.Request) ([]*subResponseWriter, error) {
+	responses := make([]*subResponseWriter, len(requests))
+	errs := make([]error, len(requests))
+
+	var wg sync.WaitGroup
+	wg.Add(len(requests))
+	for i, r := range requests {
+		responses[i] = &subResponseWriter{header: make(http.Header)}
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = s.ServeHTTP(responses[i], r)
+			if sfc, ok := fromContext(r.Context()); ok && errors.Is(sfc.handlerError, errSubRequestNoThread) {
+				errs[i] = sfc.handlerError
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// the sub-requests still running write to responses that are not read anymore
+		return nil, ctx.Err()
+	}
+
+	return responses, errors.Join(errs...)
+}
+
+// parseSubRequest converts an URL or an array having the "url", "method", "headers" and "body" keys
+func (fc *frankenPHPContext) parseSubRequest(v any) (*subRequest, error) {
+	s := &subRequest{method: http.MethodGet, header: make(http.Header)}
+
+	var target string
+	switch v := v.(type) {
+	case string:
+		target = v
+	case AssociativeArray:
+		for k, value := range v.Map {
+			switch k {
+			case "url":
+				var ok bool
+				if target, ok = value.(string); !ok {
+					return nil, errors.New(`the "url" option must be a string`)
+				}
+			case "method":
+				method, ok := value.(string)
+				if !ok || method == "" {
+					return nil, errors.New(`the "method" option must be a non-empty string`)
+				}
+				s.method = strings.ToUpper(method)
+			case "headers":
+				headers, ok := value.(AssociativeArray)
+				if !ok {
+					return nil, errors.New(`the "headers" option must be an array of strings or arrays of strings indexed by name`)
+				}
+
+				for name, values := range headers.Map {
+					switch values := values.(type) {
+					case string:
+						s.header.Add(name, values)
+					case []any:
+						for _, hv := range values {
+							str, ok := hv.(string)
+							if !ok {
+								return nil, fmt.Errorf("the values of the %q header must be strings", name)
+							}
+							s.header.Add(name, str)
+						}
+					default:
+						return nil, fmt.Errorf("the values of the %q header must be strings", name)
+					}
+				}
+			case "body":
+				var ok bool
+				if s.body, ok = value.(string); !ok {
+					return nil, errors.New(`the "body" option must be a string`)
+				}
+			default:
+				return nil, fmt.Errorf("unknown option %q", k)
+			}
+		}
+	default:
+		return nil, errors.New("requests must be URLs or arrays")
+	}
+
+	if target == "" {
+		return nil, errors.New(`the "url" option is required`)
+	}
+
+	var err error
+	if s.url, err = fc.resolveSubRequestURL(target); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// phpResponse converts the response to an array having the "status", "headers" and "body" keys
+func (w *subResponseWriter) phpResponse() AssociativeArray {
+	names := make([]string, 0, len(w.header))
+	for name := range w.header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	headers := AssociativeArray{Map: make(map[string]any, len(names)), Order: names}
+	for _, name := range names {
+		values := make([]any, 0, len(w.header[name]))
+		for _, v := range w.header[name] {
+			values = append(values, v)
+		}
+		headers.Map[name] = values
+	}
+
+	return AssociativeArray{
+		Map:   map[string]any{"status": int64(w.statusCode()), "headers": headers, "body": w.body.String()},
+		Order: []string{"status", "headers", "body"},
+	}
+}
+
+// subResponseWriter records the response to a sub-request
+type subResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *subResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *subResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.body.Write(p)
+}
+
+func (w *subResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 && statusCode >= 200 {
+		w.status = statusCode
+	}
+}
+
+func (w *subResponseWriter) Flush() {}
+
+func (w *subResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+//export go_frankenphp_subrequest
+func go_frankenphp_subrequest(threadIndex C.uintptr_t, requests *C.zval) (unsafe.Pointer, *C.zend_string) {
+	fail := func(err error) (unsafe.Pointer, *C.zend_string) {
+		return nil, (*C.zend_string)(PHPString(err.Error(), false))
+	}
+
+	thread := phpThreads[threadIndex]
+	fc := thread.getRequestContext()
+	if fc == nil || fc.request == nil {
+		return fail(errors.New("sub-requests can only be sent while handling a request"))
+	}
+
+	var (
+		keys  []string
+		specs []any
+	)
+	switch v := GoValue(unsafe.Pointer(requests)).(type) {
+	case []any:
+		specs = v
+	case AssociativeArray:
+		keys = v.Order
+		for _, k := range keys {
+			specs = append(specs, v.Map[k])
+		}
+	}
+
+	srs := make([]*http.Request, len(specs))
+	for i, spec := range specs {
+		s, err := fc.parseSubRequest(spec)
+		if err != nil {
+			key := strconv.Itoa(i)
+			if keys != nil {
+				key = keys[i]
+			}
+
+			return fail(fmt.Errorf("invalid request %q: %w", key, err))
+		}
+
+		if srs[i], err = fc.newSubRequest(s.method, s.url, s.header, strings.NewReader(s.body)); err != nil {
+			return fail(err)
+		}
+
+		// the current thread waits for the sub-request, waiting for a thread in turn could deadlock
+		sfc, _ := fromContext(srs[i].Context())
+		sfc.isSubRequest = true
+	}
+
+	responses, err := fc.server.serveSubRequests(fc.request.Context(), srs)
+	if err != nil {
+		return fail(err)
+	}
+
+	var result any
+	if keys == nil {
+		packed := make([]any, len(responses))
+		for i, w := range responses {
+			packed[i] = w.phpResponse()
+		}
+		result = packed
+	} else {
+		assoc := AssociativeArray{Map: make(map[string]any, len(responses)), Order: keys}
+		for i, w := range responses {
+			assoc.Map[keys[i]] = w.phpResponse()
+		}
+		result = assoc
+	}
+
+	ptr := PHPValue(result)
+	thread.Pin(ptr)
+
+	return ptr, nil
+}
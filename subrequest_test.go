@@ -0,0 +1,26 @@
+package frankenphp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSubRequestURL(t *testing.T) {
+	fc := newFrankenPHPContext()
+	fc.request = httptest.NewRequest(http.MethodGet, "http://example.com/blog/index.php?page=2", nil)
+
+	u, err := fc.resolveSubRequestURL("post.php?id=1")
+	require.NoError(t, err)
+	assert.Equal(t, "/blog/post.php?id=1", u.RequestURI())
+
+	u, err = fc.resolveSubRequestURL("//example.com/api")
+	require.NoError(t, err)
+	assert.Equal(t, "/api", u.RequestURI())
+
+	_, err = fc.resolveSubRequestURL("https://example.org/")
+	assert.ErrorIs(t, err, errSubRequestOtherHost)
+}
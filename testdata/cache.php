@@ -0,0 +1,21 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    $key = 'cache-test-'.$_GET['i'];
+
+    var_dump(frankenphp_cache_get($key, 'default'));
+    var_dump(frankenphp_cache_set($key, ['foo' => 'bar', 'list' => [1, 2.5, null]]));
+    var_dump(frankenphp_cache_get($key));
+    var_dump(frankenphp_cache_incr($key.'-counter', 2));
+    var_dump(frankenphp_cache_incr($key.'-counter'));
+    var_dump(frankenphp_cache_delete($key));
+    var_dump(frankenphp_cache_delete($key));
+
+    try {
+        frankenphp_cache_set($key, new stdClass());
+    } catch (TypeError $e) {
+        echo $e->getMessage(), "\n";
+    }
+};
@@ -0,0 +1,14 @@
+#!/usr/bin/env php
+<?php
+
+if (($argv[1] ?? '') === 'loop') {
+    while (true) {
+    }
+}
+
+echo 'argv: '.implode(',', $argv)."\n";
+fwrite(STDOUT, 'stdin: '.stream_get_contents(STDIN)."\n");
+fwrite(STDERR, 'getenv: '.getenv('FOO')."\n");
+file_put_contents('php://stderr', 'server: '.$_SERVER['FOO']."\n");
+
+exit(3);
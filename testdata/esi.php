@@ -0,0 +1,32 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    if (isset($_GET['fragment'])) {
+        if ('missing' === $_GET['fragment']) {
+            http_response_code(404);
+
+            return;
+        }
+
+        echo 'fragment '.$_GET['fragment'];
+
+        return;
+    }
+
+    if (isset($_GET['unmarked'])) {
+        echo '<esi:include src="/esi.php?fragment=a" />';
+
+        return;
+    }
+
+    header('Surrogate-Control: content="ESI/1.0"');
+    echo $_SERVER['HTTP_SURROGATE_CAPABILITY'].':';
+    echo '<p><esi:include src="/esi.php?fragment=a" /></p>';
+    echo '<p><esi:include src="esi.php?fragment=b"></esi:include></p>';
+    echo '<esi:include src="/esi.php?fragment=missing" alt="/esi.php?fragment=alt" />';
+    echo '<esi:include src="/esi.php?fragment=missing" onerror="continue" />';
+    echo '<esi:remove>removed</esi:remove><esi:comment text="comment" />';
+    echo '<!--esi <esi:include src="/esi.php?fragment=c" />-->';
+};
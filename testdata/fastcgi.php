@@ -0,0 +1,17 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    header('X-Script-Name: '.$_SERVER['SCRIPT_NAME']);
+
+    echo implode("\n", [
+        $_SERVER['REQUEST_METHOD'].' '.$_SERVER['REQUEST_URI'],
+        'SCRIPT_NAME: '.$_SERVER['SCRIPT_NAME'],
+        'PATH_INFO: '.($_SERVER['PATH_INFO'] ?? ''),
+        'APP_ENV: '.($_SERVER['APP_ENV'] ?? ''),
+        'X-Foo: '.($_SERVER['HTTP_X_FOO'] ?? ''),
+        'i: '.$_GET['i'],
+        'body: '.file_get_contents('php://input'),
+    ]);
+};
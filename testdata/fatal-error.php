@@ -0,0 +1,10 @@
+<?php
+
+if (isset($_GET['flush'])) {
+    while (@ob_end_flush());
+
+    echo 'partial';
+    flush();
+}
+
+undefined_function();
@@ -0,0 +1,10 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    header('X-Accel-Redirect: '.($_GET['to'] ?? $_SERVER['REQUEST_URI']));
+    header('X-Discarded: 1');
+
+    echo 'discarded';
+};
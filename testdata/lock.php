@@ -0,0 +1,24 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    $lock = 'lock-test-'.$_GET['i'];
+    $semaphore = 'semaphore-test-'.$_GET['i'];
+
+    // the lock and the permits are not released explicitly, they must be released when the request ends
+    var_dump(frankenphp_lock($lock, 0));
+
+    try {
+        frankenphp_lock($lock);
+    } catch (RuntimeException $e) {
+        echo $e->getMessage(), "\n";
+    }
+
+    var_dump(frankenphp_semaphore_acquire($semaphore, 2, 0));
+    var_dump(frankenphp_semaphore_acquire($semaphore, 2, 0));
+    var_dump(frankenphp_semaphore_acquire($semaphore, 2, 0.01));
+    var_dump(frankenphp_semaphore_release($semaphore));
+    var_dump(frankenphp_semaphore_acquire($semaphore, 2, 0));
+    var_dump(frankenphp_unlock('not-held'));
+};
@@ -0,0 +1,7 @@
+<?php
+
+try {
+    echo mercure_publish(['https://example.com/books/1', 'https://example.com/books'], 'Hello', id: $_GET['id'] ?? null);
+} catch (RuntimeException $e) {
+    echo $e->getMessage();
+}
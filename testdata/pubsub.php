@@ -0,0 +1,16 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    $topic = 'pubsub-test-'.$_GET['i'];
+
+    frankenphp_subscribe($topic);
+    var_dump(frankenphp_publish($topic, ['foo' => 'bar']));
+    var_dump(frankenphp_receive(1));
+    var_dump(frankenphp_receive());
+    frankenphp_unsubscribe($topic);
+
+    var_dump(frankenphp_publish($topic, 'nobody is listening'));
+    var_dump(frankenphp_publish('pubsub-go-'.$_GET['i'], 'hello Go'));
+};
@@ -0,0 +1,13 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    echo frankenphp_queue_push('jobs', [
+        'file' => $_GET['file'] ?? null,
+        'fail' => isset($_GET['fail']),
+        'ratio' => 1.5,
+        'binary' => "\xff\x00\xfe",
+        'sparse' => [10 => 'b', 3 => 'a'],
+    ], ['max_attempts' => 2]);
+};
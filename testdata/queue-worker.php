@@ -0,0 +1,18 @@
+<?php
+
+$handler = function (array $job) {
+    if ($job['payload']['fail']) {
+        throw new RuntimeException('job failed');
+    }
+
+    $job['checks'] = [
+        'binary' => "\xff\x00\xfe" === $job['payload']['binary'],
+        'sparse' => [10 => 'b', 3 => 'a'] === $job['payload']['sparse'],
+    ];
+    unset($job['payload']['binary']);
+
+    file_put_contents($job['payload']['file'], json_encode($job));
+};
+
+while (frankenphp_handle_request($handler)) {
+}
@@ -0,0 +1,12 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    header('Cache-Control: '.($_GET['cache_control'] ?? 'public'));
+
+    $generation = frankenphp_counter_add('request-coalescing-'.$_GET['run'].'-'.$_GET['i']);
+    usleep(300000);
+
+    echo 'generation '.$generation;
+};
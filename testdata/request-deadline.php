@@ -0,0 +1,10 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    $deadline = frankenphp_request_deadline();
+
+    echo $deadline === null ? 'none' : sprintf('%.6F', $deadline), "\n";
+    echo $_SERVER['REQUEST_DEADLINE_FLOAT'] ?? 'none';
+};
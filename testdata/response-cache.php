@@ -0,0 +1,14 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    header('Cache-Control: '.($_GET['cache_control'] ?? 'public, max-age=60'));
+    header('Cache-Tag: page');
+    if (isset($_GET['vary'])) {
+        header('Vary: '.$_GET['vary']);
+    }
+    frankenphp_response_cache_tag('page-'.$_GET['i']);
+
+    echo 'generation '.frankenphp_counter_add('response-cache-'.$_GET['run'].'-'.$_GET['i']);
+};
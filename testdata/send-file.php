@@ -0,0 +1,18 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    header('Cache-Control: private');
+
+    try {
+        frankenphp_send_file(__DIR__.'/'.$_GET['file'], isset($_GET['name']) ? ['filename' => $_GET['name']] : []);
+    } catch (RuntimeException $e) {
+        echo $e->getMessage();
+
+        return;
+    }
+
+    // frankenphp_send_file() ends the script
+    frankenphp_publish('send-file', 'not ended');
+};
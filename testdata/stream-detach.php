@@ -0,0 +1,15 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    $channel = 'stream-'.$_GET['i'];
+
+    header('Content-Type: text/event-stream');
+    echo "data: start\n\n";
+
+    frankenphp_stream_detach($channel, 5, 2);
+
+    echo 'not sent';
+    frankenphp_publish($channel, "data: from PHP\n\n");
+};
@@ -0,0 +1,36 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    if (isset($_GET['item'])) {
+        header('X-Item: '.$_GET['item']);
+        echo $_SERVER['REQUEST_METHOD'].' '.$_GET['item'].' '.file_get_contents('php://input').' '.($_SERVER['HTTP_X_FOO'] ?? '');
+
+        return;
+    }
+
+    if (isset($_GET['self'])) {
+        try {
+            frankenphp_subrequest(['/subrequest.php?item=a']);
+        } catch (RuntimeException $e) {
+            echo $e->getMessage();
+        }
+
+        return;
+    }
+
+    $responses = frankenphp_subrequest([
+        'a' => '/subrequest.php?item=a',
+        'b' => ['url' => 'subrequest.php?item=b', 'method' => 'post', 'headers' => ['X-Foo' => 'bar'], 'body' => 'hello'],
+    ]);
+    foreach ($responses as $key => $response) {
+        echo $key.':'.$response['status'].':'.$response['headers']['X-Item'][0].':'.$response['body']."\n";
+    }
+
+    try {
+        frankenphp_subrequest(['https://example.org/']);
+    } catch (RuntimeException $e) {
+        echo $e->getMessage();
+    }
+};
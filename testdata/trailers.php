@@ -0,0 +1,18 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    var_dump(frankenphp_set_trailer('Grpc-Status', '0'));
+    var_dump(frankenphp_set_trailer('Grpc-Message', 'OK '.$_GET['i']));
+
+    try {
+        frankenphp_set_trailer('Invalid Name', 'foo');
+    } catch (RuntimeException $e) {
+        echo $e->getMessage(), "\n";
+    }
+
+    frankenphp_finish_request();
+
+    var_dump(frankenphp_set_trailer('Too-Late', 'foo'));
+};
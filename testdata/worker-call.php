@@ -0,0 +1,16 @@
+<?php
+
+$handler = function (?array $params = null) {
+    if ($params['throw'] ?? false) {
+        throw new RuntimeException('something went wrong', 42);
+    }
+
+    if (isset($params['sleep'])) {
+        usleep($params['sleep']);
+    }
+
+    return ['sum' => ($params['a'] ?? 0) + ($params['b'] ?? 0)];
+};
+
+while (frankenphp_handle_request($handler)) {
+}
@@ -0,0 +1,24 @@
+<?php
+
+$handled = 0;
+
+$handler = static function (array $call) use (&$handled): string|array {
+    ++$handled;
+
+    // messages are usually (de)serialized with the google/protobuf package
+    switch ($call['message']) {
+        case 'not-found':
+            throw new RuntimeException('no greeting for you', 5);
+        case 'fail':
+            throw new RuntimeException('something went wrong', 42);
+        case 'denied':
+            return ['code' => 7, 'message' => 'permission denied'];
+        case 'handled':
+            return (string) $handled;
+    }
+
+    return $call['method'].': Hello '.$call['message'].' '.($call['metadata']['x-foo'][0] ?? '');
+};
+
+while (frankenphp_handle_request($handler)) {
+}
@@ -0,0 +1,31 @@
+<?php
+
+$handler = function (array $event) {
+    switch ($event['event']) {
+        case 'open':
+            if (($event['headers']['X-Reject'] ?? '') === '1') {
+                return false;
+            }
+
+            frankenphp_ws_send($event['connection'], 'welcome '.$event['uri']);
+
+            return true;
+
+        case 'message':
+            match ($event['data']) {
+                'broadcast' => frankenphp_ws_broadcast('to everyone'),
+                'close' => frankenphp_ws_close($event['connection']),
+                default => frankenphp_ws_send($event['connection'], 'echo: '.$event['data'], $event['binary']),
+            };
+
+            return null;
+
+        case 'close':
+            frankenphp_ws_broadcast('bye');
+
+            return null;
+    }
+};
+
+while (frankenphp_handle_request($handler)) {
+}
@@ -46,7 +46,11 @@ func (handler *regularThread) beforeScriptExecution() string {
 	panic("unexpected state: " + handler.state.name())
 }
 
-func (handler *regularThread) afterScriptExecution(int) {
+func (handler *regularThread) afterScriptExecution(exitStatus int) {
+	if cli := handler.requestContext.cli; cli != nil {
+		cli.finish(exitStatus)
+	}
+
 	handler.afterRequest()
 }
 
@@ -84,34 +88,47 @@ func (handler *regularThread) afterRequest() {
 	handler.requestContext = nil
 }
 
-func handleRequestWithRegularPHPThreads(fc *frankenPHPContext) {
-	metrics.StartRequest()
+func (s *Server) handleRequestWithRegularPHPThreads(fc *frankenPHPContext, cancel <-chan struct{}) bool {
+	s.metrics.StartRequest()
 	select {
 	case regularRequestChan <- fc:
 		// a thread was available to handle the request immediately
 		<-fc.done
-		metrics.StopRequest()
-		return
+		s.metrics.StopRequest()
+		return true
 	default:
 		// no thread was available
 	}
 
+	if fc.isSubRequest {
+		s.metrics.StopRequest()
+		fc.rejectSubRequest()
+
+		return false
+	}
+
 	// if no thread was available, mark the request as queued and fan it out to all threads
-	metrics.QueuedRequest()
+	s.metrics.QueuedRequest()
 	for {
 		select {
 		case regularRequestChan <- fc:
-			metrics.DequeuedRequest()
+			s.metrics.DequeuedRequest()
 			<-fc.done
-			metrics.StopRequest()
-			return
+			s.metrics.StopRequest()
+			return true
 		case scaleChan <- fc:
 			// the request has triggered scaling, continue to wait for a thread
-		case <-timeoutChan(maxWaitTime):
+		case <-cancel:
+			s.metrics.DequeuedRequest()
+			s.metrics.StopRequest()
+			fc.closeContext()
+			return false
+		case <-timeoutChan(s.maxWaitTime):
 			// the request has timed out stalling
-			metrics.DequeuedRequest()
+			s.metrics.DequeuedRequest()
+			s.metrics.StopRequest()
 			fc.reject(504, "Gateway Timeout")
-			return
+			return false
 		}
 	}
 }
@@ -20,14 +20,17 @@ type workerThread struct {
 	dummyContext    *frankenPHPContext
 	workerContext   *frankenPHPContext
 	backoff         *exponentialBackoff
-	externalWorker  Worker
-	isBootingScript bool // true if the worker has not reached frankenphp_handle_request yet
+	externalWorker  WorkerV2
+	stopPipe        context.CancelFunc // stops piping requests from the external worker
+	isBootingScript bool               // true if the worker has not reached frankenphp_handle_request yet
 }
 
 func convertToWorkerThread(thread *phpThread, worker *worker) {
+	extensionWorkersMutex.Lock()
 	externalWorker := extensionWorkers[worker.name]
+	extensionWorkersMutex.Unlock()
 
-	thread.setHandler(&workerThread{
+	handler := &workerThread{
 		state:  thread.state,
 		thread: thread,
 		worker: worker,
@@ -37,18 +40,33 @@ func convertToWorkerThread(thread *phpThread, worker *worker) {
 			maxConsecutiveFailures: worker.maxConsecutiveFailures,
 		},
 		externalWorker: externalWorker,
-	})
+	}
+
+	// create a pipe from the external worker to the main worker for each thread
+	if externalWorker != nil {
+		var ctx context.Context
+		ctx, handler.stopPipe = context.WithCancel(extensionWorkersCtx)
+		go startWorker(ctx, worker, externalWorker, thread)
+	}
+
+	thread.setHandler(handler)
 	worker.attachThread(thread)
 }
 
+// detach removes the thread from the worker
+func (handler *workerThread) detach() {
+	if handler.externalWorker != nil {
+		handler.stopPipe()
+		handler.externalWorker.ThreadDeactivatedNotification(handler.thread.threadIndex)
+	}
+	handler.worker.detachThread(handler.thread)
+}
+
 // beforeScriptExecution returns the name of the script or an empty string on shutdown
 func (handler *workerThread) beforeScriptExecution() string {
 	switch handler.state.get() {
 	case stateTransitionRequested:
-		if handler.externalWorker != nil {
-			handler.externalWorker.ThreadDeactivatedNotification(handler.thread.threadIndex)
-		}
-		handler.worker.detachThread(handler.thread)
+		handler.detach()
 		return handler.thread.transitionToNewHandler()
 	case stateRestarting:
 		if handler.externalWorker != nil {
@@ -64,10 +82,7 @@ func (handler *workerThread) beforeScriptExecution() string {
 		setupWorkerScript(handler, handler.worker)
 		return handler.worker.fileName
 	case stateShuttingDown:
-		if handler.externalWorker != nil {
-			handler.externalWorker.ThreadDeactivatedNotification(handler.thread.threadIndex)
-		}
-		handler.worker.detachThread(handler.thread)
+		handler.detach()
 		// signal to stop
 		return ""
 	}
@@ -92,10 +107,10 @@ func (handler *workerThread) name() string {
 
 func setupWorkerScript(handler *workerThread, worker *worker) {
 	handler.backoff.wait()
-	metrics.StartWorker(worker.name)
+	worker.server.metrics.StartWorker(worker.name)
 
 	if handler.state.is(stateReady) {
-		metrics.ReadyWorker(handler.worker.name)
+		worker.server.metrics.ReadyWorker(handler.worker.name)
 	}
 
 	// Create a dummy request to set up the worker
@@ -103,16 +118,18 @@ func setupWorkerScript(handler *workerThread, worker *worker) {
 		filepath.Base(worker.fileName),
 		WithRequestDocumentRoot(filepath.Dir(worker.fileName), false),
 		WithRequestPreparedEnv(worker.env),
+		WithRequestLogger(worker.server.logger),
 	)
 	if err != nil {
 		panic(err)
 	}
 
+	fc.server = worker.server
 	fc.worker = worker
 	handler.dummyContext = fc
 	handler.isBootingScript = true
 	clearSandboxedEnv(handler.thread)
-	logger.LogAttrs(context.Background(), slog.LevelDebug, "starting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
+	worker.server.logger.LogAttrs(context.Background(), slog.LevelDebug, "starting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
 }
 
 func tearDownWorkerScript(handler *workerThread, exitStatus int) {
@@ -124,38 +141,41 @@ func tearDownWorkerScript(handler *workerThread, exitStatus int) {
 	// if the worker request is not nil, the script might have crashed
 	// make sure to close the worker request context
 	if handler.workerContext != nil {
+		if handler.workerContext.handlerError == nil {
+			handler.workerContext.handlerError = ErrScriptExecution
+		}
 		handler.workerContext.closeContext()
 		handler.workerContext = nil
 	}
 
 	// on exit status 0 we just run the worker script again
 	if exitStatus == 0 && !handler.isBootingScript {
-		metrics.StopWorker(worker.name, StopReasonRestart)
+		worker.server.metrics.StopWorker(worker.name, StopReasonRestart)
 		handler.backoff.recordSuccess()
-		logger.LogAttrs(ctx, slog.LevelDebug, "restarting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("exit_status", exitStatus))
+		worker.server.logger.LogAttrs(ctx, slog.LevelDebug, "restarting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("exit_status", exitStatus))
 
 		return
 	}
 
 	// worker has thrown a fatal error or has not reached frankenphp_handle_request
-	metrics.StopWorker(worker.name, StopReasonCrash)
+	worker.server.metrics.StopWorker(worker.name, StopReasonCrash)
 
 	if !handler.isBootingScript {
 		// fatal error (could be due to exit(1), timeouts, etc.)
-		logger.LogAttrs(ctx, slog.LevelDebug, "restarting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("exit_status", exitStatus))
+		worker.server.logger.LogAttrs(ctx, slog.LevelDebug, "restarting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("exit_status", exitStatus))
 
 		return
 	}
 
-	logger.LogAttrs(ctx, slog.LevelError, "worker script has not reached frankenphp_handle_request()", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
+	worker.server.logger.LogAttrs(ctx, slog.LevelError, "worker script has not reached frankenphp_handle_request()", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
 
 	// panic after exponential backoff if the worker has never reached frankenphp_handle_request
 	if handler.backoff.recordFailure() {
 		if !watcherIsEnabled && !handler.state.is(stateReady) {
-			logger.LogAttrs(ctx, slog.LevelError, "too many consecutive worker failures", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("failures", handler.backoff.failureCount))
+			worker.server.logger.LogAttrs(ctx, slog.LevelError, "too many consecutive worker failures", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("failures", handler.backoff.failureCount))
 			panic("too many consecutive worker failures")
 		}
-		logger.LogAttrs(ctx, slog.LevelWarn, "many consecutive worker failures", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("failures", handler.backoff.failureCount))
+		worker.server.logger.LogAttrs(ctx, slog.LevelWarn, "many consecutive worker failures", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("failures", handler.backoff.failureCount))
 	}
 }
 
@@ -165,7 +185,7 @@ func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 	handler.thread.Unpin()
 
 	ctx := context.Background()
-	logger.LogAttrs(ctx, slog.LevelDebug, "waiting for request", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex))
+	handler.worker.server.logger.LogAttrs(ctx, slog.LevelDebug, "waiting for request", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex))
 
 	// Clear the first dummy request created to initialize the worker
 	if handler.isBootingScript {
@@ -179,7 +199,7 @@ func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 	// 'stateTransitionComplete' is only true on the first boot of the worker script,
 	// while 'isBootingScript' is true on every boot of the worker script
 	if handler.state.is(stateTransitionComplete) {
-		metrics.ReadyWorker(handler.worker.name)
+		handler.worker.server.metrics.ReadyWorker(handler.worker.name)
 		handler.state.set(stateReady)
 	}
 
@@ -188,7 +208,7 @@ func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 	var fc *frankenPHPContext
 	select {
 	case <-handler.thread.drainChan:
-		logger.LogAttrs(ctx, slog.LevelDebug, "shutting down", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex))
+		handler.worker.server.logger.LogAttrs(ctx, slog.LevelDebug, "shutting down", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex))
 
 		// flush the opcache when restarting due to watcher or admin api
 		// note: this is done right before frankenphp_handle_request() returns 'false'
@@ -205,9 +225,9 @@ func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 	handler.state.markAsWaiting(false)
 
 	if fc.request == nil {
-		logger.LogAttrs(ctx, slog.LevelDebug, "request handling started", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex))
+		handler.worker.server.logger.LogAttrs(ctx, slog.LevelDebug, "request handling started", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex))
 	} else {
-		logger.LogAttrs(ctx, slog.LevelDebug, "request handling started", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex), slog.String("url", fc.request.RequestURI))
+		handler.worker.server.logger.LogAttrs(ctx, slog.LevelDebug, "request handling started", slog.String("worker", handler.worker.name), slog.Int("thread", handler.thread.threadIndex), slog.String("url", fc.request.RequestURI))
 	}
 
 	return true, fc.handlerParameters
@@ -250,6 +270,7 @@ func go_frankenphp_finish_worker_request(threadIndex C.uintptr_t, retval *C.zval
 
 	fc.closeContext()
 	thread.handler.(*workerThread).workerContext = nil
+	thread.releaseAllSemaphores()
 
 	if fc.request == nil {
 		fc.logger.LogAttrs(context.Background(), slog.LevelDebug, "request handling finished", slog.String("worker", fc.worker.name), slog.Int("thread", thread.threadIndex))
@@ -258,6 +279,22 @@ func go_frankenphp_finish_worker_request(threadIndex C.uintptr_t, retval *C.zval
 	}
 }
 
+// go_frankenphp_report_exception is called when the callback passed to frankenphp_handle_request() throws.
+//
+//export go_frankenphp_report_exception
+func go_frankenphp_report_exception(threadIndex C.uintptr_t, className *C.zend_string, message *C.zend_string, code C.zend_long) {
+	fc := phpThreads[threadIndex].getRequestContext()
+	if fc == nil {
+		return
+	}
+
+	fc.handlerError = &PHPException{
+		Class:   GoString(unsafe.Pointer(className)),
+		Message: GoString(unsafe.Pointer(message)),
+		Code:    int64(code),
+	}
+}
+
 // when frankenphp_finish_request() is directly called from PHP
 //
 //export go_frankenphp_finish_php_request
@@ -267,5 +304,9 @@ func go_frankenphp_finish_php_request(threadIndex C.uintptr_t) {
 
 	fc.closeContext()
 
-	fc.logger.LogAttrs(context.Background(), slog.LevelDebug, "request handling finished", slog.Int("thread", thread.threadIndex), slog.String("url", fc.request.RequestURI))
+	if fc.request == nil {
+		fc.logger.LogAttrs(context.Background(), slog.LevelDebug, "request handling finished", slog.Int("thread", thread.threadIndex))
+	} else {
+		fc.logger.LogAttrs(context.Background(), slog.LevelDebug, "request handling finished", slog.Int("thread", thread.threadIndex), slog.String("url", fc.request.RequestURI))
+	}
 }
@@ -0,0 +1,64 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"errors"
+	"net/http"
+	"unsafe"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+var (
+	errInvalidTrailerName  = errors.New("the trailer name is invalid")
+	errInvalidTrailerValue = errors.New("the trailer value is invalid")
+)
+
+// setTrailer stores a trailer to send once the response body is written, it returns false if the response is already finished
+func (fc *frankenPHPContext) setTrailer(name, value string) (bool, error) {
+	if !httpguts.ValidHeaderFieldName(name) {
+		return false, errInvalidTrailerName
+	}
+	if !httpguts.ValidHeaderFieldValue(value) {
+		return false, errInvalidTrailerValue
+	}
+
+	if fc.isDone || fc.responseWriter == nil {
+		return false, nil
+	}
+
+	if fc.trailers == nil {
+		fc.trailers = make(http.Header)
+	}
+	fc.trailers.Set(name, value)
+
+	return true, nil
+}
+
+// writeTrailers must be called when the response body has been entirely written
+func (fc *frankenPHPContext) writeTrailers() {
+	if len(fc.trailers) == 0 {
+		return
+	}
+
+	h := fc.responseWriter.Header()
+	for name, values := range fc.trailers {
+		h[http.TrailerPrefix+name] = values
+	}
+}
+
+//export go_frankenphp_set_trailer
+func go_frankenphp_set_trailer(threadIndex C.uintptr_t, name *C.zend_string, value *C.zend_string) (C.bool, *C.zend_string) {
+	fc := phpThreads[threadIndex].getRequestContext()
+	if fc == nil {
+		return C.bool(false), nil
+	}
+
+	ok, err := fc.setTrailer(GoString(unsafe.Pointer(name)), GoString(unsafe.Pointer(value)))
+	if err != nil {
+		return C.bool(false), (*C.zend_string)(PHPString(err.Error(), false))
+	}
+
+	return C.bool(ok), nil
+}
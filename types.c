@@ -34,3 +34,8 @@ void __zval_string__(zval *zv, zend_string *str) { ZVAL_STR(zv, str); }
 void __zval_arr__(zval *zv, zend_array *arr) { ZVAL_ARR(zv, arr); }
 
 zend_array *__zend_new_array__(uint32_t size) { return zend_new_array(size); }
+
+void __zend_symtable_str_update__(HashTable *ht, const char *key, size_t len,
+                                  zval *pData) {
+  zend_symtable_str_update(ht, key, len, pData);
+}
@@ -170,6 +170,7 @@ func PHPAssociativeArray(arr AssociativeArray) unsafe.Pointer {
 	return phpArray(arr.Map, arr.Order)
 }
 
+// phpArray converts the entries to a zend_array, numeric string keys become integer keys as they do in PHP
 func phpArray(entries map[string]any, order []string) unsafe.Pointer {
 	var zendArray *C.HashTable
 
@@ -178,13 +179,13 @@ func phpArray(entries map[string]any, order []string) unsafe.Pointer {
 		for _, key := range order {
 			val := entries[key]
 			zval := phpValue(val)
-			C.zend_hash_str_update(zendArray, toUnsafeChar(key), C.size_t(len(key)), zval)
+			C.__zend_symtable_str_update__(zendArray, toUnsafeChar(key), C.size_t(len(key)), zval)
 		}
 	} else {
 		zendArray = createNewArray((uint32)(len(entries)))
 		for key, val := range entries {
 			zval := phpValue(val)
-			C.zend_hash_str_update(zendArray, toUnsafeChar(key), C.size_t(len(key)), zval)
+			C.__zend_symtable_str_update__(zendArray, toUnsafeChar(key), C.size_t(len(key)), zval)
 		}
 	}
 
@@ -291,6 +292,34 @@ func phpValue(value any) *C.zval {
 	return &zval
 }
 
+// checkPHPValue makes sure that phpValue will be able to convert the value
+func checkPHPValue(value any) error {
+	switch v := value.(type) {
+	case nil, bool, int, int64, float64, string:
+		return nil
+	case AssociativeArray:
+		return checkPHPValue(v.Map)
+	case map[string]any:
+		for _, val := range v {
+			if err := checkPHPValue(val); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case []any:
+		for _, val := range v {
+			if err := checkPHPValue(val); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported Go type %T", v)
+	}
+}
+
 // createNewArray creates a new zend_array with the specified size.
 func createNewArray(size uint32) *C.HashTable {
 	arr := C.__zend_new_array__(C.uint32_t(size))
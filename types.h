@@ -21,5 +21,7 @@ void __zval_double__(zval *zv, double val);
 void __zval_string__(zval *zv, zend_string *str);
 void __zval_arr__(zval *zv, zend_array *arr);
 zend_array *__zend_new_array__(uint32_t size);
+void __zend_symtable_str_update__(HashTable *ht, const char *key, size_t len,
+                                  zval *pData);
 
 #endif
@@ -0,0 +1,350 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/net/websocket"
+)
+
+// webSocketOutboxSize is the number of frames waiting to be written to a connection before new ones are dropped
+const webSocketOutboxSize = 64
+
+// webSocketConn is a connection kept open by Go, PHP threads are only used while its events are handled
+type webSocketConn struct {
+	id     string
+	server *Server
+	ws     *websocket.Conn
+	outbox chan webSocketFrame
+	// closed is closed when the connection must be closed, outbox is never closed to not panic on concurrent sends
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type webSocketFrame struct {
+	data   []byte
+	binary bool
+}
+
+var (
+	webSocketConnsMu sync.RWMutex
+	webSocketConns   = make(map[string]*webSocketConn)
+	webSocketWg      sync.WaitGroup
+)
+
+var errWebSocketOriginNotAllowed = errors.New("origin not allowed")
+
+// WebSocketOption instances allow configuring ServeWebSocket.
+type WebSocketOption func(*webSocketOpt) error
+
+type webSocketOpt struct {
+	allowedOrigins []string
+}
+
+// EXPERIMENTAL: WithWebSocketAllowedOrigins allows connections from cross-origin pages.
+// Origins are compared with the Origin header, such as "https://example.com", "*" allows all origins.
+func WithWebSocketAllowedOrigins(origins ...string) WebSocketOption {
+	return func(o *webSocketOpt) error {
+		o.allowedOrigins = append(o.allowedOrigins, origins...)
+
+		return nil
+	}
+}
+
+// checkOrigin prevents cross-site WebSocket hijacking: browsers send the cookies of the site with the upgrade requests
+// made by any page, so only the pages of the same host and the allowed origins can connect.
+// Requests without Origin header aren't sent by browsers and are accepted.
+func (o *webSocketOpt) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+
+	for _, allowed := range o.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q", errWebSocketOriginNotAllowed, origin)
+}
+
+// webSocketCodec keeps the type of the received frames
+var webSocketCodec = websocket.Codec{
+	Marshal: func(v any) ([]byte, byte, error) {
+		f := v.(webSocketFrame)
+		if f.binary {
+			return f.data, websocket.BinaryFrame, nil
+		}
+
+		return f.data, websocket.TextFrame, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v any) error {
+		*v.(*webSocketFrame) = webSocketFrame{data: data, binary: payloadType == websocket.BinaryFrame}
+
+		return nil
+	},
+}
+
+// EXPERIMENTAL: ServeWebSocket serves the WebSocket connection with the running server, see Server.ServeWebSocket.
+func ServeWebSocket(w http.ResponseWriter, r *http.Request, workerName string, options ...WebSocketOption) error {
+	s := activeServer.Load()
+	if s == nil {
+		return ErrNotRunning
+	}
+
+	return s.ServeWebSocket(w, r, workerName, options...)
+}
+
+// EXPERIMENTAL: ServeWebSocket upgrades the request to a WebSocket connection and dispatches its events
+// to the callback of the worker named workerName.
+//
+// The callback receives an array containing the "event" ("open", "message" or "close") and the "connection" ID.
+// Open events also contain the "uri" and the "headers" of the request, the connection is closed if the callback returns false.
+// Message events also contain the "data" and whether it is "binary".
+//
+// Connections from pages of other hosts than the one of the request are refused with a 403 status,
+// unless their origin is allowed with WithWebSocketAllowedOrigins.
+//
+// Connections don't use a PHP thread while they are idle. ServeWebSocket returns when the connection is closed.
+func (s *Server) ServeWebSocket(w http.ResponseWriter, r *http.Request, workerName string, options ...WebSocketOption) error {
+	if !s.Running() {
+		return ErrNotRunning
+	}
+
+	if s.getWorkerByName(workerName) == nil {
+		return fmt.Errorf("%w: %q", ErrWorkerNotFound, workerName)
+	}
+
+	opt := &webSocketOpt{}
+	for _, o := range options {
+		if err := o(opt); err != nil {
+			return err
+		}
+	}
+
+	webSocketWg.Add(1)
+	defer webSocketWg.Done()
+
+	websocket.Server{
+		// once the origin is checked, the worker decides whether to accept the connection when handling the open event
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			if err := opt.checkOrigin(r); err != nil {
+				s.logger.LogAttrs(r.Context(), slog.LevelDebug, "WebSocket connection refused", slog.Any("error", err))
+
+				return err
+			}
+
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			c := &webSocketConn{
+				id:     rand.Text(),
+				server: s,
+				ws:     ws,
+				outbox: make(chan webSocketFrame, webSocketOutboxSize),
+				closed: make(chan struct{}),
+			}
+
+			c.serve(r, workerName)
+		},
+	}.ServeHTTP(w, r)
+
+	return nil
+}
+
+func (c *webSocketConn) serve(r *http.Request, workerName string) {
+	webSocketConnsMu.Lock()
+	webSocketConns[c.id] = c
+	webSocketConnsMu.Unlock()
+
+	defer func() {
+		webSocketConnsMu.Lock()
+		delete(webSocketConns, c.id)
+		webSocketConnsMu.Unlock()
+
+		c.close()
+	}()
+
+	go c.write()
+
+	headers := AssociativeArray{Map: make(map[string]any, len(r.Header))}
+	for name, values := range r.Header {
+		headers.Map[name] = strings.Join(values, ", ")
+		headers.Order = append(headers.Order, name)
+	}
+
+	ret, err := c.dispatch(workerName, "open", "uri", r.RequestURI, "headers", headers)
+	if accepted, ok := ret.(bool); err != nil || (ok && !accepted) {
+		return
+	}
+
+	for {
+		var f webSocketFrame
+		if err := webSocketCodec.Receive(c.ws, &f); err != nil {
+			break
+		}
+
+		if _, err := c.dispatch(workerName, "message", "data", string(f.data), "binary", f.binary); err != nil {
+			break
+		}
+	}
+
+	_, _ = c.dispatch(workerName, "close")
+}
+
+// dispatch calls the worker with the event, the connection ID and the additional key/value pairs
+func (c *webSocketConn) dispatch(workerName string, event string, keyValues ...any) (any, error) {
+	params := AssociativeArray{
+		Map:   map[string]any{"event": event, "connection": c.id},
+		Order: []string{"event", "connection"},
+	}
+	for i := 0; i < len(keyValues); i += 2 {
+		k := keyValues[i].(string)
+		params.Map[k] = keyValues[i+1]
+		params.Order = append(params.Order, k)
+	}
+
+	ret, err := c.server.Call(context.Background(), workerName, params)
+	if err != nil {
+		c.server.logger.LogAttrs(context.Background(), slog.LevelError, "unable to handle the WebSocket event", slog.String("worker", workerName), slog.String("event", event), slog.String("connection", c.id), slog.Any("error", err))
+	}
+
+	return ret, err
+}
+
+// write sends the queued frames until the connection is closed
+func (c *webSocketConn) write() {
+	for {
+		select {
+		case f := <-c.outbox:
+			if err := webSocketCodec.Send(c.ws, f); err != nil {
+				c.close()
+
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// send queues a frame without blocking, it returns false if the connection is closed or too slow
+func (c *webSocketConn) send(f webSocketFrame) bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+
+	select {
+	case c.outbox <- f:
+		return true
+	default:
+		c.server.logger.LogAttrs(context.Background(), slog.LevelWarn, "WebSocket connection too slow, dropping the frame", slog.String("connection", c.id))
+
+		return false
+	}
+}
+
+func (c *webSocketConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.ws.Close()
+	})
+}
+
+func getWebSocketConn(id string) *webSocketConn {
+	webSocketConnsMu.RLock()
+	defer webSocketConnsMu.RUnlock()
+
+	return webSocketConns[id]
+}
+
+// drainWebSockets closes all connections and waits for their close events to be handled
+func drainWebSockets() {
+	webSocketConnsMu.RLock()
+	for _, c := range webSocketConns {
+		c.close()
+	}
+	webSocketConnsMu.RUnlock()
+
+	webSocketWg.Wait()
+}
+
+//export go_frankenphp_ws_send
+func go_frankenphp_ws_send(id *C.zend_string, data *C.zend_string, binary C.bool) C.bool {
+	c := getWebSocketConn(GoString(unsafe.Pointer(id)))
+	if c == nil {
+		return C.bool(false)
+	}
+
+	return C.bool(c.send(webSocketFrame{data: []byte(GoString(unsafe.Pointer(data))), binary: bool(binary)}))
+}
+
+//export go_frankenphp_ws_broadcast
+func go_frankenphp_ws_broadcast(data *C.zend_string, ids *C.zval, binary C.bool) C.zend_long {
+	f := webSocketFrame{data: []byte(GoString(unsafe.Pointer(data))), binary: bool(binary)}
+
+	var conns []*webSocketConn
+	if ids == nil {
+		webSocketConnsMu.RLock()
+		for _, c := range webSocketConns {
+			conns = append(conns, c)
+		}
+		webSocketConnsMu.RUnlock()
+	} else {
+		var values []any
+		switch v := GoValue(unsafe.Pointer(ids)).(type) {
+		case []any:
+			values = v
+		case AssociativeArray:
+			for _, k := range v.Order {
+				values = append(values, v.Map[k])
+			}
+		}
+
+		for _, id := range values {
+			if s, ok := id.(string); ok {
+				if c := getWebSocketConn(s); c != nil {
+					conns = append(conns, c)
+				}
+			}
+		}
+	}
+
+	sent := 0
+	for _, c := range conns {
+		if c.send(f) {
+			sent++
+		}
+	}
+
+	return C.zend_long(sent)
+}
+
+//export go_frankenphp_ws_close
+func go_frankenphp_ws_close(id *C.zend_string) C.bool {
+	c := getWebSocketConn(GoString(unsafe.Pointer(id)))
+	if c == nil {
+		return C.bool(false)
+	}
+
+	c.close()
+
+	return C.bool(true)
+}
@@ -0,0 +1,126 @@
+package frankenphp_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// startWebSocketServer returns the URL of the server and the origin of its pages
+func startWebSocketServer(t *testing.T, options ...frankenphp.WebSocketOption) (string, string) {
+	t.Helper()
+
+	cwd, _ := os.Getwd()
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		frankenphp.WithWorkers("websocket", cwd+"/testdata/worker-websocket.php", 1),
+	))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, frankenphp.ServeWebSocket(w, r, "websocket", options...))
+	}))
+	t.Cleanup(func() {
+		// connections must be closed before the server
+		frankenphp.Shutdown()
+		ts.Close()
+	})
+
+	return "ws" + strings.TrimPrefix(ts.URL, "http"), ts.URL
+}
+
+func receiveWebSocketMessage(t *testing.T, ws *websocket.Conn) string {
+	t.Helper()
+
+	var msg string
+	require.NoError(t, websocket.Message.Receive(ws, &msg))
+
+	return msg
+}
+
+func TestWebSocket(t *testing.T) {
+	url, origin := startWebSocketServer(t)
+
+	ws1, err := websocket.Dial(url+"/chat", "", origin)
+	require.NoError(t, err)
+	defer ws1.Close()
+	assert.Equal(t, "welcome /chat", receiveWebSocketMessage(t, ws1))
+
+	ws2, err := websocket.Dial(url+"/chat", "", origin)
+	require.NoError(t, err)
+	defer ws2.Close()
+	assert.Equal(t, "welcome /chat", receiveWebSocketMessage(t, ws2))
+
+	require.NoError(t, websocket.Message.Send(ws1, "hello"))
+	assert.Equal(t, "echo: hello", receiveWebSocketMessage(t, ws1))
+
+	require.NoError(t, websocket.Message.Send(ws1, []byte("binary")))
+	var binary []byte
+	require.NoError(t, websocket.Message.Receive(ws1, &binary))
+	assert.Equal(t, []byte("echo: binary"), binary)
+
+	require.NoError(t, websocket.Message.Send(ws2, "broadcast"))
+	assert.Equal(t, "to everyone", receiveWebSocketMessage(t, ws1))
+	assert.Equal(t, "to everyone", receiveWebSocketMessage(t, ws2))
+
+	require.NoError(t, websocket.Message.Send(ws2, "close"))
+	var msg string
+	assert.Error(t, websocket.Message.Receive(ws2, &msg))
+
+	// the close event is dispatched to the worker
+	assert.Equal(t, "bye", receiveWebSocketMessage(t, ws1))
+}
+
+func TestWebSocketRejectedByTheWorker(t *testing.T) {
+	url, origin := startWebSocketServer(t)
+
+	config, err := websocket.NewConfig(url+"/chat", origin)
+	require.NoError(t, err)
+	config.Header.Set("X-Reject", "1")
+
+	ws, err := websocket.DialConfig(config)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var msg string
+	assert.Error(t, websocket.Message.Receive(ws, &msg))
+}
+
+func TestWebSocketRejectsCrossOriginConnections(t *testing.T) {
+	url, _ := startWebSocketServer(t)
+
+	_, err := websocket.Dial(url+"/chat", "", "https://attacker.example")
+	require.Error(t, err)
+
+	var status *websocket.DialError
+	require.ErrorAs(t, err, &status)
+	assert.ErrorIs(t, status.Err, websocket.ErrBadStatus)
+}
+
+func TestWebSocketAllowedOrigins(t *testing.T) {
+	url, _ := startWebSocketServer(t, frankenphp.WithWebSocketAllowedOrigins("https://app.example"))
+
+	ws, err := websocket.Dial(url+"/chat", "", "https://app.example")
+	require.NoError(t, err)
+	defer ws.Close()
+	assert.Equal(t, "welcome /chat", receiveWebSocketMessage(t, ws))
+
+	_, err = websocket.Dial(url+"/chat", "", "https://other.example")
+	assert.Error(t, err)
+}
+
+func TestWebSocketUnknownWorker(t *testing.T) {
+	require.NoError(t, frankenphp.Init(frankenphp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	defer frankenphp.Shutdown()
+
+	err := frankenphp.ServeWebSocket(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "unknown")
+	assert.ErrorIs(t, err, frankenphp.ErrWorkerNotFound)
+}
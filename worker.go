@@ -23,40 +23,36 @@ type worker struct {
 	threadMutex            sync.RWMutex
 	allowPathMatching      bool
 	maxConsecutiveFailures int
+
+	// server is the server the worker belongs to
+	server *Server
 }
 
-var (
-	workers          []*worker
-	watcherIsEnabled bool
-)
+var watcherIsEnabled bool
 
-func initWorkers(opt []workerOpt) error {
-	workers = make([]*worker, 0, len(opt))
+func (s *Server) initWorkers(opt []workerOpt) error {
+	initExtensionWorkers()
+
+	s.workers = make([]*worker, 0, len(opt))
 	workersReady := sync.WaitGroup{}
 	directoriesToWatch := getDirectoriesToWatch(opt)
 	watcherIsEnabled = len(directoriesToWatch) > 0
 
 	for _, o := range opt {
-		w, err := newWorker(o)
+		w, err := s.newWorker(o)
 		if err != nil {
 			return err
 		}
-		workers = append(workers, w)
+		s.workers = append(s.workers, w)
 	}
 
-	for _, w := range workers {
+	for _, w := range s.workers {
 		workersReady.Add(w.num)
 		for i := 0; i < w.num; i++ {
 			thread := getInactivePHPThread()
 			convertToWorkerThread(thread, w)
 			go func() {
 				thread.state.waitFor(stateReady)
-
-				// create a pipe from the external worker to the main worker
-				// note: this is locked to the initial thread size the external worker requested
-				if workerThread, ok := thread.handler.(*workerThread); ok && workerThread.externalWorker != nil {
-					go startWorker(w, workerThread.externalWorker, thread)
-				}
 				workersReady.Done()
 			}()
 		}
@@ -69,15 +65,15 @@ func initWorkers(opt []workerOpt) error {
 	}
 
 	watcherIsEnabled = true
-	if err := watcher.InitWatcher(directoriesToWatch, RestartWorkers, logger); err != nil {
+	if err := watcher.InitWatcher(directoriesToWatch, s.RestartWorkers, s.logger); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func getWorkerByName(name string) *worker {
-	for _, w := range workers {
+func (s *Server) getWorkerByName(name string) *worker {
+	for _, w := range s.workers {
 		if w.name == name {
 			return w
 		}
@@ -86,8 +82,8 @@ func getWorkerByName(name string) *worker {
 	return nil
 }
 
-func getWorkerByPath(path string) *worker {
-	for _, w := range workers {
+func (s *Server) getWorkerByPath(path string) *worker {
+	for _, w := range s.workers {
 		if w.fileName == path && w.allowPathMatching {
 			return w
 		}
@@ -96,7 +92,7 @@ func getWorkerByPath(path string) *worker {
 	return nil
 }
 
-func newWorker(o workerOpt) (*worker, error) {
+func (s *Server) newWorker(o workerOpt) (*worker, error) {
 	absFileName, err := fastabs.FastAbs(o.fileName)
 	if err != nil {
 		return nil, fmt.Errorf("worker filename is invalid %q: %w", o.fileName, err)
@@ -110,10 +106,10 @@ func newWorker(o workerOpt) (*worker, error) {
 	// they can only be matched by their name, not by their path
 	allowPathMatching := !strings.HasPrefix(o.name, "m#")
 
-	if w := getWorkerByPath(absFileName); w != nil && allowPathMatching {
+	if w := s.getWorkerByPath(absFileName); w != nil && allowPathMatching {
 		return w, fmt.Errorf("two workers cannot have the same filename: %q", absFileName)
 	}
-	if w := getWorkerByName(o.name); w != nil {
+	if w := s.getWorkerByName(o.name); w != nil {
 		return w, fmt.Errorf("two workers cannot have the same name: %q", o.name)
 	}
 
@@ -131,17 +127,32 @@ func newWorker(o workerOpt) (*worker, error) {
 		threads:                make([]*phpThread, 0, o.num),
 		allowPathMatching:      allowPathMatching,
 		maxConsecutiveFailures: o.maxConsecutiveFailures,
+		server:                 s,
 	}
 
 	return w, nil
 }
 
-// EXPERIMENTAL: DrainWorkers finishes all worker scripts before a graceful shutdown
+// EXPERIMENTAL: DrainWorkers finishes all worker scripts of the running server before a graceful shutdown
 func DrainWorkers() {
-	_ = drainWorkerThreads()
+	if s := activeServer.Load(); s != nil {
+		s.DrainWorkers()
+	}
 }
 
-func drainWorkerThreads() []*phpThread {
+// EXPERIMENTAL: DrainWorkers finishes all worker scripts before a graceful shutdown
+func (s *Server) DrainWorkers() {
+	if !s.Running() {
+		return
+	}
+
+	s.drainSchedule()
+	drainWebSockets()
+	drainStreams()
+	_ = drainWorkerThreads(s.workers)
+}
+
+func drainWorkerThreads(workers []*worker) []*phpThread {
 	ready := sync.WaitGroup{}
 	drainedThreads := make([]*phpThread, 0)
 	for _, worker := range workers {
@@ -174,13 +185,24 @@ func drainWatcher() {
 	}
 }
 
-// RestartWorkers attempts to restart all workers gracefully
+// RestartWorkers attempts to restart all workers of the running server gracefully
 func RestartWorkers() {
+	if s := activeServer.Load(); s != nil {
+		s.RestartWorkers()
+	}
+}
+
+// RestartWorkers attempts to restart all workers gracefully
+func (s *Server) RestartWorkers() {
+	if !s.Running() {
+		return
+	}
+
 	// disallow scaling threads while restarting workers
 	scalingMu.Lock()
 	defer scalingMu.Unlock()
 
-	threadsToRestart := drainWorkerThreads()
+	threadsToRestart := drainWorkerThreads(s.workers)
 
 	for _, thread := range threadsToRestart {
 		thread.drainChan = make(chan struct{})
@@ -222,39 +244,57 @@ func (worker *worker) countThreads() int {
 }
 
 func (worker *worker) handleRequest(fc *frankenPHPContext) {
-	metrics.StartWorkerRequest(worker.name)
+	if !worker.queueRequest(fc, nil) {
+		return
+	}
 
+	// the request is only counted once a thread has taken it, it would never be stopped otherwise
+	worker.server.metrics.StartWorkerRequest(worker.name)
+
+	<-fc.done
+	worker.server.metrics.StopWorkerRequest(worker.name, time.Since(fc.startedAt))
+}
+
+// queueRequest hands the request over to a worker thread
+// it returns false if cancel is closed or max_wait_time is exceeded before a thread picks up the request
+func (worker *worker) queueRequest(fc *frankenPHPContext, cancel <-chan struct{}) bool {
 	// dispatch requests to all worker threads in order
 	worker.threadMutex.RLock()
 	for _, thread := range worker.threads {
 		select {
 		case thread.requestChan <- fc:
 			worker.threadMutex.RUnlock()
-			<-fc.done
-			metrics.StopWorkerRequest(worker.name, time.Since(fc.startedAt))
-			return
+			return true
 		default:
 			// thread is busy, continue
 		}
 	}
 	worker.threadMutex.RUnlock()
 
+	if fc.isSubRequest {
+		fc.rejectSubRequest()
+
+		return false
+	}
+
 	// if no thread was available, mark the request as queued and apply the scaling strategy
-	metrics.QueuedWorkerRequest(worker.name)
+	worker.server.metrics.QueuedWorkerRequest(worker.name)
 	for {
 		select {
 		case worker.requestChan <- fc:
-			metrics.DequeuedWorkerRequest(worker.name)
-			<-fc.done
-			metrics.StopWorkerRequest(worker.name, time.Since(fc.startedAt))
-			return
+			worker.server.metrics.DequeuedWorkerRequest(worker.name)
+			return true
 		case scaleChan <- fc:
 			// the request has triggered scaling, continue to wait for a thread
-		case <-timeoutChan(maxWaitTime):
-			metrics.DequeuedWorkerRequest(worker.name)
+		case <-cancel:
+			worker.server.metrics.DequeuedWorkerRequest(worker.name)
+			fc.closeContext()
+			return false
+		case <-timeoutChan(worker.server.maxWaitTime):
+			worker.server.metrics.DequeuedWorkerRequest(worker.name)
 			// the request has timed out stalling
 			fc.reject(504, "Gateway Timeout")
-			return
+			return false
 		}
 	}
 }
@@ -2,10 +2,12 @@ package frankenphp
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // EXPERIMENTAL: Worker allows you to register a worker where instead of calling FrankenPHP handlers on
@@ -29,6 +31,8 @@ import (
 // Note: External workers receive the lowest priority when determining thread allocations. If GetMinThreads cannot be
 // allocated, then frankenphp will panic and provide this information to the user (who will need to allocate more
 // total threads). Don't be greedy.
+//
+// Deprecated: ProvideRequest cannot be interrupted, implement WorkerV2 instead.
 type Worker interface {
 	Name() string
 	FileName() string
@@ -41,69 +45,247 @@ type Worker interface {
 	InjectRequest(r *WorkerRequest)
 }
 
+// EXPERIMENTAL: WorkerV2 is a context-aware version of Worker.
+//
+// ProvideRequest receives a context that is cancelled when the thread it feeds is removed from the worker
+// or when FrankenPHP shuts down, it must then return as soon as possible with ctx.Err().
+// Any other error is logged and ProvideRequest is called again after a backoff.
+//
+// Each thread of the worker calls ProvideRequest concurrently and the returned request is handled by
+// whichever thread is free, so ProvideRequest must be safe for concurrent use and blocking in it is the
+// way to apply backpressure. The number of threads can be changed at runtime with ScaleWorker.
+type WorkerV2 interface {
+	Name() string
+	FileName() string
+	Env() PreparedEnv
+	GetMinThreads() int
+	ThreadActivatedNotification(threadId int)
+	ThreadDrainNotification(threadId int)
+	ThreadDeactivatedNotification(threadId int)
+	ProvideRequest(ctx context.Context) (*WorkerRequest, error)
+}
+
 // EXPERIMENTAL
 type WorkerRequest struct {
 	// The request for your worker script to handle
 	Request *http.Request
 	// Response is a response writer that provides the output of the provided request, it must not be nil to access the request body
 	Response http.ResponseWriter
+	// Context is an optional context used when Request is nil, the request is dropped if it is done before a thread picks it up
+	Context context.Context
 	// CallbackParameters is an optional field that will be converted in PHP types and passed as parameter to the PHP callback
 	CallbackParameters any
 	// AfterFunc is an optional function that will be called after the request is processed with the original value, the return of the PHP callback, converted in Go types, is passed as parameter
 	AfterFunc func(callbackReturn any)
+	// ErrorFunc is an optional function that will be called instead of AfterFunc if the request could not be processed
+	ErrorFunc func(err error)
 }
 
-var extensionWorkers = make(map[string]Worker)
-var extensionWorkersMutex sync.Mutex
+var (
+	extensionWorkers      = make(map[string]WorkerV2)
+	extensionWorkersMutex sync.Mutex
+
+	// extensionWorkersCtx is cancelled when FrankenPHP shuts down
+	extensionWorkersCtx                       = context.Background()
+	cancelExtensionWorkers context.CancelFunc = func() {}
+)
 
 // EXPERIMENTAL
+//
+// Deprecated: use RegisterWorkerV2 instead.
 func RegisterWorker(worker Worker) {
+	RegisterWorkerV2(newLegacyWorker(worker))
+}
+
+// EXPERIMENTAL
+func RegisterWorkerV2(worker WorkerV2) {
 	extensionWorkersMutex.Lock()
 	defer extensionWorkersMutex.Unlock()
 
 	extensionWorkers[worker.Name()] = worker
 }
 
-// startWorker creates a pipe from a worker to the main worker.
-func startWorker(w *worker, extensionWorker Worker, thread *phpThread) {
-	for {
-		rq := extensionWorker.ProvideRequest()
+// EXPERIMENTAL: ScaleWorker changes the number of threads assigned to a registered extension worker.
+func ScaleWorker(name string, numThreads int) error {
+	s := activeServer.Load()
+	if s == nil {
+		return ErrNotRunning
+	}
 
-		var fc *frankenPHPContext
-		if rq.Request == nil {
-			fc = newFrankenPHPContext()
-			fc.logger = logger
-		} else {
-			fr, err := NewRequestWithContext(rq.Request, WithOriginalRequest(rq.Request))
-			if err != nil {
-				logger.LogAttrs(context.Background(), slog.LevelError, "error creating request for external worker", slog.String("worker", w.name), slog.Int("thread", thread.threadIndex), slog.Any("error", err))
-				continue
+	if numThreads < 1 {
+		return fmt.Errorf("the number of threads must be positive, got %d", numThreads)
+	}
+
+	extensionWorkersMutex.Lock()
+	_, ok := extensionWorkers[name]
+	extensionWorkersMutex.Unlock()
+
+	w := s.getWorkerByName(name)
+	if !ok || w == nil {
+		return fmt.Errorf("%w: %q", ErrWorkerNotFound, name)
+	}
+
+	scalingMu.Lock()
+	defer scalingMu.Unlock()
+
+	for i := w.countThreads(); i < numThreads; i++ {
+		if _, err := addWorkerThread(w); err != nil {
+			return err
+		}
+	}
+
+	for i := w.countThreads(); i > numThreads; i-- {
+		removeWorkerThread(w)
+	}
+
+	return nil
+}
+
+func initExtensionWorkers() {
+	extensionWorkersCtx, cancelExtensionWorkers = context.WithCancel(context.Background())
+}
+
+func drainExtensionWorkers() {
+	cancelExtensionWorkers()
+}
+
+// startWorker creates a pipe from a worker to the main worker, it stops once ctx is done.
+func startWorker(ctx context.Context, w *worker, extensionWorker WorkerV2, thread *phpThread) {
+	backoff := &exponentialBackoff{
+		maxBackoff:             1 * time.Second,
+		minBackoff:             100 * time.Millisecond,
+		maxConsecutiveFailures: -1,
+	}
+
+	for {
+		rq, err := extensionWorker.ProvideRequest(ctx)
+		if ctx.Err() != nil {
+			if rq != nil {
+				rq.done(nil, ctx.Err())
 			}
 
-			var ok bool
-			if fc, ok = fromContext(fr.Context()); !ok {
-				continue
+			return
+		}
+
+		if err != nil {
+			w.server.logger.LogAttrs(ctx, slog.LevelError, "external worker failed to provide a request", slog.String("worker", w.name), slog.Int("thread", thread.threadIndex), slog.Any("error", err))
+			backoff.recordFailure()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff.backoff):
 			}
+
+			continue
 		}
 
-		fc.worker = w
+		backoff.recordSuccess()
+		if rq != nil {
+			handleExternalWorkerRequest(ctx, w, rq, thread)
+		}
+	}
+}
+
+// handleExternalWorkerRequest queues the request and waits for it to be processed
+func handleExternalWorkerRequest(ctx context.Context, w *worker, rq *WorkerRequest, thread *phpThread) {
+	var fc *frankenPHPContext
+	if rq.Request == nil {
+		fc = newFrankenPHPContext()
+		fc.logger = w.server.logger
+
+		requestCtx := rq.Context
+		if requestCtx == nil {
+			requestCtx = context.Background()
+		}
+		fc.ctx, fc.cancel = context.WithCancel(requestCtx)
+	} else {
+		fr, err := NewRequestWithContext(rq.Request, WithOriginalRequest(rq.Request))
+		if err != nil {
+			w.server.logger.LogAttrs(ctx, slog.LevelError, "error creating request for external worker", slog.String("worker", w.name), slog.Int("thread", thread.threadIndex), slog.Any("error", err))
+			rq.done(nil, err)
+
+			return
+		}
+
+		fc, _ = fromContext(fr.Context())
+	}
 
-		fc.responseWriter = rq.Response
-		fc.handlerParameters = rq.CallbackParameters
+	fc.server = w.server
+	fc.worker = w
 
-		// Queue the request and wait for completion if Done channel was provided
-		logger.LogAttrs(context.Background(), slog.LevelInfo, "queue the external worker request", slog.String("worker", w.name), slog.Int("thread", thread.threadIndex))
+	fc.responseWriter = rq.Response
+	fc.handlerParameters = rq.CallbackParameters
 
-		w.requestChan <- fc
-		if rq.AfterFunc != nil {
-			go func() {
-				<-fc.done
+	// stop waiting for a thread if the request or the pipe is cancelled
+	queueCtx, cancel := context.WithCancel(fc.ctx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
 
-				if rq.AfterFunc != nil {
-					rq.AfterFunc(fc.handlerReturn)
-				}
-			}()
+	w.server.logger.LogAttrs(ctx, slog.LevelDebug, "queue the external worker request", slog.String("worker", w.name), slog.Int("thread", thread.threadIndex))
+
+	if !w.queueRequest(fc, queueCtx.Done()) {
+		if err := queueCtx.Err(); err != nil {
+			rq.done(nil, err)
+		} else {
+			rq.done(nil, ErrMaxWaitTimeExceeded)
 		}
+
+		return
+	}
+	w.server.metrics.StartWorkerRequest(w.name)
+
+	<-fc.done
+	w.server.metrics.StopWorkerRequest(w.name, time.Since(fc.startedAt))
+
+	rq.done(fc.handlerReturn, fc.handlerError)
+}
+
+// done reports the outcome of the request to the extension
+func (rq *WorkerRequest) done(callbackReturn any, err error) {
+	if err != nil && rq.ErrorFunc != nil {
+		rq.ErrorFunc(err)
+
+		return
+	}
+
+	if rq.AfterFunc != nil {
+		rq.AfterFunc(callbackReturn)
+	}
+}
+
+// legacyWorker adapts a Worker to the WorkerV2 interface
+type legacyWorker struct {
+	Worker
+
+	// requests is fed by a single goroutine, Worker.ProvideRequest() cannot be interrupted
+	// and the request it returns must be kept for the next call when the previous one has been cancelled
+	requests chan *WorkerRequest
+
+	mu sync.Mutex
+	// feeding is the context the feeding goroutine stops with, nil if it isn't running
+	feeding context.Context
+	// pending holds the request provided after the feeding goroutine has been stopped
+	pending *WorkerRequest
+}
+
+func newLegacyWorker(w Worker) *legacyWorker {
+	return &legacyWorker{Worker: w, requests: make(chan *WorkerRequest)}
+}
+
+func (w *legacyWorker) ProvideRequest(ctx context.Context) (*WorkerRequest, error) {
+	if dw, ok := w.Worker.(*defaultWorker); ok {
+		return dw.provideRequest(ctx)
+	}
+
+	w.startFeeding()
+
+	select {
+	case rq := <-w.requests:
+		return rq, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
@@ -162,6 +344,51 @@ func (w *defaultWorker) ProvideRequest() *WorkerRequest {
 	return <-w.requestChan
 }
 
+func (w *defaultWorker) provideRequest(ctx context.Context) (*WorkerRequest, error) {
+	select {
+	case rq := <-w.requestChan:
+		return rq, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func (w *defaultWorker) InjectRequest(r *WorkerRequest) {
 	w.requestChan <- r
 }
+
+// startFeeding starts the goroutine feeding w.requests unless it is already running for the current run of FrankenPHP,
+// the goroutine stops with the worker's context, as soon as the blocked Worker.ProvideRequest() call returns
+func (w *legacyWorker) startFeeding() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.feeding != nil && w.feeding.Err() == nil {
+		return
+	}
+
+	ctx := extensionWorkersCtx
+	w.feeding = ctx
+	pending := w.pending
+	w.pending = nil
+
+	go func() {
+		rq := pending
+		for {
+			if rq == nil {
+				rq = w.Worker.ProvideRequest()
+			}
+
+			select {
+			case w.requests <- rq:
+				rq = nil
+			case <-ctx.Done():
+				w.mu.Lock()
+				w.pending = rq
+				w.mu.Unlock()
+
+				return
+			}
+		}
+	}()
+}
@@ -1,8 +1,11 @@
 package frankenphp
 
 import (
+	"context"
 	"io"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -69,3 +72,132 @@ func TestWorkerExtension(t *testing.T) {
 	// We're just checking that we got a response, not the specific content
 	assert.NotEmpty(t, body, "Response body should not be empty")
 }
+
+// mockWorkerV2 implements the WorkerV2 interface on top of the default worker
+type mockWorkerV2 struct {
+	*defaultWorker
+	waiting atomic.Int32
+}
+
+func (w *mockWorkerV2) ProvideRequest(ctx context.Context) (*WorkerRequest, error) {
+	w.waiting.Add(1)
+	defer w.waiting.Add(-1)
+
+	return w.provideRequest(ctx)
+}
+
+func TestWorkerExtensionV2(t *testing.T) {
+	mockExt := &mockWorkerV2{
+		defaultWorker: NewWorker("mockWorkerV2", "testdata/worker-call.php", 1, nil).(*defaultWorker),
+	}
+
+	RegisterWorkerV2(mockExt)
+	defer func() {
+		delete(extensionWorkers, mockExt.Name())
+	}()
+
+	require.NoError(t, Init(WithNumThreads(2), WithMaxThreads(4)))
+	shutdown := sync.OnceFunc(Shutdown)
+	defer shutdown()
+
+	returnChan := make(chan any, 1)
+	mockExt.InjectRequest(&WorkerRequest{
+		CallbackParameters: map[string]any{"a": 1, "b": 2},
+		AfterFunc: func(callbackReturn any) {
+			returnChan <- callbackReturn
+		},
+		ErrorFunc: func(err error) {
+			t.Errorf("unexpected error: %v", err)
+			returnChan <- nil
+		},
+	})
+	assert.Equal(t, AssociativeArray{Map: map[string]any{"sum": int64(3)}, Order: []string{"sum"}}, <-returnChan)
+
+	errChan := make(chan error, 1)
+	mockExt.InjectRequest(&WorkerRequest{
+		CallbackParameters: map[string]any{"throw": true},
+		ErrorFunc: func(err error) {
+			errChan <- err
+		},
+	})
+	var phpErr *PHPException
+	require.ErrorAs(t, <-errChan, &phpErr)
+	assert.Equal(t, "something went wrong", phpErr.Message)
+
+	require.NoError(t, ScaleWorker(mockExt.Name(), 3))
+	assert.Equal(t, 3, activeServer.Load().getWorkerByName(mockExt.Name()).countThreads())
+	require.NoError(t, ScaleWorker(mockExt.Name(), 1))
+	assert.Equal(t, 1, activeServer.Load().getWorkerByName(mockExt.Name()).countThreads())
+	assert.ErrorIs(t, ScaleWorker("unknown", 1), ErrWorkerNotFound)
+
+	// removed threads must stop asking for requests
+	assert.Eventually(t, func() bool { return mockExt.waiting.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	shutdown()
+	assert.Eventually(t, func() bool { return mockExt.waiting.Load() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+// blockingWorker implements the Worker interface, ProvideRequest blocks until a request is injected
+type blockingWorker struct {
+	Worker
+	requests chan *WorkerRequest
+	calls    atomic.Int32
+}
+
+func (w *blockingWorker) ProvideRequest() *WorkerRequest {
+	w.calls.Add(1)
+
+	return <-w.requests
+}
+
+func TestLegacyWorkerKeepsRequestsProvidedAfterCancellation(t *testing.T) {
+	bw := &blockingWorker{Worker: NewWorker("blockingWorker", "testdata/worker.php", 1, nil), requests: make(chan *WorkerRequest)}
+	lw := newLegacyWorker(bw)
+
+	for range 5 {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := lw.ProvideRequest(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+	}
+
+	// the request provided once the calls have been cancelled is returned by the next call
+	rq := &WorkerRequest{}
+	bw.requests <- rq
+
+	provided, err := lw.ProvideRequest(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, rq, provided)
+
+	// cancelled calls don't start new goroutines blocked in Worker.ProvideRequest()
+	assert.Eventually(t, func() bool { return bw.calls.Load() == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), bw.calls.Load())
+}
+
+func TestLegacyWorkerStopsFeedingOnShutdown(t *testing.T) {
+	bw := &blockingWorker{Worker: NewWorker("blockingWorker", "testdata/worker.php", 1, nil), requests: make(chan *WorkerRequest)}
+	lw := newLegacyWorker(bw)
+
+	initExtensionWorkers()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := lw.ProvideRequest(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	drainExtensionWorkers()
+
+	// the request provided after the shutdown stops the goroutine instead of calling Worker.ProvideRequest() again
+	rq := &WorkerRequest{}
+	bw.requests <- rq
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), bw.calls.Load())
+
+	// it is kept for the next run
+	initExtensionWorkers()
+	t.Cleanup(drainExtensionWorkers)
+
+	provided, err := lw.ProvideRequest(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, rq, provided)
+}